@@ -0,0 +1,278 @@
+// Package agent implements `gobw agent`, a daemon holding the unlocked vault
+// for other gobw processes, and the client they talk to it with. Requests
+// are JSON-RPC over a unix socket that only the same user may connect to.
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/sshagent"
+)
+
+// SocketEnv names the environment variable pointing gobw at the agent.
+const SocketEnv = "GOBW_AGENT_SOCK"
+
+// DefaultSocketPath returns $GOBW_AGENT_SOCK, or agent.sock next to the SSH
+// agent socket.
+func DefaultSocketPath() string {
+	if path := os.Getenv(SocketEnv); path != "" {
+		return path
+	}
+	return filepath.Join(sshagent.RuntimeDir(), "agent.sock")
+}
+
+// Request and reply types of the Vault service.
+type (
+	Empty      struct{}
+	QueryArgs  struct{ Query string }
+	UnlockArgs struct{ Password string }
+	RevealArgs struct {
+		IDs      []string
+		Password string
+	}
+
+	StatusReply struct {
+		Status  bw.VaultStatus
+		Session string
+	}
+
+	TOTPReply struct {
+		Code      string
+		Remaining time.Duration
+	}
+)
+
+// Server holds the unlocked vault and answers requests for it.
+type Server struct {
+	rpc         *rpc.Server
+	idleTimeout time.Duration
+
+	// mu serializes the use of bwm, which is not safe for concurrent use,
+	// and guards lastUsed.
+	mu       sync.Mutex
+	bwm      *bw.Manager
+	lastUsed time.Time
+}
+
+// NewServer creates an agent for the vault managed by bwm, which locks the
+// vault once no request came in for idleTimeout. A zero idleTimeout never
+// locks.
+func NewServer(bwm *bw.Manager, idleTimeout time.Duration) (*Server, error) {
+	s := &Server{
+		rpc:         rpc.NewServer(),
+		idleTimeout: idleTimeout,
+		bwm:         bwm,
+		lastUsed:    time.Now(),
+	}
+	if err := s.rpc.RegisterName("Vault", &Vault{s}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Serve answers the connections on l, dropping those from other users.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		uid, err := peerUID(conn)
+		if err != nil || uid != os.Getuid() {
+			conn.Close()
+			continue
+		}
+		go s.rpc.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// Refresh checks whether the vault is still unlocked, and locks it once the
+// agent was idle for too long. It reports whether it did.
+func (s *Server) Refresh() (idleLocked bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.bwm.UpdateStatus(); err != nil {
+		return false, err
+	}
+	if s.bwm.VaultStatus.Status != bw.Unlocked || s.idleTimeout <= 0 || time.Since(s.lastUsed) < s.idleTimeout {
+		return false, nil
+	}
+	if err := s.bwm.Lock(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// acquire takes s.mu for a request, which counts as activity. The caller
+// releases it. Status polls do not count and take s.mu directly.
+func (s *Server) acquire() {
+	s.mu.Lock()
+	s.lastUsed = time.Now()
+}
+
+// unlocked fails requests needing the items while the vault is locked.
+func (s *Server) unlocked() error {
+	switch s.bwm.VaultStatus.Status {
+	case bw.Unlocked:
+		return nil
+	case bw.Unauthenticated:
+		return bw.ErrNotLoggedIn
+	default:
+		return bw.ErrLocked
+	}
+}
+
+// Vault is the RPC service of the agent. Its methods follow net/rpc
+// conventions, and every exported method is a request.
+type Vault struct {
+	s *Server
+}
+
+func (v *Vault) Status(_ Empty, reply *StatusReply) error {
+	// clients like the SSH agent poll the status, which must not keep the
+	// vault from locking when idle
+	v.s.mu.Lock()
+	defer v.s.mu.Unlock()
+	reply.Status = v.s.bwm.VaultStatus
+	reply.Session = v.s.bwm.Session()
+	return nil
+}
+
+// List hands out the vault with the secrets of items with master password
+// re-prompt left out, which only Get and Reveal hand out.
+func (v *Vault) List(_ Empty, reply *bw.AgentList) error {
+	v.s.acquire()
+	defer v.s.mu.Unlock()
+	if err := v.s.unlocked(); err != nil {
+		return err
+	}
+	list := v.s.bwm.List()
+	items := make([]bw.Item, len(list.Items))
+	for i, item := range list.Items {
+		items[i] = item.Redacted()
+	}
+	list.Items = items
+	*reply = list
+	return nil
+}
+
+func (v *Vault) Get(args QueryArgs, reply *bw.Item) error {
+	v.s.acquire()
+	defer v.s.mu.Unlock()
+	if err := v.s.unlocked(); err != nil {
+		return err
+	}
+	item, err := v.s.bwm.FindItem(args.Query)
+	if err != nil {
+		return err
+	}
+	item, err = v.s.bwm.CheckReprompt(item, "")
+	if err != nil {
+		return err
+	}
+	*reply = item
+	return nil
+}
+
+// Reveal hands out items in full once the master password was given along or
+// entered on the agent's pinentry.
+func (v *Vault) Reveal(args RevealArgs, reply *[]bw.Item) error {
+	v.s.acquire()
+	defer v.s.mu.Unlock()
+	if err := v.s.unlocked(); err != nil {
+		return err
+	}
+	if args.Password != "" {
+		if err := v.s.bwm.VerifyPassword(args.Password); err != nil {
+			return err
+		}
+	}
+	byID := make(map[string]bw.Item)
+	for _, item := range v.s.bwm.List().Items {
+		byID[item.ID] = item
+	}
+	items := make([]bw.Item, 0, len(args.IDs))
+	for _, id := range args.IDs {
+		item, ok := byID[id]
+		if !ok {
+			return fmt.Errorf("%w: %q", bw.ErrItemNotFound, id)
+		}
+		if args.Password == "" {
+			var err error
+			item, err = v.s.bwm.CheckReprompt(item, "")
+			if err != nil {
+				return err
+			}
+		}
+		items = append(items, item)
+	}
+	*reply = items
+	return nil
+}
+
+func (v *Vault) TOTP(args QueryArgs, reply *TOTPReply) error {
+	v.s.acquire()
+	defer v.s.mu.Unlock()
+	if err := v.s.unlocked(); err != nil {
+		return err
+	}
+	item, err := v.s.bwm.FindItem(args.Query)
+	if err != nil {
+		return err
+	}
+	if item.Login.TOTP == "" {
+		return bw.ErrFieldNotFound
+	}
+	if _, err := v.s.bwm.CheckReprompt(item, "totp"); err != nil {
+		return err
+	}
+	reply.Code, reply.Remaining, err = bw.TOTP(item.Login.TOTP, time.Now())
+	return err
+}
+
+func (v *Vault) Unlock(args UnlockArgs, _ *Empty) error {
+	v.s.acquire()
+	defer v.s.mu.Unlock()
+	if err := v.s.bwm.Unlock(args.Password); err != nil {
+		return err
+	}
+	return v.s.bwm.UpdateList()
+}
+
+func (v *Vault) Lock(_ Empty, _ *Empty) error {
+	v.s.acquire()
+	defer v.s.mu.Unlock()
+	return v.s.bwm.Lock()
+}
+
+// Reload loads the items again after a client wrote to the vault with the
+// agent's session, so the others do not get stale ones.
+func (v *Vault) Reload(_ Empty, _ *Empty) error {
+	v.s.acquire()
+	defer v.s.mu.Unlock()
+	if err := v.s.unlocked(); err != nil {
+		return err
+	}
+	return v.s.bwm.UpdateList()
+}
+
+func (v *Vault) Sync(_ Empty, _ *Empty) error {
+	v.s.acquire()
+	defer v.s.mu.Unlock()
+	if err := v.s.unlocked(); err != nil {
+		return err
+	}
+	return v.s.bwm.Sync()
+}
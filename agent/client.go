@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/sshagent"
+)
+
+// Client talks to a running agent. It implements bw.Agent.
+type Client struct {
+	rpc *rpc.Client
+}
+
+// Dial connects to the agent listening on path.
+func Dial(path string) (*Client, error) {
+	// the agent hands out the session key and is sent the master password,
+	// so its socket must be in a directory only this user controls
+	if err := sshagent.CheckDir(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("failed to connect to agent: %w", err)
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to agent: %w", err)
+	}
+	return &Client{rpc: jsonrpc.NewClient(conn)}, nil
+}
+
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+// remoteErrors are the errors that keep their identity across the socket,
+// so that callers can still tell them apart with errors.Is.
+var remoteErrors = []error{
+	bw.ErrLocked,
+	bw.ErrNotLoggedIn,
+	bw.ErrInvalidPassword,
+	bw.ErrRepromptUnavailable,
+	bw.ErrItemNotFound,
+	bw.ErrFieldNotFound,
+}
+
+// remoteError is an error returned by the agent.
+type remoteError struct {
+	msg string
+	err error
+}
+
+func (e *remoteError) Error() string {
+	return e.msg
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.err
+}
+
+func (c *Client) call(method string, args any, reply any) error {
+	err := c.rpc.Call("Vault."+method, args, reply)
+	var serverErr rpc.ServerError
+	if !errors.As(err, &serverErr) {
+		return err
+	}
+	msg := string(serverErr)
+	for _, known := range remoteErrors {
+		if strings.Contains(msg, known.Error()) {
+			return &remoteError{msg: msg, err: known}
+		}
+	}
+	return errors.New(msg)
+}
+
+func (c *Client) Status() (bw.VaultStatus, string, error) {
+	var reply StatusReply
+	err := c.call("Status", Empty{}, &reply)
+	return reply.Status, reply.Session, err
+}
+
+func (c *Client) List() (bw.AgentList, error) {
+	var reply bw.AgentList
+	err := c.call("List", Empty{}, &reply)
+	return reply, err
+}
+
+// Get looks up an item like bw.Manager.FindItem.
+func (c *Client) Get(query string) (bw.Item, error) {
+	var reply bw.Item
+	err := c.call("Get", QueryArgs{Query: query}, &reply)
+	return reply, err
+}
+
+// TOTP returns the current TOTP code of an item and how long it is valid.
+func (c *Client) TOTP(query string) (string, time.Duration, error) {
+	var reply TOTPReply
+	err := c.call("TOTP", QueryArgs{Query: query}, &reply)
+	return reply.Code, reply.Remaining, err
+}
+
+func (c *Client) Unlock(pw string) error {
+	return c.call("Unlock", UnlockArgs{Password: pw}, &Empty{})
+}
+
+func (c *Client) Lock() error {
+	return c.call("Lock", Empty{}, &Empty{})
+}
+
+func (c *Client) Sync() error {
+	return c.call("Sync", Empty{}, &Empty{})
+}
+
+func (c *Client) Reload() error {
+	return c.call("Reload", Empty{}, &Empty{})
+}
+
+func (c *Client) Reveal(ids []string, pw string) ([]bw.Item, error) {
+	var reply []bw.Item
+	err := c.call("Reveal", RevealArgs{IDs: ids, Password: pw}, &reply)
+	return reply, err
+}
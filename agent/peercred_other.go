@@ -0,0 +1,14 @@
+//go:build !linux
+
+package agent
+
+import (
+	"net"
+	"os"
+)
+
+// peerUID relies on the permissions of the socket where SO_PEERCRED is not
+// available.
+func peerUID(_ net.Conn) (int, error) {
+	return os.Getuid(), nil
+}
@@ -0,0 +1,94 @@
+package bw
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Agent is a process keeping the vault unlocked for other gobw processes,
+// such as `gobw agent`. A manager with an agent asks it for the status and
+// items instead of unlocking and listing the vault itself.
+type Agent interface {
+	// Status returns the vault status and, while unlocked, the session key.
+	Status() (VaultStatus, string, error)
+	List() (AgentList, error)
+	Unlock(pw string) error
+	Lock() error
+	Sync() error
+	// Reload makes the agent load the items again after another process
+	// changed the vault with its session.
+	Reload() error
+	// Reveal returns the items with the given IDs in full. List hands out
+	// items with master password re-prompt redacted; Reveal checks pw, or
+	// asks for the master password if pw is empty, before handing them out.
+	Reveal(ids []string, pw string) ([]Item, error)
+}
+
+// AgentList is everything UpdateList loads, as an agent hands it out.
+type AgentList struct {
+	Items         []Item         `json:"items"`
+	Folders       []Folder       `json:"folders"`
+	Organizations []Organization `json:"organizations"`
+}
+
+// WithAgent makes the manager use a running agent.
+func WithAgent(agent Agent) Option {
+	return func(bwm *Manager) {
+		bwm.agent = agent
+	}
+}
+
+// List returns everything UpdateList loaded, for handing out by an agent.
+func (bwm *Manager) List() AgentList {
+	bwm.listMu.RLock()
+	defer bwm.listMu.RUnlock()
+	return AgentList{Items: bwm.items, Folders: bwm.folders, Organizations: bwm.organizations}
+}
+
+// vaultChanged tells the agent, if any, to reload the items it hands out after
+// this manager wrote to the vault with the agent's session. During a batch of
+// writes the reload waits for the end of the batch.
+func (bwm *Manager) vaultChanged() {
+	if bwm.agent == nil {
+		return
+	}
+	if bwm.batching {
+		bwm.batchChanged = true
+		return
+	}
+	// the write itself went through; should the reload fail, the agent
+	// hands out the old items until the next sync
+	_ = bwm.agent.Reload()
+}
+
+// startBatch holds back telling the agent about writes until the returned
+// function ends the batch.
+func (bwm *Manager) startBatch() func() {
+	bwm.batching = true
+	return func() {
+		bwm.batching = false
+		if bwm.batchChanged {
+			bwm.batchChanged = false
+			bwm.vaultChanged()
+		}
+	}
+}
+
+// Sync pulls the latest changes from the server and reloads the items.
+func (bwm *Manager) Sync() error {
+	if bwm.agent != nil {
+		err := bwm.agent.Sync()
+		if err != nil {
+			return fmt.Errorf("failed to sync: %w", err)
+		}
+		return bwm.UpdateList()
+	}
+	if bwm.VaultStatus.Status == Unauthenticated {
+		return ErrNotLoggedIn
+	}
+	err := exec.Command("bw", "sync", "--session", bwm.token).Run() // #nosec G204
+	if err != nil {
+		return fmt.Errorf("failed to sync: %w", err)
+	}
+	return bwm.UpdateList()
+}
@@ -0,0 +1,277 @@
+package bw
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1" // #nosec G505 -- required by the Pwned Passwords range API
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBreachAPIURL is the Have I Been Pwned Pwned Passwords API.
+const DefaultBreachAPIURL = "https://api.pwnedpasswords.com"
+
+var ErrMalformedHashFile = errors.New("malformed hash file")
+
+// BreachSource looks up how often a password hash appears in breach corpora.
+type BreachSource interface {
+	// Lookup returns the breach count of a SHA-1 hash in upper case hex.
+	Lookup(hash string) (int, error)
+	Close() error
+}
+
+// OpenBreachSource opens the local hash file if one is set and the range API
+// at apiURL otherwise.
+func OpenBreachSource(apiURL string, hashFile string) (BreachSource, error) {
+	if hashFile != "" {
+		return OpenHashFile(hashFile)
+	}
+	if apiURL == "" {
+		apiURL = DefaultBreachAPIURL
+	}
+	return NewRangeAPI(apiURL), nil
+}
+
+// PasswordHash returns the SHA-1 hash of a password in upper case hex, as
+// used by the Pwned Passwords corpus.
+func PasswordHash(password string) string {
+	sum := sha1.Sum([]byte(password)) // #nosec G401
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// RangeAPI queries a Pwned Passwords compatible range endpoint. Only the first
+// five characters of a hash are ever sent; the matching suffixes are compared
+// locally.
+type RangeAPI struct {
+	baseURL string
+	client  *http.Client
+	ranges  map[string]map[string]int
+}
+
+func NewRangeAPI(baseURL string) *RangeAPI {
+	return &RangeAPI{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+		ranges:  make(map[string]map[string]int),
+	}
+}
+
+func (r *RangeAPI) Lookup(hash string) (int, error) {
+	hash = strings.ToUpper(hash)
+	if len(hash) != 40 {
+		return 0, fmt.Errorf("invalid SHA-1 hash %q", hash)
+	}
+	prefix, suffix := hash[:5], hash[5:]
+	suffixes, ok := r.ranges[prefix]
+	if !ok {
+		var err error
+		suffixes, err = r.fetch(prefix)
+		if err != nil {
+			return 0, err
+		}
+		r.ranges[prefix] = suffixes
+	}
+	return suffixes[suffix], nil
+}
+
+func (r *RangeAPI) fetch(prefix string) (map[string]int, error) {
+	req, err := http.NewRequest(http.MethodGet, r.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "gobw")
+	// padding hides the number of suffixes in the response size
+	req.Header.Set("Add-Padding", "true")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query breach range: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query breach range: %s", resp.Status)
+	}
+	suffixes := make(map[string]int)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		suffix, count, ok := parseHashLine(scanner.Text())
+		// padding entries have a count of zero
+		if ok && count > 0 {
+			suffixes[suffix] = count
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breach range: %w", err)
+	}
+	return suffixes, nil
+}
+
+func (r *RangeAPI) Close() error {
+	return nil
+}
+
+// parseHashLine splits a "HASH:COUNT" line of a range response or hash file.
+func parseHashLine(line string) (string, int, bool) {
+	hash, count, ok := strings.Cut(strings.TrimSpace(line), ":")
+	if !ok {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return "", 0, false
+	}
+	return strings.ToUpper(hash), n, true
+}
+
+// maxHashLine bounds the length of a line in a hash file: a 40 character hash,
+// a colon, the count and a CRLF.
+const maxHashLine = 64
+
+// HashFile looks hashes up in a downloaded Pwned Passwords file ordered by
+// hash. The file is binary searched in place, so nothing is loaded into
+// memory and lookups stay fast on the full multi-gigabyte corpus.
+type HashFile struct {
+	f    *os.File
+	size int64
+}
+
+func OpenHashFile(path string) (*HashFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open hash file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open hash file: %w", err)
+	}
+	return &HashFile{f: f, size: info.Size()}, nil
+}
+
+// lineAt returns the first line starting at or after off and where it starts.
+// ok is false past the last line.
+func (h *HashFile) lineAt(off int64) (start int64, line string, ok bool, err error) {
+	start = off
+	buf := make([]byte, 2*maxHashLine)
+	if off > 0 {
+		// read from the byte before off to tell whether off starts a line
+		n, err := h.f.ReadAt(buf, off-1)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, "", false, err
+		}
+		buf = buf[:n]
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			return h.size, "", false, nil
+		}
+		start = off + int64(i)
+		buf = buf[i+1:]
+	} else {
+		n, err := h.f.ReadAt(buf, 0)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, "", false, err
+		}
+		buf = buf[:n]
+	}
+	if len(buf) == 0 {
+		return h.size, "", false, nil
+	}
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		buf = buf[:i]
+	} else if start+int64(len(buf)) < h.size {
+		return 0, "", false, ErrMalformedHashFile
+	}
+	return start, string(buf), true, nil
+}
+
+func (h *HashFile) Lookup(hash string) (int, error) {
+	hash = strings.ToUpper(hash)
+	// every line starting before lo is below the hash and the line at hi is
+	// not, so the loop closes in on the first line at or above the hash.
+	lo, hi := int64(0), h.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, ok, err := h.lineAt(mid)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			hi = mid
+			continue
+		}
+		lineHash, _, valid := parseHashLine(line)
+		if !valid {
+			return 0, ErrMalformedHashFile
+		}
+		if lineHash < hash {
+			lo = start + int64(len(line)) + 1
+		} else {
+			hi = mid
+		}
+	}
+	_, line, ok, err := h.lineAt(lo)
+	if err != nil || !ok {
+		return 0, err
+	}
+	lineHash, count, valid := parseHashLine(line)
+	if !valid {
+		return 0, ErrMalformedHashFile
+	}
+	if lineHash != hash {
+		return 0, nil
+	}
+	return count, nil
+}
+
+func (h *HashFile) Close() error {
+	return h.f.Close()
+}
+
+type BreachedPassword struct {
+	Item HealthItem `json:"item"`
+	// Count is how often the password appears in breach corpora.
+	Count int `json:"count"`
+}
+
+// breachedPasswords looks every distinct login password up once. Only hashes
+// are handed to the source.
+func breachedPasswords(items []Item, source BreachSource) ([]BreachedPassword, error) {
+	byHash := make(map[string][]HealthItem)
+	var hashes []string
+	for _, item := range items {
+		if item.Type != Login || item.Login.Password == "" {
+			continue
+		}
+		hash := PasswordHash(item.Login.Password)
+		if _, ok := byHash[hash]; !ok {
+			hashes = append(hashes, hash)
+		}
+		byHash[hash] = append(byHash[hash], newHealthItem(item))
+	}
+	// sorted lookups share ranges and read the hash file front to back
+	sort.Strings(hashes)
+	var breached []BreachedPassword
+	for _, hash := range hashes {
+		count, err := source.Lookup(hash)
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			continue
+		}
+		for _, item := range byHash[hash] {
+			breached = append(breached, BreachedPassword{Item: item, Count: count})
+		}
+	}
+	sort.SliceStable(breached, func(i, j int) bool {
+		return breached[i].Count > breached[j].Count
+	})
+	return breached, nil
+}
@@ -0,0 +1,146 @@
+package bw
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Kubernetes client authentication API versions ExecCredential supports.
+const (
+	ExecCredentialV1      = "client.authentication.k8s.io/v1"
+	ExecCredentialV1beta1 = "client.authentication.k8s.io/v1beta1"
+)
+
+// AWSCredentials is the output the AWS SDKs expect from a credential_process.
+type AWSCredentials struct {
+	Version         int        `json:"Version"`
+	AccessKeyID     string     `json:"AccessKeyId"`
+	SecretAccessKey string     `json:"SecretAccessKey"`
+	SessionToken    string     `json:"SessionToken,omitempty"`
+	Expiration      *time.Time `json:"Expiration,omitempty"`
+}
+
+// ExecCredential is the output kubectl expects from an exec credential
+// plugin.
+type ExecCredential struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Status     ExecCredentialStatus `json:"status"`
+}
+
+type ExecCredentialStatus struct {
+	Token                 string     `json:"token,omitempty"`
+	ClientCertificateData string     `json:"clientCertificateData,omitempty"`
+	ClientKeyData         string     `json:"clientKeyData,omitempty"`
+	ExpirationTimestamp   *time.Time `json:"expirationTimestamp,omitempty"`
+}
+
+// fieldKey folds a field name so that "AccessKeyId", "access_key_id" and
+// "Access Key ID" compare equal.
+func fieldKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// customField returns the first custom field named like one of names.
+func (item Item) customField(names ...string) string {
+	for _, name := range names {
+		key := fieldKey(name)
+		for _, f := range item.Fields {
+			if fieldKey(f.Name) == key && f.Value != "" {
+				return f.Value
+			}
+		}
+	}
+	return ""
+}
+
+// expiration parses an optional expiration field.
+func (item Item) expiration(names ...string) (*time.Time, error) {
+	value := item.customField(names...)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiration in %q: %w", item.Name, err)
+	}
+	return &t, nil
+}
+
+// AWSCredentials reads an access key from the custom fields AccessKeyId,
+// SecretAccessKey and optionally SessionToken and Expiration, also spelled
+// like the aws_access_key_id keys of the credentials file. Logins without
+// these fields hold the key ID as username and the secret as password.
+func (item Item) AWSCredentials() (AWSCredentials, error) {
+	creds := AWSCredentials{
+		Version:         1,
+		AccessKeyID:     item.customField("AccessKeyId", "aws_access_key_id"),
+		SecretAccessKey: item.customField("SecretAccessKey", "aws_secret_access_key"),
+		SessionToken:    item.customField("SessionToken", "aws_session_token"),
+	}
+	if creds.AccessKeyID == "" {
+		creds.AccessKeyID = item.Login.Username
+	}
+	if creds.SecretAccessKey == "" {
+		creds.SecretAccessKey = item.Login.Password
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return AWSCredentials{}, fmt.Errorf("%w: %q has no AccessKeyId and SecretAccessKey", ErrFieldNotFound, item.Name)
+	}
+	var err error
+	creds.Expiration, err = item.expiration("Expiration")
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	return creds, nil
+}
+
+// ExecCredential reads a bearer token from the custom field token, or the
+// password, or a client certificate from the fields client-certificate-data
+// and client-key-data as in kubeconfig files, in PEM or base64 encoded PEM.
+func (item Item) ExecCredential(apiVersion string) (ExecCredential, error) {
+	switch apiVersion {
+	case "":
+		apiVersion = ExecCredentialV1
+	case ExecCredentialV1, ExecCredentialV1beta1:
+	default:
+		return ExecCredential{}, fmt.Errorf("unsupported ExecCredential version %q", apiVersion)
+	}
+	status := ExecCredentialStatus{
+		Token:                 item.customField("token"),
+		ClientCertificateData: item.customField("client-certificate-data"),
+		ClientKeyData:         item.customField("client-key-data"),
+	}
+	// ExecCredential wants PEM where kubeconfig files have it base64 encoded
+	for _, data := range []*string{&status.ClientCertificateData, &status.ClientKeyData} {
+		if *data != "" && !strings.HasPrefix(strings.TrimSpace(*data), "-----BEGIN") {
+			pem, err := base64.StdEncoding.DecodeString(strings.TrimSpace(*data))
+			if err != nil {
+				return ExecCredential{}, fmt.Errorf("invalid client certificate in %q: %w", item.Name, err)
+			}
+			*data = string(pem)
+		}
+	}
+	hasCert := status.ClientCertificateData != "" && status.ClientKeyData != ""
+	if status.Token == "" && !hasCert {
+		status.Token = item.Login.Password
+	}
+	if status.Token == "" && !hasCert {
+		return ExecCredential{}, fmt.Errorf("%w: %q has no token or client certificate", ErrFieldNotFound, item.Name)
+	}
+	var err error
+	status.ExpirationTimestamp, err = item.expiration("expirationTimestamp", "Expiration")
+	if err != nil {
+		return ExecCredential{}, err
+	}
+	return ExecCredential{APIVersion: apiVersion, Kind: "ExecCredential", Status: status}, nil
+}
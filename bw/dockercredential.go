@@ -0,0 +1,161 @@
+package bw
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultDockerCredentialFolder is where registry logins are stored unless
+// configured otherwise.
+const DefaultDockerCredentialFolder = "Docker"
+
+// ErrDockerCredentialNotFound carries the exact message docker looks for to
+// tell missing credentials from failures.
+var ErrDockerCredentialNotFound = errors.New("credentials not found in native keychain")
+
+// DockerCredential is a registry login in the docker credential helper
+// protocol.
+type DockerCredential struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// registryHost returns the host and port of a registry server URL, which may
+// come with or without a scheme and path.
+func registryHost(serverURL string) string {
+	u, err := normalizeURL(strings.TrimSpace(serverURL))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// dockerCredentialItems returns the logins with a URI on the registry host.
+// Unlike autofill, only the exact host counts: registries on the same domain
+// rarely share credentials.
+func (bwm *Manager) dockerCredentialItems(serverURL string) []Item {
+	host := registryHost(serverURL)
+	if host == "" {
+		return nil
+	}
+	var matched []Item
+	for _, item := range bwm.itemList() {
+		if item.Type != Login {
+			continue
+		}
+		for _, uri := range item.Login.URIs {
+			if registryHost(uri.URI) == host {
+				matched = append(matched, item)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// folderItems narrows items down to those in the named folder.
+func (bwm *Manager) folderItems(items []Item, folder string) []Item {
+	var inFolder []Item
+	for _, item := range items {
+		if item.FolderID != "" && bwm.FolderName(item.FolderID) == folder {
+			inFolder = append(inFolder, item)
+		}
+	}
+	return inFolder
+}
+
+// FindDockerCredential returns the login for a registry. Logins in folder
+// win over logins elsewhere in the vault.
+func (bwm *Manager) FindDockerCredential(serverURL string, folder string) (Item, error) {
+	matches := bwm.dockerCredentialItems(serverURL)
+	if len(matches) > 1 {
+		if inFolder := bwm.folderItems(matches, folder); len(inFolder) > 0 {
+			matches = inFolder
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Item{}, ErrDockerCredentialNotFound
+	case 1:
+		return matches[0], nil
+	default:
+		return Item{}, &AmbiguousError{Query: serverURL, Matches: matches}
+	}
+}
+
+// StoreDockerCredential saves a registry login docker logged in with. A login
+// for the registry and username in folder gets the new secret, otherwise a
+// new login named after the registry is created there.
+func (bwm *Manager) StoreDockerCredential(cred DockerCredential, folder string) (Item, error) {
+	if registryHost(cred.ServerURL) == "" {
+		return Item{}, fmt.Errorf("invalid registry %q", cred.ServerURL)
+	}
+	for _, item := range bwm.folderItems(bwm.dockerCredentialItems(cred.ServerURL), folder) {
+		if item.Login.Username != cred.Username {
+			continue
+		}
+		if item.Login.Password == cred.Secret {
+			return item, nil
+		}
+		item, err := bwm.editItem(item.ID, func(raw map[string]any) {
+			login, _ := raw["login"].(map[string]any)
+			if login == nil {
+				login = make(map[string]any)
+				raw["login"] = login
+			}
+			login["password"] = cred.Secret
+		})
+		if err != nil {
+			return Item{}, fmt.Errorf("failed to update credential: %w", err)
+		}
+		return item, nil
+	}
+	folderID, _, err := bwm.ensureFolder(folder)
+	if err != nil {
+		return Item{}, err
+	}
+	return bwm.CreateItem(Item{
+		Type:     Login,
+		Name:     registryHost(cred.ServerURL),
+		FolderID: folderID,
+		Login: ItemLogin{
+			Username: cred.Username,
+			Password: cred.Secret,
+			URIs:     []ItemLoginURI{{Match: MatchHost, URI: cred.ServerURL}},
+		},
+	})
+}
+
+// EraseDockerCredential moves the logins for a registry in folder to the
+// trash, as on docker logout. Logins elsewhere in the vault are left alone.
+func (bwm *Manager) EraseDockerCredential(serverURL string, folder string) error {
+	items := bwm.folderItems(bwm.dockerCredentialItems(serverURL), folder)
+	if len(items) == 0 {
+		return ErrDockerCredentialNotFound
+	}
+	for _, item := range items {
+		if err := bwm.DeleteItem(item.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListDockerCredentials returns the username of every registry login in
+// folder by the registry's server URL.
+func (bwm *Manager) ListDockerCredentials(folder string) map[string]string {
+	creds := make(map[string]string)
+	for _, item := range bwm.folderItems(bwm.itemList(), folder) {
+		if item.Type != Login {
+			continue
+		}
+		for _, uri := range item.Login.URIs {
+			if registryHost(uri.URI) != "" {
+				creds[uri.URI] = item.Login.Username
+			}
+		}
+	}
+	return creds
+}
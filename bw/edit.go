@@ -0,0 +1,96 @@
+package bw
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+)
+
+// editItem fetches the full item from the CLI, lets mutate change it and saves
+// it with `bw edit item`. The raw JSON is edited rather than Item so that
+// fields gobw does not decode survive the round trip.
+func (bwm *Manager) editItem(id string, mutate func(map[string]any)) (Item, error) {
+	if bwm.VaultStatus.Status == Unauthenticated {
+		return Item{}, ErrNotLoggedIn
+	}
+	out, err := exec.Command("bw", "get", "item", id, "--session", bwm.token).Output() // #nosec G204
+	if err != nil {
+		return Item{}, err
+	}
+	var raw map[string]any
+	err = json.Unmarshal(out, &raw)
+	if err != nil {
+		return Item{}, err
+	}
+	mutate(raw)
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return Item{}, err
+	}
+	// the encoded item is passed on stdin to keep secrets out of the
+	// process list.
+	cmd := exec.Command("bw", "edit", "item", id, "--session", bwm.token) // #nosec G204
+	cmd.Stdin = bytes.NewBufferString(base64.StdEncoding.EncodeToString(data))
+	out, err = cmd.Output()
+	if err != nil {
+		return Item{}, err
+	}
+	bwm.vaultChanged()
+	var item Item
+	err = json.Unmarshal(out, &item)
+	if err != nil {
+		return Item{}, err
+	}
+	bwm.replaceItem(item)
+	return item, nil
+}
+
+// replaceItem swaps in an updated item. The slice is copied rather than
+// modified in place since the UI may still be rendering the old one.
+func (bwm *Manager) replaceItem(item Item) {
+	bwm.listMu.Lock()
+	defer bwm.listMu.Unlock()
+	items := make([]Item, len(bwm.items))
+	copy(items, bwm.items)
+	for i := range items {
+		if items[i].ID == item.ID {
+			items[i] = item
+		}
+	}
+	bwm.items = items
+}
+
+// SetFavorite marks or unmarks an item as favorite.
+func (bwm *Manager) SetFavorite(id string, favorite bool) (Item, error) {
+	item, err := bwm.editItem(id, func(raw map[string]any) {
+		raw["favorite"] = favorite
+	})
+	if err != nil {
+		return Item{}, fmt.Errorf("failed to set favorite: %w", err)
+	}
+	return item, nil
+}
+
+// DeleteItem moves an item to the trash.
+func (bwm *Manager) DeleteItem(id string) error {
+	if bwm.VaultStatus.Status == Unauthenticated {
+		return ErrNotLoggedIn
+	}
+	err := exec.Command("bw", "delete", "item", id, "--session", bwm.token).Run() // #nosec G204
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %w", err)
+	}
+	bwm.vaultChanged()
+	bwm.listMu.Lock()
+	defer bwm.listMu.Unlock()
+	items := make([]Item, 0, len(bwm.items))
+	for _, item := range bwm.items {
+		if item.ID != id {
+			items = append(items, item)
+		}
+	}
+	bwm.items = items
+	return nil
+}
@@ -0,0 +1,480 @@
+package bw
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/sapslaj/gobw/kdbx"
+)
+
+// ExportFormat names match the --format values of `bw export`, plus kdbx for
+// KeePass databases.
+type ExportFormat string
+
+const (
+	ExportJSON          ExportFormat = "json"
+	ExportCSV           ExportFormat = "csv"
+	ExportEncryptedJSON ExportFormat = "encrypted_json"
+	ExportKDBX          ExportFormat = "kdbx"
+)
+
+var ErrUnknownExportFormat = errors.New("unknown export format")
+
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch f := ExportFormat(strings.ToLower(s)); f {
+	case ExportJSON, ExportCSV, ExportEncryptedJSON, ExportKDBX:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownExportFormat, s)
+	}
+}
+
+func (f ExportFormat) Extension() string {
+	switch f {
+	case ExportCSV:
+		return "csv"
+	case ExportKDBX:
+		return "kdbx"
+	default:
+		return "json"
+	}
+}
+
+// IsEncrypted reports whether exports in the format need a password.
+func (f ExportFormat) IsEncrypted() bool {
+	return f == ExportEncryptedJSON || f == ExportKDBX
+}
+
+// DefaultExportPath is the file name `bw export` would pick.
+func DefaultExportPath(format ExportFormat, now time.Time) string {
+	return fmt.Sprintf("bitwarden_export_%s.%s", now.Format("20060102150405"), format.Extension())
+}
+
+// ExportFilter limits an export. Folder and Organization match the ID or the
+// case-insensitive name; empty fields match everything.
+type ExportFilter struct {
+	Folder       string
+	Organization string
+	Type         ItemType
+}
+
+func (bwm *Manager) exportMatch(filter ExportFilter, item Item) bool {
+	matches := func(id string, name string, query string) bool {
+		return query == "" || id == query || strings.EqualFold(name, query)
+	}
+	return matches(item.FolderID, bwm.FolderName(item.FolderID), filter.Folder) &&
+		matches(item.OrganizationID, bwm.OrganizationName(item.OrganizationID), filter.Organization) &&
+		(filter.Type == 0 || item.Type == filter.Type)
+}
+
+type ExportOptions struct {
+	Format ExportFormat
+	Filter ExportFilter
+	// Password protects encrypted JSON and KeePass exports. It is unrelated
+	// to the master password.
+	Password string
+	// MasterPassword has an agent reveal the items with master password
+	// re-prompt, see RevealItems.
+	MasterPassword string
+	// KeePass tunes KeePass exports; nil means kdbx.DefaultOptions.
+	KeePass *kdbx.Options
+}
+
+type ExportResult struct {
+	Exported int
+	// Skipped counts items the format cannot hold, i.e. cards, identities
+	// and SSH keys in CSV exports.
+	Skipped int
+}
+
+var ErrExportPassword = errors.New("encrypted exports need a password")
+
+// Export writes the items matching the filter in one of the formats the
+// Bitwarden clients import.
+func (bwm *Manager) Export(w io.Writer, opts ExportOptions) (ExportResult, error) {
+	items, err := bwm.GetList()
+	if err != nil {
+		return ExportResult{}, err
+	}
+	matched := make([]Item, 0, len(items))
+	for _, item := range items {
+		if bwm.exportMatch(opts.Filter, item) {
+			matched = append(matched, item)
+		}
+	}
+	matched, err = bwm.RevealItems(matched, opts.MasterPassword)
+	if err != nil {
+		return ExportResult{}, err
+	}
+
+	switch opts.Format {
+	case ExportCSV:
+		return bwm.exportCSV(w, matched)
+	case ExportJSON:
+		data, err := json.MarshalIndent(bwm.exportJSON(matched), "", "  ")
+		if err != nil {
+			return ExportResult{}, err
+		}
+		_, err = w.Write(data)
+		return ExportResult{Exported: len(matched)}, err
+	case ExportEncryptedJSON:
+		if opts.Password == "" {
+			return ExportResult{}, ErrExportPassword
+		}
+		data, err := json.Marshal(bwm.exportJSON(matched))
+		if err != nil {
+			return ExportResult{}, err
+		}
+		encrypted, err := encryptExport(data, opts.Password)
+		if err != nil {
+			return ExportResult{}, err
+		}
+		_, err = w.Write(encrypted)
+		return ExportResult{Exported: len(matched)}, err
+	case ExportKDBX:
+		kdbxOpts := kdbx.DefaultOptions()
+		if opts.KeePass != nil {
+			kdbxOpts = *opts.KeePass
+		}
+		err := bwm.WriteKDBX(w, matched, opts.Password, kdbxOpts)
+		return ExportResult{Exported: len(matched)}, err
+	default:
+		return ExportResult{}, fmt.Errorf("%w: %q", ErrUnknownExportFormat, opts.Format)
+	}
+}
+
+// ExportFile writes an export to path, readable by the owner only. An existing
+// file is only replaced if overwrite is set.
+func (bwm *Manager) ExportFile(path string, overwrite bool, opts ExportOptions) (ExportResult, error) {
+	// the export is built in memory first so a failure leaves no partial file
+	var buf bytes.Buffer
+	result, err := bwm.Export(&buf, opts)
+	if err != nil {
+		return ExportResult{}, fmt.Errorf("failed to export: %w", err)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o600)
+	if err != nil {
+		return ExportResult{}, fmt.Errorf("failed to export: %w", err)
+	}
+	defer f.Close()
+	// a replaced file keeps its old mode otherwise
+	err = f.Chmod(0o600)
+	if err != nil {
+		return ExportResult{}, fmt.Errorf("failed to export: %w", err)
+	}
+	_, err = buf.WriteTo(f)
+	if err != nil {
+		return ExportResult{}, fmt.Errorf("failed to export: %w", err)
+	}
+	return result, f.Close()
+}
+
+type exportFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type exportField struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Type     FieldType `json:"type"`
+	LinkedID *int      `json:"linkedId"`
+}
+
+type exportLogin struct {
+	URIs     []ItemLoginURI `json:"uris"`
+	Username *string        `json:"username"`
+	Password *string        `json:"password"`
+	TOTP     *string        `json:"totp"`
+}
+
+type exportItem struct {
+	PasswordHistory []ItemPasswordHistory `json:"passwordHistory"`
+	RevisionDate    time.Time             `json:"revisionDate"`
+	CreationDate    time.Time             `json:"creationDate"`
+	DeletedDate     *time.Time            `json:"deletedDate"`
+	ID              string                `json:"id"`
+	OrganizationID  *string               `json:"organizationId"`
+	FolderID        *string               `json:"folderId"`
+	Type            ItemType              `json:"type"`
+	Reprompt        int                   `json:"reprompt"`
+	Name            string                `json:"name"`
+	Notes           *string               `json:"notes"`
+	Favorite        bool                  `json:"favorite"`
+	Fields          []exportField         `json:"fields,omitempty"`
+	Login           *exportLogin          `json:"login,omitempty"`
+	SecureNote      *ItemSecureNote       `json:"secureNote,omitempty"`
+	Card            *ItemCard             `json:"card,omitempty"`
+	Identity        *ItemIdentity         `json:"identity,omitempty"`
+	SSHKey          *ItemSSHKey           `json:"sshKey,omitempty"`
+	CollectionIDs   []string              `json:"collectionIds"`
+}
+
+type exportDocument struct {
+	Encrypted bool           `json:"encrypted"`
+	Folders   []exportFolder `json:"folders"`
+	Items     []exportItem   `json:"items"`
+}
+
+// nullable maps empty strings to null like the Bitwarden exports do.
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// exportJSON builds the unencrypted Bitwarden JSON export, with the folders
+// the items are in.
+func (bwm *Manager) exportJSON(items []Item) exportDocument {
+	doc := exportDocument{
+		Folders: []exportFolder{},
+		Items:   make([]exportItem, 0, len(items)),
+	}
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if item.FolderID != "" && !seen[item.FolderID] {
+			seen[item.FolderID] = true
+			doc.Folders = append(doc.Folders, exportFolder{ID: item.FolderID, Name: bwm.FolderName(item.FolderID)})
+		}
+		doc.Items = append(doc.Items, newExportItem(item))
+	}
+	return doc
+}
+
+// newExportItem converts an item to the layout of the Bitwarden JSON export,
+// which is also what `bw create item` reads.
+func newExportItem(item Item) exportItem {
+	ei := exportItem{
+		PasswordHistory: item.PasswordHistory,
+		RevisionDate:    item.RevisionDate,
+		CreationDate:    item.CreationDate,
+		ID:              item.ID,
+		OrganizationID:  nullable(item.OrganizationID),
+		FolderID:        nullable(item.FolderID),
+		Type:            item.Type,
+		Reprompt:        item.Reprompt,
+		Name:            item.Name,
+		Notes:           nullable(item.Notes),
+		Favorite:        item.Favorite,
+		CollectionIDs:   item.CollectionIDs,
+	}
+	if !item.DeletedDate.IsZero() {
+		deleted := item.DeletedDate
+		ei.DeletedDate = &deleted
+	}
+	for _, f := range item.Fields {
+		ei.Fields = append(ei.Fields, exportField{Name: f.Name, Value: f.Value, Type: f.Type})
+	}
+	switch item.Type {
+	case Login:
+		uris := item.Login.URIs
+		if uris == nil {
+			uris = []ItemLoginURI{}
+		}
+		ei.Login = &exportLogin{
+			URIs:     uris,
+			Username: nullable(item.Login.Username),
+			Password: nullable(item.Login.Password),
+			TOTP:     nullable(item.Login.TOTP),
+		}
+	case SecureNote:
+		note := item.SecureNote
+		ei.SecureNote = &note
+	case Card:
+		card := item.Card
+		ei.Card = &card
+	case Identity:
+		identity := item.Identity
+		ei.Identity = &identity
+	case SSHKey:
+		key := item.SSHKey
+		ei.SSHKey = &key
+	}
+	return ei
+}
+
+// csvHeader is the column layout of Bitwarden's individual vault CSV export.
+var csvHeader = []string{
+	"folder", "favorite", "type", "name", "notes", "fields", "reprompt",
+	"login_uri", "login_username", "login_password", "login_totp",
+}
+
+// exportCSV writes logins and secure notes; the CSV format has no columns for
+// the other item types.
+func (bwm *Manager) exportCSV(w io.Writer, items []Item) (ExportResult, error) {
+	var result ExportResult
+	cw := csv.NewWriter(w)
+	err := cw.Write(csvHeader)
+	if err != nil {
+		return result, err
+	}
+	for _, item := range items {
+		var itemType string
+		switch item.Type {
+		case Login:
+			itemType = "login"
+		case SecureNote:
+			itemType = "note"
+		default:
+			result.Skipped++
+			continue
+		}
+		favorite := ""
+		if item.Favorite {
+			favorite = "1"
+		}
+		fields := make([]string, 0, len(item.Fields))
+		for _, f := range item.Fields {
+			fields = append(fields, f.Name+": "+f.Value)
+		}
+		uris := make([]string, 0, len(item.Login.URIs))
+		for _, u := range item.Login.URIs {
+			uris = append(uris, u.URI)
+		}
+		err = cw.Write([]string{
+			bwm.FolderName(item.FolderID),
+			favorite,
+			itemType,
+			item.Name,
+			item.Notes,
+			strings.Join(fields, "\n"),
+			fmt.Sprint(item.Reprompt),
+			strings.Join(uris, ","),
+			item.Login.Username,
+			item.Login.Password,
+			item.Login.TOTP,
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Exported++
+	}
+	cw.Flush()
+	return result, cw.Error()
+}
+
+// exportKDFIterations is the PBKDF2 work factor of password protected
+// exports, the default of the Bitwarden clients.
+const exportKDFIterations = 600000
+
+type encryptedExport struct {
+	Encrypted         bool   `json:"encrypted"`
+	PasswordProtected bool   `json:"passwordProtected"`
+	Salt              string `json:"salt"`
+	KDFType           int    `json:"kdfType"`
+	KDFIterations     int    `json:"kdfIterations"`
+	KDFMemory         int    `json:"kdfMemory,omitempty"`
+	KDFParallelism    int    `json:"kdfParallelism,omitempty"`
+	EncKeyValidation  string `json:"encKeyValidation_DO_NOT_EDIT"`
+	Data              string `json:"data"`
+}
+
+// encryptExport wraps an unencrypted JSON export in the password protected
+// format of the Bitwarden clients: a PBKDF2-SHA256 key stretched with HKDF
+// into AES-256-CBC and HMAC-SHA256 keys.
+func encryptExport(data []byte, password string) ([]byte, error) {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+	// the clients derive the key from the base64 salt, not the raw bytes
+	b64Salt := base64.StdEncoding.EncodeToString(salt)
+	encKey, macKey, err := exportKeys(password, b64Salt, kdfConfig{Type: kdfPBKDF2, Iterations: exportKDFIterations})
+	if err != nil {
+		return nil, err
+	}
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	validation, err := encString([]byte(id), encKey, macKey)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := encString(data, encKey, macKey)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(encryptedExport{
+		Encrypted:         true,
+		PasswordProtected: true,
+		Salt:              b64Salt,
+		KDFType:           kdfPBKDF2,
+		KDFIterations:     exportKDFIterations,
+		EncKeyValidation:  validation,
+		Data:              payload,
+	}, "", "  ")
+}
+
+func exportKeys(password string, salt string, kdf kdfConfig) (encKey []byte, macKey []byte, err error) {
+	key, err := deriveKey(password, salt, kdf)
+	if err != nil {
+		return nil, nil, err
+	}
+	encKey = make([]byte, 32)
+	_, err = io.ReadFull(hkdf.Expand(sha256.New, key, []byte("enc")), encKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	macKey = make([]byte, 32)
+	_, err = io.ReadFull(hkdf.Expand(sha256.New, key, []byte("mac")), macKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encKey, macKey, nil
+}
+
+// encString encrypts plaintext into a type 2 cipher string,
+// "2.iv|ciphertext|mac" in base64.
+func encString(plaintext []byte, encKey []byte, macKey []byte) (string, error) {
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, aes.BlockSize)
+	_, err = rand.Read(iv)
+	if err != nil {
+		return "", err
+	}
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+	b64 := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("2.%s|%s|%s", b64(iv), b64(ciphertext), b64(mac.Sum(nil))), nil
+}
+
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
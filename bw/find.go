@@ -0,0 +1,98 @@
+package bw
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrItemNotFound  = errors.New("item not found")
+	ErrFieldNotFound = errors.New("field not found")
+)
+
+// AmbiguousError is returned when a query matches more than one item.
+type AmbiguousError struct {
+	Query   string
+	Matches []Item
+}
+
+func (e *AmbiguousError) Error() string {
+	names := make([]string, 0, len(e.Matches))
+	for _, item := range e.Matches {
+		names = append(names, fmt.Sprintf("%s (%s)", item.Name, item.ID))
+	}
+	return fmt.Sprintf("%q matches %d items: %s", e.Query, len(e.Matches), strings.Join(names, ", "))
+}
+
+// FindItem looks up a single item by ID or name. An exact ID match wins, then
+// exact name matches, then case-insensitive name matches, then
+// case-insensitive substring matches on the name.
+func (bwm *Manager) FindItem(query string) (Item, error) {
+	items, err := bwm.GetList()
+	if err != nil {
+		return Item{}, err
+	}
+	var exact, folded, partial []Item
+	q := strings.ToLower(query)
+	for _, item := range items {
+		if item.ID == query {
+			return item, nil
+		}
+		name := strings.ToLower(item.Name)
+		switch {
+		case item.Name == query:
+			exact = append(exact, item)
+		case name == q:
+			folded = append(folded, item)
+		case strings.Contains(name, q):
+			partial = append(partial, item)
+		}
+	}
+	matches := exact
+	if len(matches) == 0 {
+		matches = folded
+	}
+	if len(matches) == 0 {
+		matches = partial
+	}
+	switch len(matches) {
+	case 0:
+		return Item{}, fmt.Errorf("%w: %q", ErrItemNotFound, query)
+	case 1:
+		return matches[0], nil
+	default:
+		return Item{}, &AmbiguousError{Query: query, Matches: matches}
+	}
+}
+
+// FieldValue returns the value of a named field of an item. The well-known
+// names name, password, username, totp, notes and uri are tried first, then
+// custom fields (case-insensitive).
+func (item Item) FieldValue(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "name":
+		return item.Name, nil
+	case "password":
+		return item.Login.Password, nil
+	case "username":
+		return item.Login.Username, nil
+	case "notes":
+		return item.Notes, nil
+	case "totp":
+		code, _, err := TOTP(item.Login.TOTP, time.Now())
+		return code, err
+	case "uri", "url":
+		if len(item.Login.URIs) > 0 {
+			return item.Login.URIs[0].URI, nil
+		}
+		return "", nil
+	}
+	for _, f := range item.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q has no field %q", ErrFieldNotFound, item.Name, name)
+}
@@ -0,0 +1,213 @@
+package bw
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+var ErrGitCredentialHost = errors.New("credential request has no host")
+
+// GitCredential is a credential description in git's credential helper
+// protocol, see gitcredentials(7).
+type GitCredential struct {
+	Protocol string
+	Host     string
+	Path     string
+	Username string
+	Password string
+}
+
+// ReadGitCredential reads the key=value lines git sends to credential
+// helpers, up to a blank line or the end of input. Keys gobw has no use for,
+// like capability[] or wwwauth[], are ignored.
+func ReadGitCredential(r io.Reader) (GitCredential, error) {
+	var c GitCredential
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return c, fmt.Errorf("invalid credential line %q", line)
+		}
+		switch key {
+		case "protocol":
+			c.Protocol = value
+		case "host":
+			c.Host = value
+		case "path":
+			c.Path = value
+		case "username":
+			c.Username = value
+		case "password":
+			c.Password = value
+		case "url":
+			u, err := url.Parse(value)
+			if err != nil {
+				return c, fmt.Errorf("invalid credential url: %w", err)
+			}
+			c.Protocol = u.Scheme
+			c.Host = u.Host
+			c.Path = strings.TrimPrefix(u.Path, "/")
+			if u.User != nil {
+				c.Username = u.User.Username()
+				c.Password, _ = u.User.Password()
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return c, err
+	}
+	if c.Host == "" {
+		return c, ErrGitCredentialHost
+	}
+	return c, nil
+}
+
+// Write writes the credential in the format git reads back from helpers.
+func (c GitCredential) Write(w io.Writer) error {
+	for _, kv := range [][2]string{
+		{"protocol", c.Protocol},
+		{"host", c.Host},
+		{"path", c.Path},
+		{"username", c.Username},
+		{"password", c.Password},
+	} {
+		if kv[1] == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// URL returns the remote the credential is for.
+func (c GitCredential) URL() string {
+	protocol := c.Protocol
+	if protocol == "" {
+		protocol = "https"
+	}
+	u := protocol + "://" + c.Host
+	if c.Path != "" {
+		u += "/" + c.Path
+	}
+	return u
+}
+
+// gitCredentialScore ranks how closely a login URI matches the remote: the
+// longest path prefix wins over the bare host, which wins over a path the
+// remote does not name, which wins over the same site.
+func gitCredentialScore(c GitCredential, uri ItemLoginURI) int {
+	u, err := normalizeURL(uri.URI)
+	if err != nil {
+		return 0
+	}
+	if !strings.EqualFold(u.Host, c.Host) {
+		return 1
+	}
+	uriPath := strings.Trim(u.Path, "/")
+	path := strings.Trim(c.Path, "/")
+	switch {
+	case uriPath == "":
+		return 3
+	case path == "":
+		return 2
+	case path == uriPath || strings.HasPrefix(path, uriPath+"/"):
+		return 3 + len(uriPath)
+	default:
+		return 0
+	}
+}
+
+// FindGitCredential returns the login for a git remote. Logins are matched
+// on their URIs like autofill does and narrowed down to the username if git
+// already knows it. The login with the URI closest to the remote wins.
+func (bwm *Manager) FindGitCredential(c GitCredential) (Item, error) {
+	candidates, err := bwm.MatchURI(c.URL())
+	if err != nil {
+		return Item{}, err
+	}
+	best := 0
+	var matches []Item
+	for _, item := range candidates {
+		if item.Login.Password == "" {
+			continue
+		}
+		if c.Username != "" && item.Login.Username != c.Username {
+			continue
+		}
+		score := 0
+		for _, uri := range item.Login.URIs {
+			if s := gitCredentialScore(c, uri); s > score {
+				score = s
+			}
+		}
+		switch {
+		case score == 0 || score < best:
+		case score > best:
+			best = score
+			matches = []Item{item}
+		default:
+			matches = append(matches, item)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Item{}, fmt.Errorf("%w: %q", ErrItemNotFound, c.URL())
+	case 1:
+		return matches[0], nil
+	default:
+		return Item{}, &AmbiguousError{Query: c.URL(), Matches: matches}
+	}
+}
+
+// StoreGitCredential saves a credential git reports as working. The
+// password of a matching login is updated if it changed; otherwise a new
+// login named after the host is created.
+func (bwm *Manager) StoreGitCredential(c GitCredential) (Item, error) {
+	if c.Username == "" || c.Password == "" {
+		return Item{}, nil
+	}
+	item, err := bwm.FindGitCredential(c)
+	switch {
+	case err == nil:
+		if item.Login.Password == c.Password {
+			return item, nil
+		}
+		item, err = bwm.editItem(item.ID, func(raw map[string]any) {
+			login, _ := raw["login"].(map[string]any)
+			if login == nil {
+				login = make(map[string]any)
+				raw["login"] = login
+			}
+			login["password"] = c.Password
+		})
+		if err != nil {
+			return Item{}, fmt.Errorf("failed to update credential: %w", err)
+		}
+		return item, nil
+	case errors.Is(err, ErrItemNotFound):
+		// logins are for the whole host, git only sends paths when
+		// credential.useHttpPath is set
+		uri := GitCredential{Protocol: c.Protocol, Host: c.Host}.URL()
+		return bwm.CreateItem(Item{
+			Type: Login,
+			Name: c.Host,
+			Login: ItemLogin{
+				Username: c.Username,
+				Password: c.Password,
+				URIs:     []ItemLoginURI{{Match: MatchDefault, URI: uri}},
+			},
+		})
+	default:
+		return Item{}, err
+	}
+}
@@ -0,0 +1,224 @@
+package bw
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nbutton23/zxcvbn-go"
+)
+
+// HealthOptions tunes the vault health report.
+type HealthOptions struct {
+	// MaxPasswordAge is how old a password may get before it is reported.
+	MaxPasswordAge time.Duration
+	// WeakScore is the highest strength score (0-4) reported as weak.
+	WeakScore int
+	// TwoFactorDomains are extra domains known to support TOTP.
+	TwoFactorDomains []string
+	// Breaches, if set, is checked for breached passwords.
+	Breaches BreachSource
+}
+
+func DefaultHealthOptions() HealthOptions {
+	return HealthOptions{
+		MaxPasswordAge: 365 * 24 * time.Hour,
+		WeakScore:      2,
+	}
+}
+
+// HealthItem identifies the item a finding is about. Secrets are never part
+// of a report so it can be exported safely.
+type HealthItem struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Username string `json:"username,omitempty"`
+}
+
+func newHealthItem(item Item) HealthItem {
+	return HealthItem{
+		ID:       item.ID,
+		Name:     item.Name,
+		Username: item.Login.Username,
+	}
+}
+
+type WeakPassword struct {
+	Item HealthItem `json:"item"`
+	// Score is the zxcvbn strength score from 0 (worst) to 4.
+	Score     int     `json:"score"`
+	Entropy   float64 `json:"entropy"`
+	CrackTime string  `json:"crackTime"`
+}
+
+type ReusedPassword struct {
+	Items []HealthItem `json:"items"`
+}
+
+type OldPassword struct {
+	Item         HealthItem `json:"item"`
+	PasswordDate time.Time  `json:"passwordDate"`
+	AgeDays      int        `json:"ageDays"`
+}
+
+type InsecureURI struct {
+	Item HealthItem `json:"item"`
+	URI  string     `json:"uri"`
+}
+
+type MissingTwoFactor struct {
+	Item   HealthItem `json:"item"`
+	Domain string     `json:"domain"`
+}
+
+type HealthReport struct {
+	Generated        time.Time          `json:"generated"`
+	Weak             []WeakPassword     `json:"weak"`
+	Reused           []ReusedPassword   `json:"reused"`
+	Old              []OldPassword      `json:"old"`
+	InsecureURIs     []InsecureURI      `json:"insecureUris"`
+	MissingTwoFactor []MissingTwoFactor `json:"missingTwoFactor"`
+	// Breached is only set when the report was run against a breach source.
+	Breached []BreachedPassword `json:"breached,omitempty"`
+}
+
+// PasswordDate is when the password of a login was last changed. Logins whose
+// password never changed report their creation date.
+func PasswordDate(item Item) time.Time {
+	if !item.Login.PasswordRevisionDate.IsZero() {
+		return item.Login.PasswordRevisionDate
+	}
+	return item.CreationDate
+}
+
+// strengthInputs are the item-specific words zxcvbn should penalize, like
+// the Bitwarden clients do with the name, username and domains.
+func strengthInputs(item Item) []string {
+	inputs := []string{item.Name}
+	if item.Login.Username != "" {
+		inputs = append(inputs, item.Login.Username)
+		if user, _, ok := strings.Cut(item.Login.Username, "@"); ok {
+			inputs = append(inputs, user)
+		}
+	}
+	for _, uri := range item.Login.URIs {
+		if u, err := normalizeURL(uri.URI); err == nil && u.Hostname() != "" {
+			domain := baseDomain(u.Hostname())
+			inputs = append(inputs, domain, strings.Split(domain, ".")[0])
+		}
+	}
+	return inputs
+}
+
+// HealthReport analyses the logins held by the manager.
+func (bwm *Manager) HealthReport(opts HealthOptions) (*HealthReport, error) {
+	items, err := bwm.GetList()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	report := HealthReport{Generated: now}
+	twoFactor := make(map[string]bool)
+	for _, d := range append(TwoFactorDomains(), opts.TwoFactorDomains...) {
+		twoFactor[strings.ToLower(d)] = true
+	}
+	byPassword := make(map[string][]HealthItem)
+
+	for _, item := range items {
+		if item.Type != Login {
+			continue
+		}
+		hi := newHealthItem(item)
+		if pw := item.Login.Password; pw != "" {
+			strength := zxcvbn.PasswordStrength(pw, strengthInputs(item))
+			if strength.Score <= opts.WeakScore {
+				report.Weak = append(report.Weak, WeakPassword{
+					Item:      hi,
+					Score:     strength.Score,
+					Entropy:   strength.Entropy,
+					CrackTime: strength.CrackTimeDisplay,
+				})
+			}
+			byPassword[pw] = append(byPassword[pw], hi)
+
+			date := PasswordDate(item)
+			if opts.MaxPasswordAge > 0 && !date.IsZero() && now.Sub(date) > opts.MaxPasswordAge {
+				report.Old = append(report.Old, OldPassword{
+					Item:         hi,
+					PasswordDate: date,
+					AgeDays:      int(now.Sub(date).Hours() / 24),
+				})
+			}
+		}
+
+		missing := ""
+		for _, uri := range item.Login.URIs {
+			u, err := url.Parse(strings.TrimSpace(uri.URI))
+			if err == nil && strings.EqualFold(u.Scheme, "http") {
+				report.InsecureURIs = append(report.InsecureURIs, InsecureURI{Item: hi, URI: uri.URI})
+			}
+			if nu, err := normalizeURL(uri.URI); err == nil && missing == "" {
+				if domain := baseDomain(nu.Hostname()); twoFactor[domain] {
+					missing = domain
+				}
+			}
+		}
+		if missing != "" && item.Login.TOTP == "" {
+			report.MissingTwoFactor = append(report.MissingTwoFactor, MissingTwoFactor{Item: hi, Domain: missing})
+		}
+	}
+
+	for _, group := range byPassword {
+		if len(group) > 1 {
+			report.Reused = append(report.Reused, ReusedPassword{Items: group})
+		}
+	}
+	sort.Slice(report.Reused, func(i, j int) bool {
+		if len(report.Reused[i].Items) != len(report.Reused[j].Items) {
+			return len(report.Reused[i].Items) > len(report.Reused[j].Items)
+		}
+		return report.Reused[i].Items[0].Name < report.Reused[j].Items[0].Name
+	})
+	sort.SliceStable(report.Weak, func(i, j int) bool {
+		return report.Weak[i].Score < report.Weak[j].Score
+	})
+	sort.SliceStable(report.Old, func(i, j int) bool {
+		return report.Old[i].AgeDays > report.Old[j].AgeDays
+	})
+	if opts.Breaches != nil {
+		report.Breached, err = breachedPasswords(items, opts.Breaches)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &report, nil
+}
+
+// TwoFactorDomains returns domains of popular sites known to support TOTP
+// two-factor authentication, after the list published by 2fa.directory.
+func TwoFactorDomains() []string {
+	return []string{
+		"1password.com", "adobe.com", "airbnb.com", "amazon.com", "apple.com",
+		"atlassian.com", "atlassian.net", "autodesk.com", "azure.com",
+		"backblaze.com", "battle.net", "binance.com",
+		"bitbucket.org", "bitwarden.com", "box.com", "cloudflare.com",
+		"coinbase.com", "digitalocean.com", "discord.com", "docker.com",
+		"dropbox.com", "ebay.com", "epicgames.com", "evernote.com",
+		"facebook.com", "fastmail.com", "figma.com", "gandi.net", "gitea.com",
+		"github.com", "gitlab.com", "godaddy.com", "google.com",
+		"heroku.com", "hetzner.com", "hubspot.com", "ifttt.com",
+		"instagram.com", "kraken.com", "linkedin.com", "linode.com",
+		"live.com", "mailchimp.com", "mega.nz", "microsoft.com",
+		"namecheap.com", "netlify.com", "nintendo.com", "notion.so",
+		"npmjs.com", "nvidia.com", "okta.com", "openai.com", "ovh.com",
+		"paypal.com", "playstation.com", "porkbun.com", "proton.me",
+		"protonmail.com", "pypi.org", "reddit.com", "rubygems.org",
+		"salesforce.com", "scaleway.com", "sentry.io", "shopify.com",
+		"slack.com", "snapchat.com", "sourceforge.net", "stripe.com",
+		"tailscale.com", "terraform.io", "tiktok.com", "tumblr.com",
+		"tutanota.com", "twilio.com", "twitch.tv", "twitter.com",
+		"ubisoft.com", "vercel.com", "vultr.com", "wordpress.com", "x.com",
+		"xbox.com", "yahoo.com", "zoho.com", "zoom.us",
+	}
+}
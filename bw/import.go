@@ -0,0 +1,510 @@
+package bw
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportFormat names match the format names of `bw import` where there is
+// one.
+type ImportFormat string
+
+const (
+	ImportBitwardenCSV  ImportFormat = "bitwardencsv"
+	ImportBitwardenJSON ImportFormat = "bitwardenjson"
+	ImportLastPassCSV   ImportFormat = "lastpasscsv"
+	Import1PUX          ImportFormat = "1password1pux"
+	ImportChromeCSV     ImportFormat = "chromecsv"
+	ImportFirefoxCSV    ImportFormat = "firefoxcsv"
+	ImportPass          ImportFormat = "pass"
+	ImportKDBX          ImportFormat = "kdbx"
+)
+
+var (
+	ErrUnknownImportFormat = errors.New("unknown import format")
+	// ErrImportPassword is returned when the file is encrypted and no
+	// password was given, so the caller can ask for one and try again.
+	ErrImportPassword = errors.New("the file is password protected")
+)
+
+func ImportFormats() []ImportFormat {
+	return []ImportFormat{
+		ImportBitwardenCSV, ImportBitwardenJSON, ImportLastPassCSV, Import1PUX,
+		ImportChromeCSV, ImportFirefoxCSV, ImportPass, ImportKDBX,
+	}
+}
+
+func ParseImportFormat(s string) (ImportFormat, error) {
+	for _, f := range ImportFormats() {
+		if string(f) == strings.ToLower(s) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownImportFormat, s)
+}
+
+// csvHeaders are the first columns that tell the CSV exports apart.
+var csvHeaders = map[ImportFormat][]string{
+	ImportBitwardenCSV: {"folder", "favorite", "type", "name"},
+	ImportLastPassCSV:  {"url", "username", "password"},
+	ImportChromeCSV:    {"name", "url", "username", "password"},
+	ImportFirefoxCSV:   {"url", "username", "password", "httprealm"},
+}
+
+// DetectImportFormat guesses the format of path: directories are password
+// stores, other files go by their extension and, for CSV, their header row.
+func DetectImportFormat(path string) (ImportFormat, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return ImportPass, nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".kdbx":
+		return ImportKDBX, nil
+	case ".1pux":
+		return Import1PUX, nil
+	case ".json":
+		return ImportBitwardenJSON, nil
+	case ".csv":
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		header, err := csv.NewReader(f).Read()
+		if err != nil {
+			return "", fmt.Errorf("failed to read CSV header: %w", err)
+		}
+		normalizeHeader(header)
+		// Firefox goes first since its header starts like LastPass's
+		for _, format := range []ImportFormat{ImportFirefoxCSV, ImportBitwardenCSV, ImportLastPassCSV, ImportChromeCSV} {
+			if hasPrefix(header, csvHeaders[format]) {
+				return format, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: cannot tell the format of %s", ErrUnknownImportFormat, path)
+}
+
+// normalizeHeader lowercases the column names and drops the byte order mark
+// some exporters start with.
+func normalizeHeader(header []string) []string {
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff")))
+	}
+	return header
+}
+
+func hasPrefix(header []string, prefix []string) bool {
+	if len(header) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if header[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadImport parses an export of another password manager. password is only
+// used for encrypted formats; ErrImportPassword is returned if one is needed
+// but empty.
+func ReadImport(format ImportFormat, path string, password string) ([]ImportedItem, error) {
+	if format == ImportPass {
+		return readPass(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch format {
+	case ImportBitwardenCSV:
+		return readCSV(f, readBitwardenCSV)
+	case ImportLastPassCSV:
+		return readCSV(f, readLastPassCSV)
+	case ImportChromeCSV:
+		return readCSV(f, readChromeCSV)
+	case ImportFirefoxCSV:
+		return readCSV(f, readFirefoxCSV)
+	case ImportBitwardenJSON:
+		return readBitwardenJSON(f, password)
+	case Import1PUX:
+		return read1PUX(f)
+	case ImportKDBX:
+		if password == "" {
+			return nil, ErrImportPassword
+		}
+		return ReadKDBX(f, password)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownImportFormat, format)
+	}
+}
+
+// readCSV reads a CSV file with a header row and hands every record to row as
+// a map from lowercase column name to value.
+func readCSV(r io.Reader, row func(map[string]string) (ImportedItem, bool)) ([]ImportedItem, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := normalizeHeader(records[0])
+	var items []ImportedItem
+	for _, record := range records[1:] {
+		values := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				values[column] = record[i]
+			}
+		}
+		if item, ok := row(values); ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func loginURIs(uris ...string) []ItemLoginURI {
+	var out []ItemLoginURI
+	for _, u := range uris {
+		if u = strings.TrimSpace(u); u != "" {
+			out = append(out, ItemLoginURI{Match: MatchDefault, URI: u})
+		}
+	}
+	return out
+}
+
+func readBitwardenCSV(row map[string]string) (ImportedItem, bool) {
+	item := Item{
+		Type:     Login,
+		Name:     row["name"],
+		Notes:    row["notes"],
+		Favorite: row["favorite"] == "1",
+	}
+	if row["type"] == "note" {
+		item.Type = SecureNote
+	} else {
+		item.Login = ItemLogin{
+			URIs:     loginURIs(strings.Split(row["login_uri"], ",")...),
+			Username: row["login_username"],
+			Password: row["login_password"],
+			TOTP:     row["login_totp"],
+		}
+	}
+	if row["reprompt"] == "1" {
+		item.Reprompt = 1
+	}
+	for _, line := range strings.Split(row["fields"], "\n") {
+		if line == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(line, ": ")
+		item.Fields = append(item.Fields, ItemField{Name: name, Value: value, Type: FieldText})
+	}
+	return ImportedItem{Item: item, Folder: row["folder"]}, true
+}
+
+// lastPassNoteURL marks secure notes in LastPass exports.
+const lastPassNoteURL = "http://sn"
+
+func readLastPassCSV(row map[string]string) (ImportedItem, bool) {
+	item := Item{
+		Type:     Login,
+		Name:     row["name"],
+		Notes:    row["extra"],
+		Favorite: row["fav"] == "1",
+	}
+	if row["url"] == lastPassNoteURL {
+		item.Type = SecureNote
+	} else {
+		item.Login = ItemLogin{
+			URIs:     loginURIs(row["url"]),
+			Username: row["username"],
+			Password: row["password"],
+			TOTP:     row["totp"],
+		}
+	}
+	// LastPass nests folders with backslashes
+	folder := strings.ReplaceAll(row["grouping"], `\`, "/")
+	return ImportedItem{Item: item, Folder: folder}, true
+}
+
+func readChromeCSV(row map[string]string) (ImportedItem, bool) {
+	item := Item{
+		Type:  Login,
+		Name:  row["name"],
+		Notes: row["note"],
+		Login: ItemLogin{
+			URIs:     loginURIs(row["url"]),
+			Username: row["username"],
+			Password: row["password"],
+		},
+	}
+	if item.Name == "" {
+		item.Name = hostName(row["url"])
+	}
+	return ImportedItem{Item: item}, true
+}
+
+func readFirefoxCSV(row map[string]string) (ImportedItem, bool) {
+	// the Firefox account itself is stored as a login of the browser
+	if strings.HasPrefix(row["url"], "chrome://") {
+		return ImportedItem{}, false
+	}
+	item := Item{
+		Type: Login,
+		Name: hostName(row["url"]),
+		Login: ItemLogin{
+			URIs:     loginURIs(row["url"]),
+			Username: row["username"],
+			Password: row["password"],
+		},
+	}
+	if ms, err := strconv.ParseInt(row["timepasswordchanged"], 10, 64); err == nil && ms > 0 {
+		item.Login.PasswordRevisionDate = time.UnixMilli(ms).UTC()
+	}
+	return ImportedItem{Item: item}, true
+}
+
+// hostName names logins after their site when the export has no names.
+func hostName(raw string) string {
+	u, err := normalizeURL(raw)
+	if err != nil || u.Hostname() == "" {
+		return raw
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+func readBitwardenJSON(r io.Reader, password string) ([]ImportedItem, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var encrypted encryptedExport
+	err = json.Unmarshal(data, &encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Bitwarden JSON: %w", err)
+	}
+	if encrypted.Encrypted {
+		if !encrypted.PasswordProtected {
+			return nil, fmt.Errorf("%w: exports encrypted with the account key cannot be read outside the account", ErrUnknownImportFormat)
+		}
+		if password == "" {
+			return nil, ErrImportPassword
+		}
+		data, err = decryptExport(encrypted, password)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var doc struct {
+		Folders []exportFolder `json:"folders"`
+		Items   []Item         `json:"items"`
+	}
+	err = json.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Bitwarden JSON: %w", err)
+	}
+	folders := make(map[string]string, len(doc.Folders))
+	for _, f := range doc.Folders {
+		folders[f.ID] = f.Name
+	}
+	items := make([]ImportedItem, 0, len(doc.Items))
+	for _, item := range doc.Items {
+		folder := folders[item.FolderID]
+		// the IDs belong to the exporting account
+		item.ID = ""
+		item.FolderID = ""
+		item.OrganizationID = ""
+		item.CollectionIDs = nil
+		item.Attachments = nil
+		items = append(items, ImportedItem{Item: item, Folder: folder})
+	}
+	return items, nil
+}
+
+var ErrImportPasswordInvalid = errors.New("invalid file password")
+
+// decryptExport reverses encryptExport, and reads the exports of Argon2id
+// accounts too.
+func decryptExport(e encryptedExport, password string) ([]byte, error) {
+	kdf := kdfConfig{Type: e.KDFType, Iterations: e.KDFIterations, Memory: e.KDFMemory, Parallelism: e.KDFParallelism}
+	encKey, macKey, err := exportKeys(password, e.Salt, kdf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Bitwarden JSON: %w", err)
+	}
+	if _, err := decString(e.EncKeyValidation, encKey, macKey); err != nil {
+		return nil, ErrImportPasswordInvalid
+	}
+	return decString(e.Data, encKey, macKey)
+}
+
+// decString decrypts a type 2 cipher string made by encString.
+func decString(s string, encKey []byte, macKey []byte) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "2."), "|")
+	if !strings.HasPrefix(s, "2.") || len(parts) != 3 {
+		return nil, fmt.Errorf("%w: invalid cipher string", ErrUnknownImportFormat)
+	}
+	raw := make([][]byte, 3)
+	for i, p := range parts {
+		var err error
+		raw[i], err = base64.StdEncoding.DecodeString(p)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid cipher string", ErrUnknownImportFormat)
+		}
+	}
+	iv, ciphertext, sum := raw[0], raw[1], raw[2]
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), sum) {
+		return nil, ErrImportPasswordInvalid
+	}
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: invalid cipher string", ErrUnknownImportFormat)
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.HasSuffix(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("%w: invalid padding", ErrUnknownImportFormat)
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// ImportedItem is an item read from another password manager that is not in
+// the vault yet.
+type ImportedItem struct {
+	Item Item
+	// Folder is the "/" separated folder path, empty for none.
+	Folder      string
+	Attachments []ImportedAttachment
+	// DuplicateOf is the ID of a vault item that looks like the same entry,
+	// set by MarkDuplicates.
+	DuplicateOf string
+}
+
+type ImportedAttachment struct {
+	Name string
+	Data []byte
+}
+
+// duplicateKey identifies what makes two items the same entry: the account
+// and site for logins, the name for everything else.
+func duplicateKey(item Item) string {
+	if item.Type == Login && (item.Login.Username != "" || len(item.Login.URIs) > 0) {
+		host := ""
+		if len(item.Login.URIs) > 0 {
+			host = hostName(item.Login.URIs[0].URI)
+		}
+		return fmt.Sprintf("%d\x00%s\x00%s", item.Type, strings.ToLower(item.Login.Username), strings.ToLower(host))
+	}
+	return fmt.Sprintf("%d\x00%s", item.Type, strings.ToLower(strings.TrimSpace(item.Name)))
+}
+
+// MarkDuplicates sets DuplicateOf on the imported items that are already in
+// the vault.
+func (bwm *Manager) MarkDuplicates(items []ImportedItem) {
+	vault := bwm.itemList()
+	existing := make(map[string]string, len(vault))
+	for _, item := range vault {
+		key := duplicateKey(item)
+		if _, ok := existing[key]; !ok {
+			existing[key] = item.ID
+		}
+	}
+	for i := range items {
+		items[i].DuplicateOf = existing[duplicateKey(items[i].Item)]
+	}
+}
+
+type ImportOptions struct {
+	// Folder is prepended to the folder of every imported item, so an import
+	// can be kept apart from the rest of the vault.
+	Folder string
+}
+
+type ImportResult struct {
+	Imported    int
+	Folders     int
+	Attachments int
+}
+
+// JoinFolder joins folder path elements, skipping empty ones.
+func JoinFolder(elems ...string) string {
+	parts := make([]string, 0, len(elems))
+	for _, e := range elems {
+		if e = strings.Trim(e, "/"); e != "" {
+			parts = append(parts, e)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// folderID returns the ID of the folder with the given name, creating it if it
+// does not exist yet.
+func (bwm *Manager) folderID(name string, result *ImportResult) (string, error) {
+	id, created, err := bwm.ensureFolder(name)
+	if created {
+		result.Folders++
+	}
+	return id, err
+}
+
+// Import creates the items in the vault, along with their folders and
+// attachments. On failure the result counts what was created up to then.
+func (bwm *Manager) Import(items []ImportedItem, opts ImportOptions) (ImportResult, error) {
+	var result ImportResult
+	if bwm.VaultStatus.Status != Unlocked {
+		return result, ErrLocked
+	}
+	defer bwm.startBatch()()
+	for _, imported := range items {
+		item := imported.Item
+		var err error
+		item.FolderID, err = bwm.folderID(JoinFolder(opts.Folder, imported.Folder), &result)
+		if err != nil {
+			return result, fmt.Errorf("failed to import %q: %w", item.Name, err)
+		}
+		created, err := bwm.CreateItem(item)
+		if err != nil {
+			return result, fmt.Errorf("failed to import %q: %w", item.Name, err)
+		}
+		result.Imported++
+		for _, a := range imported.Attachments {
+			_, err = bwm.CreateAttachment(created.ID, a.Name, a.Data)
+			if err != nil {
+				return result, fmt.Errorf("failed to import %q: %w", item.Name, err)
+			}
+			result.Attachments++
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,269 @@
+package bw
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// onePUXDataFile is the JSON document inside a 1PUX archive.
+const onePUXDataFile = "export.data"
+
+type onePUXExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePUXItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePUXField struct {
+	Title string                     `json:"title"`
+	ID    string                     `json:"id"`
+	Value map[string]json.RawMessage `json:"value"`
+}
+
+type onePUXItem struct {
+	FavIndex     int    `json:"favIndex"`
+	CreatedAt    int64  `json:"createdAt"`
+	UpdatedAt    int64  `json:"updatedAt"`
+	CategoryUUID string `json:"categoryUuid"`
+	Details      struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Name        string `json:"name"`
+			FieldType   string `json:"fieldType"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Sections   []struct {
+			Title  string        `json:"title"`
+			Fields []onePUXField `json:"fields"`
+		} `json:"sections"`
+		PasswordHistory []struct {
+			Value string `json:"value"`
+			Time  int64  `json:"time"`
+		} `json:"passwordHistory"`
+		Password string `json:"password"`
+	} `json:"details"`
+	Overview struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+		URLs  []struct {
+			URL string `json:"url"`
+		} `json:"urls"`
+	} `json:"overview"`
+}
+
+// 1Password item categories that have a Bitwarden counterpart. Everything
+// else becomes a secure note with the details as fields.
+const (
+	onePUXLogin      = "001"
+	onePUXCreditCard = "002"
+	onePUXSecureNote = "003"
+	onePUXIdentity   = "004"
+	onePUXPassword   = "005"
+)
+
+// read1PUX reads a 1Password 1PUX export. Vaults become folders; attached
+// documents are not imported.
+func read1PUX(r io.Reader) ([]ImportedItem, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read 1PUX archive: %w", err)
+	}
+	f, err := zr.Open(onePUXDataFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read 1PUX archive: %w", err)
+	}
+	defer f.Close()
+	var export onePUXExport
+	err = json.NewDecoder(f).Decode(&export)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read 1PUX archive: %w", err)
+	}
+	var items []ImportedItem
+	for _, account := range export.Accounts {
+		for _, vault := range account.Vaults {
+			for _, it := range vault.Items {
+				items = append(items, ImportedItem{Item: it.item(), Folder: vault.Attrs.Name})
+			}
+		}
+	}
+	return items, nil
+}
+
+// fieldValue renders the typed value of a 1Password field as text.
+func (f onePUXField) fieldValue() (kind string, value string) {
+	// a value has exactly one key naming its type
+	kinds := make([]string, 0, len(f.Value))
+	for k := range f.Value {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	if len(kinds) == 0 {
+		return "", ""
+	}
+	kind = kinds[0]
+	raw := f.Value[kind]
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return kind, s
+	}
+	var n json.Number
+	if json.Unmarshal(raw, &n) == nil {
+		if secs, err := n.Int64(); err == nil && kind == "date" {
+			return kind, time.Unix(secs, 0).UTC().Format("2006-01-02")
+		}
+		return kind, n.String()
+	}
+	var obj map[string]any
+	if json.Unmarshal(raw, &obj) == nil {
+		if email, ok := obj["email_address"].(string); ok {
+			return kind, email
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			if v, ok := obj[k].(string); ok && v != "" {
+				parts = append(parts, v)
+			}
+		}
+		return kind, strings.Join(parts, ", ")
+	}
+	return kind, ""
+}
+
+func (it onePUXItem) item() Item {
+	item := Item{
+		Name:         it.Overview.Title,
+		Notes:        it.Details.NotesPlain,
+		Favorite:     it.FavIndex > 0,
+		CreationDate: time.Unix(it.CreatedAt, 0).UTC(),
+		RevisionDate: time.Unix(it.UpdatedAt, 0).UTC(),
+	}
+	switch it.CategoryUUID {
+	case onePUXLogin, onePUXPassword:
+		item.Type = Login
+	case onePUXCreditCard:
+		item.Type = Card
+	case onePUXIdentity:
+		item.Type = Identity
+	default:
+		item.Type = SecureNote
+	}
+
+	if item.Type == Login {
+		var uris []string
+		if it.Overview.URL != "" {
+			uris = append(uris, it.Overview.URL)
+		}
+		for _, u := range it.Overview.URLs {
+			if u.URL != it.Overview.URL {
+				uris = append(uris, u.URL)
+			}
+		}
+		item.Login.URIs = loginURIs(uris...)
+		item.Login.Password = it.Details.Password
+		for _, f := range it.Details.LoginFields {
+			switch {
+			case f.Designation == "username":
+				item.Login.Username = f.Value
+			case f.Designation == "password":
+				item.Login.Password = f.Value
+			case f.Value != "":
+				fieldType := FieldText
+				if f.FieldType == "P" {
+					fieldType = FieldHidden
+				}
+				item.Fields = append(item.Fields, ItemField{Name: f.Name, Value: f.Value, Type: fieldType})
+			}
+		}
+		for _, h := range it.Details.PasswordHistory {
+			item.PasswordHistory = append(item.PasswordHistory, ItemPasswordHistory{
+				LastUsedDate: time.Unix(h.Time, 0).UTC(),
+				Password:     h.Value,
+			})
+		}
+	}
+
+	mapped := map[string]*string{}
+	switch item.Type {
+	case Card:
+		mapped = map[string]*string{
+			"cardholder": &item.Card.CardholderName,
+			"type":       &item.Card.Brand,
+			"ccnum":      &item.Card.Number,
+			"cvv":        &item.Card.Code,
+		}
+	case Identity:
+		mapped = map[string]*string{
+			"firstname": &item.Identity.FirstName,
+			"initial":   &item.Identity.MiddleName,
+			"lastname":  &item.Identity.LastName,
+			"company":   &item.Identity.Company,
+			"email":     &item.Identity.Email,
+			"defphone":  &item.Identity.Phone,
+			"username":  &item.Identity.Username,
+		}
+	}
+	for _, section := range it.Details.Sections {
+		for _, f := range section.Fields {
+			kind, value := f.fieldValue()
+			switch {
+			case value == "":
+			case kind == "totp" && item.Type == Login && item.Login.TOTP == "":
+				item.Login.TOTP = value
+			case mapped[f.ID] != nil:
+				*mapped[f.ID] = value
+			case item.Type == Card && f.ID == "expiry" && len(value) == 6:
+				// month and year are stored as the number YYYYMM
+				item.Card.ExpYear = value[:4]
+				month, _ := strconv.Atoi(value[4:])
+				item.Card.ExpMonth = strconv.Itoa(month)
+			case item.Type == Identity && kind == "address" && item.Identity.Address1 == "":
+				var a struct {
+					Street  string `json:"street"`
+					City    string `json:"city"`
+					State   string `json:"state"`
+					Zip     string `json:"zip"`
+					Country string `json:"country"`
+				}
+				_ = json.Unmarshal(f.Value[kind], &a)
+				item.Identity.Address1 = a.Street
+				item.Identity.City = a.City
+				item.Identity.State = a.State
+				item.Identity.PostalCode = a.Zip
+				item.Identity.Country = a.Country
+			default:
+				fieldType := FieldText
+				if kind == "concealed" || kind == "totp" {
+					fieldType = FieldHidden
+				}
+				name := f.Title
+				if name == "" {
+					name = f.ID
+				}
+				item.Fields = append(item.Fields, ItemField{Name: name, Value: value, Type: fieldType})
+			}
+		}
+	}
+	return item
+}
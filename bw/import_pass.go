@@ -0,0 +1,87 @@
+package bw
+
+import (
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// passUsernameKeys and passURLKeys are the "key: value" lines of a pass entry
+// that map to login fields, following the conventions of the pass browser
+// extensions.
+var (
+	passUsernameKeys = map[string]bool{"login": true, "username": true, "user": true}
+	passURLKeys      = map[string]bool{"url": true, "website": true, "site": true}
+)
+
+// readPass reads a password-store directory. Every .gpg file is decrypted with
+// gpg, so the agent may ask for the key's passphrase; directories become
+// folders.
+func readPass(dir string) ([]ImportedItem, error) {
+	var items []ImportedItem
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// .git and .extensions are not entries
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".gpg" {
+			return nil
+		}
+		out, err := exec.Command("gpg", "--quiet", "--batch", "--decrypt", path).Output() // #nosec G204
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", path, err)
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(strings.TrimSuffix(rel, ".gpg"))
+		folder, name := "", rel
+		if i := strings.LastIndex(rel, "/"); i >= 0 {
+			folder, name = rel[:i], rel[i+1:]
+		}
+		items = append(items, ImportedItem{Item: parsePassEntry(name, string(out)), Folder: folder})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password store: %w", err)
+	}
+	return items, nil
+}
+
+// parsePassEntry reads the password from the first line and the username, URL
+// and otpauth URI from the rest. Everything else is kept as the notes.
+func parsePassEntry(name string, content string) Item {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	item := Item{Type: Login, Name: name}
+	item.Login.Password = strings.TrimRight(lines[0], "\r")
+	var notes []string
+	for _, line := range lines[1:] {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "otpauth://") && item.Login.TOTP == "" {
+			item.Login.TOTP = line
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch {
+		case ok && passUsernameKeys[key] && item.Login.Username == "":
+			item.Login.Username = value
+		case ok && passURLKeys[key]:
+			item.Login.URIs = append(item.Login.URIs, loginURIs(value)...)
+		default:
+			notes = append(notes, line)
+		}
+	}
+	item.Notes = strings.TrimSpace(strings.Join(notes, "\n"))
+	return item
+}
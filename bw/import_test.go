@@ -0,0 +1,83 @@
+package bw
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// testExport encrypts a one-item export with kdf like the Bitwarden clients.
+func testExport(t *testing.T, password string, kdf kdfConfig) []byte {
+	t.Helper()
+	const salt = "c2FsdHNhbHRzYWx0c2FsdA=="
+	encKey, macKey, err := exportKeys(password, salt, kdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validation, err := encString([]byte("check"), encKey, macKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := encString([]byte(`{"items":[{"type":1,"name":"Example","login":{"password":"hunter2"}}]}`), encKey, macKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(encryptedExport{
+		Encrypted:         true,
+		PasswordProtected: true,
+		Salt:              salt,
+		KDFType:           kdf.Type,
+		KDFIterations:     kdf.Iterations,
+		KDFMemory:         kdf.Memory,
+		KDFParallelism:    kdf.Parallelism,
+		EncKeyValidation:  validation,
+		Data:              payload,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReadPasswordProtectedExport(t *testing.T) {
+	for name, kdf := range map[string]kdfConfig{
+		"pbkdf2":   {Type: kdfPBKDF2, Iterations: 1000},
+		"argon2id": {Type: kdfArgon2id, Iterations: 2, Memory: 16, Parallelism: 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			data := testExport(t, "correct horse", kdf)
+			items, err := readBitwardenJSON(bytes.NewReader(data), "correct horse")
+			if err != nil {
+				t.Fatalf("right password: %v", err)
+			}
+			if len(items) != 1 || items[0].Item.Login.Password != "hunter2" {
+				t.Errorf("unexpected items: %+v", items)
+			}
+			_, err = readBitwardenJSON(bytes.NewReader(data), "wrong")
+			if !errors.Is(err, ErrImportPasswordInvalid) {
+				t.Errorf("wrong password: got %v, want %v", err, ErrImportPasswordInvalid)
+			}
+		})
+	}
+
+	// the settings come from the file, so absurd ones must not hang the import
+	for name, e := range map[string]encryptedExport{
+		"pbkdf2 iterations":   {KDFType: kdfPBKDF2, KDFIterations: 1 << 30},
+		"argon2id iterations": {KDFType: kdfArgon2id, KDFIterations: 1 << 20, KDFMemory: 16, KDFParallelism: 1},
+		"argon2id memory":     {KDFType: kdfArgon2id, KDFIterations: 2, KDFMemory: 1 << 20, KDFParallelism: 1},
+		"unknown type":        {KDFType: 7, KDFIterations: 1000},
+	} {
+		t.Run(name, func(t *testing.T) {
+			e.Encrypted, e.PasswordProtected = true, true
+			data, err := json.Marshal(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = readBitwardenJSON(bytes.NewReader(data), "pw")
+			if !errors.Is(err, ErrUnsupportedKDF) {
+				t.Errorf("got %v, want %v", err, ErrUnsupportedKDF)
+			}
+		})
+	}
+}
@@ -0,0 +1,414 @@
+package bw
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sapslaj/gobw/kdbx"
+)
+
+// keePassRootName names the root group of exported databases.
+const keePassRootName = "Bitwarden"
+
+// keePassFavoriteTag marks favorites, which KeePass has no notion of.
+const keePassFavoriteTag = "favorite"
+
+// keePassURLPrefix is the Keepass2Android convention for additional URLs,
+// which KeePassXC also understands.
+const keePassURLPrefix = "KP2A_URL"
+
+// keePassField maps an item property to an entry string field.
+type keePassField struct {
+	key       string
+	value     *string
+	protected bool
+}
+
+func keePassCardFields(c *ItemCard) []keePassField {
+	return []keePassField{
+		{"Cardholder Name", &c.CardholderName, false},
+		{"Brand", &c.Brand, false},
+		{"Card Number", &c.Number, true},
+		{"Expiration Month", &c.ExpMonth, false},
+		{"Expiration Year", &c.ExpYear, false},
+		{"Security Code", &c.Code, true},
+	}
+}
+
+func keePassIdentityFields(i *ItemIdentity) []keePassField {
+	return []keePassField{
+		{"Identity Title", &i.Title, false},
+		{"First Name", &i.FirstName, false},
+		{"Middle Name", &i.MiddleName, false},
+		{"Last Name", &i.LastName, false},
+		{"Address 1", &i.Address1, false},
+		{"Address 2", &i.Address2, false},
+		{"Address 3", &i.Address3, false},
+		{"City", &i.City, false},
+		{"State", &i.State, false},
+		{"Postal Code", &i.PostalCode, false},
+		{"Country", &i.Country, false},
+		{"Company", &i.Company, false},
+		{"Email", &i.Email, false},
+		{"Phone", &i.Phone, false},
+		{"SSN", &i.SSN, true},
+		{"Identity Username", &i.Username, false},
+		{"Passport Number", &i.PassportNumber, true},
+		{"License Number", &i.LicenseNumber, true},
+	}
+}
+
+func keePassSSHKeyFields(k *ItemSSHKey) []keePassField {
+	return []keePassField{
+		{"Private Key", &k.PrivateKey, true},
+		{"Public Key", &k.PublicKey, false},
+		{"Key Fingerprint", &k.KeyFingerprint, false},
+	}
+}
+
+// WriteKDBX writes the items as a KeePass KDBX 4 database protected by
+// password. Folders become groups, custom fields string fields, the TOTP
+// secret the otp field KeePassXC uses and attachments binaries. Password
+// history and linked fields are not carried over.
+func (bwm *Manager) WriteKDBX(w io.Writer, items []Item, password string, opts kdbx.Options) error {
+	if password == "" {
+		return ErrExportPassword
+	}
+	db := &kdbx.Database{
+		Name: keePassRootName,
+		Root: &kdbx.Group{UUID: kdbx.NewUUID(), Name: keePassRootName},
+	}
+	groups := make(map[string]*kdbx.Group)
+	var group func(path string) *kdbx.Group
+	group = func(path string) *kdbx.Group {
+		if path == "" {
+			return db.Root
+		}
+		if g, ok := groups[path]; ok {
+			return g
+		}
+		parent := db.Root
+		name := path
+		if i := strings.LastIndex(path, "/"); i >= 0 {
+			parent = group(path[:i])
+			name = path[i+1:]
+		}
+		g := &kdbx.Group{UUID: kdbx.NewUUID(), Name: name}
+		parent.Groups = append(parent.Groups, g)
+		groups[path] = g
+		return g
+	}
+
+	for _, item := range items {
+		e, err := bwm.keePassEntry(item)
+		if err != nil {
+			return err
+		}
+		g := group(JoinFolder(bwm.FolderName(item.FolderID)))
+		g.Entries = append(g.Entries, e)
+	}
+	return kdbx.Write(w, db, password, opts)
+}
+
+func (bwm *Manager) keePassEntry(item Item) (*kdbx.Entry, error) {
+	e := &kdbx.Entry{
+		UUID:             kdbx.NewUUID(),
+		CreationTime:     item.CreationDate,
+		ModificationTime: item.RevisionDate,
+	}
+	e.Set(kdbx.TitleKey, item.Name, false)
+	e.Set(kdbx.UserNameKey, item.Login.Username, false)
+	e.Set(kdbx.PasswordKey, item.Login.Password, true)
+	uri := ""
+	if len(item.Login.URIs) > 0 {
+		uri = item.Login.URIs[0].URI
+	}
+	e.Set(kdbx.URLKey, uri, false)
+	e.Set(kdbx.NotesKey, item.Notes, false)
+	if item.Favorite {
+		e.Tags = append(e.Tags, keePassFavoriteTag)
+	}
+
+	// keys must be unique within an entry, so clashing names get a suffix
+	add := func(key string, value string, protected bool) {
+		if key == "" {
+			key = "Field"
+		}
+		unique := key
+		for n := 2; hasField(e, unique); n++ {
+			unique = fmt.Sprintf("%s (%d)", key, n)
+		}
+		e.Set(unique, value, protected)
+	}
+	for i, u := range item.Login.URIs {
+		if i > 0 {
+			add(fmt.Sprintf("%s_%d", keePassURLPrefix, i), u.URI, false)
+		}
+	}
+	if item.Login.TOTP != "" {
+		add(kdbx.OTPKey, keePassOTP(item.Login.TOTP, item.Name, item.Login.Username), true)
+	}
+	var mapped []keePassField
+	switch item.Type {
+	case Card:
+		mapped = keePassCardFields(&item.Card)
+	case Identity:
+		mapped = keePassIdentityFields(&item.Identity)
+	case SSHKey:
+		mapped = keePassSSHKeyFields(&item.SSHKey)
+	}
+	for _, f := range mapped {
+		if *f.value != "" {
+			add(f.key, *f.value, f.protected)
+		}
+	}
+	for _, f := range item.Fields {
+		switch f.Type {
+		case FieldLinked:
+			continue
+		case FieldHidden:
+			add(f.Name, f.Value, true)
+		default:
+			add(f.Name, f.Value, false)
+		}
+	}
+
+	for _, a := range item.Attachments {
+		data, err := bwm.Attachment(item.ID, a.ID)
+		if err != nil {
+			return nil, err
+		}
+		e.Binaries = append(e.Binaries, kdbx.Binary{Name: a.FileName, Data: data})
+	}
+	return e, nil
+}
+
+func hasField(e *kdbx.Entry, key string) bool {
+	for _, f := range e.Fields {
+		if f.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// keePassOTP converts a Bitwarden TOTP secret to the otpauth URI KeePassXC
+// stores. Steam secrets are marked with KeePassXC's encoder parameter.
+func keePassOTP(secret string, issuer string, account string) string {
+	secret = strings.TrimSpace(secret)
+	if strings.HasPrefix(secret, "otpauth://") {
+		return secret
+	}
+	q := url.Values{}
+	if strings.HasPrefix(secret, "steam://") {
+		secret = strings.TrimPrefix(secret, "steam://")
+		issuer = "Steam"
+		q.Set("encoder", "steam")
+	}
+	q.Set("secret", strings.ToUpper(strings.ReplaceAll(secret, " ", "")))
+	label := issuer
+	if account != "" {
+		label += ":" + account
+	}
+	if issuer != "" {
+		q.Set("issuer", issuer)
+	}
+	return (&url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + label, RawQuery: q.Encode()}).String()
+}
+
+// bitwardenTOTP converts an otpauth URI back, turning KeePassXC Steam entries
+// into steam:// secrets and URIs with default parameters into bare secrets.
+func bitwardenTOTP(uri string) string {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil || u.Scheme != "otpauth" {
+		return strings.TrimSpace(uri)
+	}
+	q := u.Query()
+	if strings.EqualFold(q.Get("encoder"), "steam") {
+		return "steam://" + q.Get("secret")
+	}
+	q.Del("issuer")
+	if len(q) == 1 && q.Get("secret") != "" {
+		return q.Get("secret")
+	}
+	return u.String()
+}
+
+// ReadKDBX reads the entries of a KeePass KDBX 4 database, reversing the
+// mapping of WriteKDBX. Groups become folder paths; entries in the recycle bin
+// are skipped.
+func ReadKDBX(r io.Reader, password string) ([]ImportedItem, error) {
+	db, err := kdbx.Read(r, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read KeePass database: %w", err)
+	}
+	var items []ImportedItem
+	var walk func(g *kdbx.Group, path string)
+	walk = func(g *kdbx.Group, path string) {
+		if g.UUID == db.RecycleBin {
+			return
+		}
+		for _, e := range g.Entries {
+			items = append(items, importKeePassEntry(e, path))
+		}
+		for _, sub := range g.Groups {
+			walk(sub, JoinFolder(path, strings.ReplaceAll(sub.Name, "/", "-")))
+		}
+	}
+	// the root group is the database itself rather than a folder
+	walk(db.Root, "")
+	return items, nil
+}
+
+// keePassOTPKeys are the fields KeePass clients keep TOTP secrets in, besides
+// the otp URI.
+var keePassOTPKeys = map[string]bool{
+	kdbx.OTPKey:             true,
+	"TimeOtp-Secret-Base32": true,
+	"TimeOtp-Secret":        true,
+	"TimeOtp-Secret-Hex":    true,
+	"TimeOtp-Secret-Base64": true,
+	"TimeOtp-Length":        true,
+	"TimeOtp-Period":        true,
+	"TimeOtp-Algorithm":     true,
+	"TOTP Seed":             true,
+	"TOTP Settings":         true,
+}
+
+func importKeePassEntry(e *kdbx.Entry, folder string) ImportedItem {
+	item := Item{
+		Name:         e.Get(kdbx.TitleKey),
+		Notes:        e.Get(kdbx.NotesKey),
+		CreationDate: e.CreationTime,
+		RevisionDate: e.ModificationTime,
+		Login: ItemLogin{
+			Username: e.Get(kdbx.UserNameKey),
+			Password: e.Get(kdbx.PasswordKey),
+		},
+	}
+	for _, tag := range e.Tags {
+		if strings.EqualFold(tag, keePassFavoriteTag) {
+			item.Favorite = true
+		}
+	}
+	if u := e.Get(kdbx.URLKey); u != "" {
+		item.Login.URIs = append(item.Login.URIs, ItemLoginURI{Match: MatchDefault, URI: u})
+	}
+	switch {
+	case e.Get(kdbx.OTPKey) != "":
+		item.Login.TOTP = bitwardenTOTP(e.Get(kdbx.OTPKey))
+	case e.Get("TimeOtp-Secret-Base32") != "":
+		item.Login.TOTP = keePassTimeOTP(e)
+	case e.Get("TOTP Seed") != "":
+		item.Login.TOTP = e.Get("TOTP Seed")
+	}
+
+	// entries are told apart by their fields: anything with login data is a
+	// login, then come the card, identity and SSH key fields WriteKDBX adds.
+	var mapped []keePassField
+	hasAny := func(fields []keePassField) bool {
+		for _, f := range fields {
+			if e.Get(f.key) != "" {
+				return true
+			}
+		}
+		return false
+	}
+	hasURL := false
+	for _, f := range e.Fields {
+		if strings.HasPrefix(f.Key, keePassURLPrefix) && f.Value != "" {
+			hasURL = true
+		}
+	}
+	switch {
+	case item.Login.Username != "" || item.Login.Password != "" || len(item.Login.URIs) > 0 || hasURL || item.Login.TOTP != "":
+		item.Type = Login
+	case hasAny(keePassCardFields(&item.Card)):
+		item.Type = Card
+		mapped = keePassCardFields(&item.Card)
+	case hasAny(keePassIdentityFields(&item.Identity)):
+		item.Type = Identity
+		mapped = keePassIdentityFields(&item.Identity)
+	case hasAny(keePassSSHKeyFields(&item.SSHKey)):
+		item.Type = SSHKey
+		mapped = keePassSSHKeyFields(&item.SSHKey)
+	default:
+		item.Type = SecureNote
+	}
+	mappedKeys := make(map[string]*string, len(mapped))
+	for _, f := range mapped {
+		mappedKeys[f.key] = f.value
+	}
+
+	var extraURLs []kdbx.Field
+	for _, f := range e.Fields {
+		switch {
+		case kdbx.IsStandard(f.Key), keePassOTPKeys[f.Key]:
+		case strings.HasPrefix(f.Key, keePassURLPrefix):
+			extraURLs = append(extraURLs, f)
+		case mappedKeys[f.Key] != nil:
+			*mappedKeys[f.Key] = f.Value
+		default:
+			fieldType := FieldText
+			if f.Protected {
+				fieldType = FieldHidden
+			}
+			item.Fields = append(item.Fields, ItemField{Name: f.Key, Value: f.Value, Type: fieldType})
+		}
+	}
+	// KP2A_URL, KP2A_URL_1, ... keep their order
+	sort.SliceStable(extraURLs, func(i, j int) bool {
+		return keePassURLIndex(extraURLs[i].Key) < keePassURLIndex(extraURLs[j].Key)
+	})
+	for _, f := range extraURLs {
+		if f.Value != "" {
+			item.Login.URIs = append(item.Login.URIs, ItemLoginURI{Match: MatchDefault, URI: f.Value})
+		}
+	}
+	if item.Type != Login {
+		item.Login = ItemLogin{}
+	}
+
+	imported := ImportedItem{Item: item, Folder: folder}
+	for _, b := range e.Binaries {
+		imported.Attachments = append(imported.Attachments, ImportedAttachment{Name: b.Name, Data: b.Data})
+	}
+	return imported
+}
+
+// keePassURLIndex orders KP2A_URL (0), KP2A_URL_1 (1), ...
+func keePassURLIndex(key string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(key, keePassURLPrefix), "_"))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// keePassTimeOTP builds an otpauth URI from the TimeOtp fields of KeePass 2.
+func keePassTimeOTP(e *kdbx.Entry) string {
+	secret := strings.ToUpper(strings.ReplaceAll(e.Get("TimeOtp-Secret-Base32"), " ", ""))
+	q := url.Values{}
+	q.Set("secret", secret)
+	if v := e.Get("TimeOtp-Length"); v != "" {
+		q.Set("digits", v)
+	}
+	if v := e.Get("TimeOtp-Period"); v != "" {
+		q.Set("period", v)
+	}
+	switch e.Get("TimeOtp-Algorithm") {
+	case "HMAC-SHA-256":
+		q.Set("algorithm", "SHA256")
+	case "HMAC-SHA-512":
+		q.Set("algorithm", "SHA512")
+	}
+	if len(q) == 1 {
+		// a bare secret is what Bitwarden stores for the defaults
+		return secret
+	}
+	return (&url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + e.Get(kdbx.TitleKey), RawQuery: q.Encode()}).String()
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,6 +18,7 @@ const (
 	SecureNote ItemType = 2
 	Card       ItemType = 3
 	Identity   ItemType = 4
+	SSHKey     ItemType = 5
 )
 
 func (it ItemType) String() string {
@@ -28,8 +31,30 @@ func (it ItemType) String() string {
 		return "Card"
 	case Identity:
 		return "Identity"
+	case SSHKey:
+		return "SSHKey"
 	default:
-		panic("undefined item type")
+		return fmt.Sprintf("Type(%d)", it)
+	}
+}
+
+var ErrUnknownItemType = errors.New("unknown item type")
+
+// ParseItemType parses the item type names accepted on the command line.
+func ParseItemType(s string) (ItemType, error) {
+	switch strings.ToLower(s) {
+	case "login", "1":
+		return Login, nil
+	case "securenote", "secure-note", "note", "2":
+		return SecureNote, nil
+	case "card", "3":
+		return Card, nil
+	case "identity", "4":
+		return Identity, nil
+	case "sshkey", "ssh-key", "5":
+		return SSHKey, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrUnknownItemType, s)
 	}
 }
 
@@ -42,7 +67,19 @@ const (
 )
 
 type ItemLoginURI struct {
-	URI string `json:"uri"`
+	Match URIMatch `json:"match"`
+	URI   string   `json:"uri"`
+}
+
+func (u *ItemLoginURI) UnmarshalJSON(data []byte) error {
+	type plain ItemLoginURI
+	p := plain{Match: MatchDefault}
+	err := json.Unmarshal(data, &p)
+	if err != nil {
+		return err
+	}
+	*u = ItemLoginURI(p)
+	return nil
 }
 
 type ItemLogin struct {
@@ -50,23 +87,110 @@ type ItemLogin struct {
 	Username             string         `json:"username"`
 	Password             string         `json:"password"`
 	PasswordRevisionDate time.Time      `json:"passwordRevisionDate"`
-	// TODO: totp
+	TOTP                 string         `json:"totp"`
+}
+
+type FieldType int
+
+const (
+	FieldText    FieldType = 0
+	FieldHidden  FieldType = 1
+	FieldBoolean FieldType = 2
+	FieldLinked  FieldType = 3
+)
+
+type ItemField struct {
+	Name  string    `json:"name"`
+	Value string    `json:"value"`
+	Type  FieldType `json:"type"`
+}
+
+type ItemSecureNote struct {
+	Type int `json:"type"`
+}
+
+type ItemCard struct {
+	CardholderName string `json:"cardholderName"`
+	Brand          string `json:"brand"`
+	Number         string `json:"number"`
+	ExpMonth       string `json:"expMonth"`
+	ExpYear        string `json:"expYear"`
+	Code           string `json:"code"`
+}
+
+type ItemIdentity struct {
+	Title          string `json:"title"`
+	FirstName      string `json:"firstName"`
+	MiddleName     string `json:"middleName"`
+	LastName       string `json:"lastName"`
+	Address1       string `json:"address1"`
+	Address2       string `json:"address2"`
+	Address3       string `json:"address3"`
+	City           string `json:"city"`
+	State          string `json:"state"`
+	PostalCode     string `json:"postalCode"`
+	Country        string `json:"country"`
+	Company        string `json:"company"`
+	Email          string `json:"email"`
+	Phone          string `json:"phone"`
+	SSN            string `json:"ssn"`
+	Username       string `json:"username"`
+	PassportNumber string `json:"passportNumber"`
+	LicenseNumber  string `json:"licenseNumber"`
+}
+
+type ItemSSHKey struct {
+	PrivateKey     string `json:"privateKey"`
+	PublicKey      string `json:"publicKey"`
+	KeyFingerprint string `json:"keyFingerprint"`
+}
+
+type ItemPasswordHistory struct {
+	LastUsedDate time.Time `json:"lastUsedDate"`
+	Password     string    `json:"password"`
+}
+
+type ItemAttachment struct {
+	ID       string `json:"id"`
+	FileName string `json:"fileName"`
+	Size     string `json:"size"`
+	SizeName string `json:"sizeName"`
+	URL      string `json:"url"`
 }
 
 type Item struct {
-	Object         string    `json:"object"` // TODO: enum
-	ID             string    `json:"id"`
-	OrganizationID string    `json:"organizationId"`
-	FolderID       string    `json:"folderId"`
-	Type           ItemType  `json:"type"`
-	Reprompt       int       `json:"reprompt"`
-	Name           string    `json:"name"`
-	Notes          string    `json:"notes"`
-	Favorite       bool      `json:"favorite"`
-	Login          ItemLogin `json:"login"`
-	RevisionDate   time.Time `json:"revisionDate"`
-	CreationDate   time.Time `json:"creationDate"`
-	DeletedDate    time.Time `json:"deletedDate"`
+	Object          string                `json:"object"` // TODO: enum
+	ID              string                `json:"id"`
+	OrganizationID  string                `json:"organizationId"`
+	FolderID        string                `json:"folderId"`
+	Type            ItemType              `json:"type"`
+	Reprompt        int                   `json:"reprompt"`
+	Name            string                `json:"name"`
+	Notes           string                `json:"notes"`
+	Favorite        bool                  `json:"favorite"`
+	Login           ItemLogin             `json:"login"`
+	SecureNote      ItemSecureNote        `json:"secureNote"`
+	Card            ItemCard              `json:"card"`
+	Identity        ItemIdentity          `json:"identity"`
+	SSHKey          ItemSSHKey            `json:"sshKey"`
+	Fields          []ItemField           `json:"fields"`
+	PasswordHistory []ItemPasswordHistory `json:"passwordHistory"`
+	Attachments     []ItemAttachment      `json:"attachments"`
+	CollectionIDs   []string              `json:"collectionIds"`
+	RevisionDate    time.Time             `json:"revisionDate"`
+	CreationDate    time.Time             `json:"creationDate"`
+	DeletedDate     time.Time             `json:"deletedDate"`
+}
+
+type Folder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Organization struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
 }
 
 type VaultStatus struct {
@@ -78,19 +202,95 @@ type VaultStatus struct {
 }
 
 type Manager struct {
-	items       []Item
-	token       string
+	// listMu guards items, folders and organizations, which the TUI reads
+	// while commands write to the vault in the background. The slices are
+	// replaced, never changed in place, so a slice once read stays valid.
+	listMu        sync.RWMutex
+	items         []Item
+	folders       []Folder
+	organizations []Organization
+	token         string
+	store         SessionStore
+	// equivalentDomains are user-defined sets of domains treated as the same
+	// site, in addition to GlobalEquivalentDomains.
+	equivalentDomains [][]string
+	// agent, if set, holds the unlocked vault in place of the bw CLI.
+	agent Agent
+	// batching holds back reloading the agent until a batch of writes is
+	// over; batchChanged records that one is due.
+	batching     bool
+	batchChanged bool
+	// pinStore, if set, keeps the session key wrapped with a quick-unlock
+	// PIN.
+	pinStore   SessionStore
+	pinOptions PinOptions
+	// reprompt asks for the master password before secrets of items with
+	// master password re-prompt are handed out.
+	reprompt    RepromptFunc
 	VaultStatus VaultStatus
 }
 
-var ErrNotLoggedIn = errors.New("not logged in")
+var (
+	ErrNotLoggedIn = errors.New("not logged in")
+	ErrLocked      = errors.New("vault is locked")
+	// ErrInvalidPassword is returned when unlocking or a master password
+	// re-prompt fails on a wrong password.
+	ErrInvalidPassword = errors.New("invalid master password")
+)
+
+type Option func(*Manager)
 
-func NewBWManager() *Manager {
+// WithSessionStore makes the manager reuse a stored session when BW_SESSION
+// is not set and save the session after a successful login or unlock.
+func WithSessionStore(store SessionStore) Option {
+	return func(bwm *Manager) {
+		bwm.store = store
+	}
+}
+
+// WithEquivalentDomains adds sets of domains that URI matching treats as the
+// same site.
+func WithEquivalentDomains(domains [][]string) Option {
+	return func(bwm *Manager) {
+		bwm.equivalentDomains = domains
+	}
+}
+
+func NewBWManager(opts ...Option) *Manager {
 	var bwm Manager
+	for _, opt := range opts {
+		opt(&bwm)
+	}
 	bwm.token = os.Getenv("BW_SESSION")
+	if bwm.token == "" && bwm.store != nil {
+		token, err := bwm.store.Load()
+		if err == nil {
+			bwm.token = token
+		}
+	}
 	return &bwm
 }
 
+// Session returns the current session key, if any.
+func (bwm *Manager) Session() string {
+	return bwm.token
+}
+
+// ForgetSession wipes the stored session, if a session store is configured.
+func (bwm *Manager) ForgetSession() error {
+	if bwm.store == nil {
+		return nil
+	}
+	return bwm.store.Clear()
+}
+
+func (bwm *Manager) saveSession() error {
+	if bwm.store == nil || bwm.token == "" {
+		return nil
+	}
+	return bwm.store.Save(bwm.token)
+}
+
 func (bwm *Manager) Login(un string, pw string) error {
 	if bwm.VaultStatus.Status != Unauthenticated {
 		return nil
@@ -104,6 +304,10 @@ func (bwm *Manager) Login(un string, pw string) error {
 	if err != nil {
 		return fmt.Errorf("failed to login: %w", err)
 	}
+	err = bwm.saveSession()
+	if err != nil {
+		return fmt.Errorf("failed to login: %w", err)
+	}
 	return nil
 }
 
@@ -111,18 +315,67 @@ func (bwm *Manager) Unlock(pw string) error {
 	if bwm.VaultStatus.Status == Unauthenticated {
 		return ErrNotLoggedIn
 	}
-	out, err := exec.Command("bw", "unlock", pw, "--raw").Output()
+	if bwm.agent != nil {
+		// the agent unlocks for everyone and hands out its session
+		err := bwm.agent.Unlock(pw)
+		if err != nil {
+			return fmt.Errorf("failed to unlock: %w", err)
+		}
+	} else {
+		out, err := exec.Command("bw", "unlock", pw, "--raw").Output()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && strings.Contains(string(exitErr.Stderr), "Invalid master password") {
+			return fmt.Errorf("failed to unlock: %w", ErrInvalidPassword)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to unlock: %w", err)
+		}
+		bwm.token = string(out)
+	}
+	err := bwm.UpdateStatus()
 	if err != nil {
 		return fmt.Errorf("failed to unlock: %w", err)
 	}
-	bwm.token = string(out)
-	err = bwm.UpdateStatus()
+	err = bwm.saveSession()
 	if err != nil {
 		return fmt.Errorf("failed to unlock: %w", err)
 	}
 	return nil
 }
 
+// Lock locks the vault. The session is void afterwards, so it is forgotten
+// along with the decrypted items and the quick-unlock PIN wrapping it. See
+// PinLock for keeping the session for the PIN.
+func (bwm *Manager) Lock() error {
+	if bwm.VaultStatus.Status == Unauthenticated {
+		return ErrNotLoggedIn
+	}
+	var err error
+	if bwm.agent != nil {
+		err = bwm.agent.Lock()
+	} else {
+		err = exec.Command("bw", "lock").Run()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock: %w", err)
+	}
+	bwm.token = ""
+	bwm.setItems(nil)
+	err = bwm.ForgetSession()
+	if err != nil {
+		return fmt.Errorf("failed to lock: %w", err)
+	}
+	err = bwm.ForgetPin()
+	if err != nil {
+		return fmt.Errorf("failed to lock: %w", err)
+	}
+	err = bwm.UpdateStatus()
+	if err != nil {
+		return fmt.Errorf("failed to lock: %w", err)
+	}
+	return nil
+}
+
 func (bwm *Manager) Logout() error {
 	if bwm.VaultStatus.Status == Unauthenticated {
 		return ErrNotLoggedIn
@@ -132,6 +385,14 @@ func (bwm *Manager) Logout() error {
 		return fmt.Errorf("failed to logout: %w", err)
 	}
 	bwm.token = ""
+	err = bwm.ForgetSession()
+	if err != nil {
+		return fmt.Errorf("failed to logout: %w", err)
+	}
+	err = bwm.ForgetPin()
+	if err != nil {
+		return fmt.Errorf("failed to logout: %w", err)
+	}
 	err = bwm.UpdateStatus()
 	if err != nil {
 		return fmt.Errorf("failed to logout: %w", err)
@@ -140,7 +401,20 @@ func (bwm *Manager) Logout() error {
 }
 
 func (bwm *Manager) UpdateStatus() error {
-	out, err := exec.Command("bw", "status").Output()
+	if bwm.agent != nil {
+		status, token, err := bwm.agent.Status()
+		if err != nil {
+			return fmt.Errorf("failed to update status: %w", err)
+		}
+		bwm.VaultStatus = status
+		bwm.token = token
+		return nil
+	}
+	args := []string{"status"}
+	if bwm.token != "" {
+		args = append(args, "--session", bwm.token)
+	}
+	out, err := exec.Command("bw", args...).Output() // #nosec G204
 	if err != nil {
 		return fmt.Errorf("failed to update status: %w", err)
 	}
@@ -148,6 +422,15 @@ func (bwm *Manager) UpdateStatus() error {
 	if err != nil {
 		return fmt.Errorf("failed to update status: %w", err)
 	}
+	if bwm.VaultStatus.Status != Unlocked && bwm.token != "" {
+		// the session is stale (e.g. `bw lock` was run elsewhere), so it should
+		// not be handed out again.
+		bwm.token = ""
+		err = bwm.ForgetSession()
+		if err != nil {
+			return fmt.Errorf("failed to update status: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -155,20 +438,110 @@ func (bwm *Manager) UpdateList() error {
 	if bwm.VaultStatus.Status == Unauthenticated {
 		return ErrNotLoggedIn
 	}
-	out, err := exec.Command("bw", "list", "items", "--session", bwm.token).Output() // #nosec G204
+	if bwm.agent != nil {
+		list, err := bwm.agent.List()
+		if err != nil {
+			return fmt.Errorf("failed to update list: %w", err)
+		}
+		bwm.setList(list)
+		return nil
+	}
+	var list AgentList
+	err := bwm.list("items", &list.Items)
+	if err != nil {
+		return fmt.Errorf("failed to update list: %w", err)
+	}
+	err = bwm.list("folders", &list.Folders)
 	if err != nil {
 		return fmt.Errorf("failed to update list: %w", err)
 	}
-	err = json.Unmarshal(out, &bwm.items)
+	err = bwm.list("organizations", &list.Organizations)
 	if err != nil {
 		return fmt.Errorf("failed to update list: %w", err)
 	}
+	bwm.setList(list)
 	return nil
 }
 
+func (bwm *Manager) setList(list AgentList) {
+	bwm.listMu.Lock()
+	defer bwm.listMu.Unlock()
+	bwm.items, bwm.folders, bwm.organizations = list.Items, list.Folders, list.Organizations
+}
+
+func (bwm *Manager) setItems(items []Item) {
+	bwm.listMu.Lock()
+	defer bwm.listMu.Unlock()
+	bwm.items = items
+}
+
+// itemList returns the loaded items.
+func (bwm *Manager) itemList() []Item {
+	bwm.listMu.RLock()
+	defer bwm.listMu.RUnlock()
+	return bwm.items
+}
+
+// folderList returns the loaded folders.
+func (bwm *Manager) folderList() []Folder {
+	bwm.listMu.RLock()
+	defer bwm.listMu.RUnlock()
+	return bwm.folders
+}
+
+func (bwm *Manager) organizationList() []Organization {
+	bwm.listMu.RLock()
+	defer bwm.listMu.RUnlock()
+	return bwm.organizations
+}
+
+func (bwm *Manager) list(object string, v any) error {
+	out, err := exec.Command("bw", "list", object, "--session", bwm.token).Output() // #nosec G204
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(out, v)
+}
+
 func (bwm *Manager) GetList() ([]Item, error) {
 	if bwm.VaultStatus.Status == Unauthenticated {
 		return nil, ErrNotLoggedIn
 	}
-	return bwm.items, nil
+	return bwm.itemList(), nil
+}
+
+func (bwm *Manager) GetFolders() ([]Folder, error) {
+	if bwm.VaultStatus.Status == Unauthenticated {
+		return nil, ErrNotLoggedIn
+	}
+	return bwm.folderList(), nil
+}
+
+func (bwm *Manager) GetOrganizations() ([]Organization, error) {
+	if bwm.VaultStatus.Status == Unauthenticated {
+		return nil, ErrNotLoggedIn
+	}
+	return bwm.organizationList(), nil
+}
+
+// FolderName returns the name of the folder with the given ID, or an empty
+// string if the item is not in a folder.
+func (bwm *Manager) FolderName(id string) string {
+	for _, f := range bwm.folderList() {
+		if f.ID == id && id != "" {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+// OrganizationName returns the name of the organization with the given ID, or
+// an empty string if the item is not owned by an organization.
+func (bwm *Manager) OrganizationName(id string) string {
+	for _, o := range bwm.organizationList() {
+		if o.ID == id && id != "" {
+			return o.Name
+		}
+	}
+	return ""
 }
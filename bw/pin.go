@@ -0,0 +1,274 @@
+package bw
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var (
+	ErrNoPin = errors.New("no quick-unlock PIN set")
+	// ErrWrongPin is returned for a wrong PIN while attempts are left.
+	ErrWrongPin = errors.New("wrong PIN")
+	// ErrPinExpired is returned once the PIN can no longer be used, after
+	// too many wrong attempts or when its time window is over. The master
+	// password is needed then.
+	ErrPinExpired = errors.New("quick-unlock PIN expired, use the master password")
+	// ErrPinTooShort is returned by SetPin for PINs under MinPinLength.
+	ErrPinTooShort = errors.New("PIN is too short")
+)
+
+const (
+	pinKeyDescription = "gobw:pin"
+	// MinPinLength is the shortest PIN SetPin accepts. The attempt limit is
+	// only kept by gobw, and whoever can read the PIN store can try PINs
+	// offline, so each PIN has to take real work to guess.
+	MinPinLength = 6
+)
+
+// Argon2id parameters for deriving the key from the PIN, as recommended for
+// interactive use by RFC 9106.
+const (
+	pinArgonTime    = 3
+	pinArgonMemory  = 64 * 1024
+	pinArgonThreads = 4
+	pinKeyLength    = 32
+)
+
+// PinOptions limits how a quick-unlock PIN may be used.
+type PinOptions struct {
+	// Attempts is how many wrong PINs are allowed before the PIN is wiped.
+	Attempts int
+	// Window is how long after the full unlock the PIN may be used.
+	Window time.Duration
+}
+
+func (o PinOptions) attempts() int {
+	if o.Attempts <= 0 {
+		return 1
+	}
+	return o.Attempts
+}
+
+// wrappedSession is the session key encrypted with the key derived from the
+// PIN, as kept in the PIN store.
+type wrappedSession struct {
+	Salt         []byte    `json:"salt"`
+	Nonce        []byte    `json:"nonce"`
+	Ciphertext   []byte    `json:"ciphertext"`
+	AttemptsLeft int       `json:"attemptsLeft"`
+	Expires      time.Time `json:"expires"`
+}
+
+// NewPinStore returns the store keeping the wrapped session key for the
+// given kind. An empty kind or "none" disables the quick-unlock PIN and
+// returns a nil store. "memory" keeps it for as long as gobw runs, "keyring"
+// in the Linux kernel user keyring until window is over.
+func NewPinStore(kind string, window time.Duration) (SessionStore, error) {
+	switch kind {
+	case "", "none":
+		return nil, nil //nolint:nilnil // a nil store means the PIN is disabled
+	case "memory":
+		return &MemorySessionStore{}, nil
+	case "keyring":
+		store, err := newKeyringStore(pinKeyDescription, window)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSessionKind, kind)
+	}
+}
+
+// WithPinStore enables unlocking with a short PIN: SetPin keeps the session
+// key encrypted with the PIN in store, and UnlockWithPin brings it back.
+func WithPinStore(store SessionStore, opts PinOptions) Option {
+	return func(bwm *Manager) {
+		bwm.pinStore = store
+		bwm.pinOptions = opts
+	}
+}
+
+// PinEnabled reports whether a PIN store is configured. A manager using an
+// agent leaves unlocking to the agent and has no PIN.
+func (bwm *Manager) PinEnabled() bool {
+	return bwm.pinStore != nil && bwm.agent == nil
+}
+
+// HasPin reports whether the vault can be unlocked with a PIN right now.
+func (bwm *Manager) HasPin() bool {
+	_, err := bwm.loadPin()
+	return err == nil
+}
+
+// PinAttemptsLeft returns how many more PINs may be tried.
+func (bwm *Manager) PinAttemptsLeft() int {
+	ws, err := bwm.loadPin()
+	if err != nil {
+		return 0
+	}
+	return ws.AttemptsLeft
+}
+
+// SetPin encrypts the current session key with a key derived from pin, so
+// the vault can be unlocked with the PIN instead of the master password.
+func (bwm *Manager) SetPin(pin string) error {
+	if !bwm.PinEnabled() {
+		return ErrNoPin
+	}
+	if bwm.VaultStatus.Status != Unlocked || bwm.token == "" {
+		return ErrLocked
+	}
+	if len(pin) < MinPinLength {
+		return fmt.Errorf("%w: it needs at least %d characters", ErrPinTooShort, MinPinLength)
+	}
+	ws := wrappedSession{
+		Salt:         make([]byte, 16),
+		AttemptsLeft: bwm.pinOptions.attempts(),
+	}
+	if bwm.pinOptions.Window > 0 {
+		ws.Expires = time.Now().Add(bwm.pinOptions.Window)
+	}
+	if _, err := rand.Read(ws.Salt); err != nil {
+		return fmt.Errorf("failed to set PIN: %w", err)
+	}
+	aead, err := pinCipher(pin, ws.Salt)
+	if err != nil {
+		return fmt.Errorf("failed to set PIN: %w", err)
+	}
+	ws.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(ws.Nonce); err != nil {
+		return fmt.Errorf("failed to set PIN: %w", err)
+	}
+	ws.Ciphertext = aead.Seal(nil, ws.Nonce, []byte(bwm.token), nil)
+	err = bwm.savePin(ws)
+	if err != nil {
+		return fmt.Errorf("failed to set PIN: %w", err)
+	}
+	return nil
+}
+
+// UnlockWithPin unlocks the vault with the session key kept by SetPin. Every
+// wrong PIN uses up an attempt; once they are gone, or the time window is
+// over, the PIN is wiped and ErrPinExpired returned.
+func (bwm *Manager) UnlockWithPin(pin string) error {
+	if bwm.VaultStatus.Status == Unauthenticated {
+		return ErrNotLoggedIn
+	}
+	ws, err := bwm.loadPin()
+	if err != nil {
+		return err
+	}
+	aead, err := pinCipher(pin, ws.Salt)
+	if err != nil {
+		return fmt.Errorf("failed to unlock: %w", err)
+	}
+	token, err := aead.Open(nil, ws.Nonce, ws.Ciphertext, nil)
+	if err != nil {
+		ws.AttemptsLeft--
+		if ws.AttemptsLeft <= 0 {
+			_ = bwm.ForgetPin()
+			return ErrPinExpired
+		}
+		err = bwm.savePin(ws)
+		if err != nil {
+			return fmt.Errorf("failed to unlock: %w", err)
+		}
+		return fmt.Errorf("%w, %d attempts left", ErrWrongPin, ws.AttemptsLeft)
+	}
+	bwm.token = string(token)
+	err = bwm.UpdateStatus()
+	if err != nil {
+		return fmt.Errorf("failed to unlock: %w", err)
+	}
+	if bwm.VaultStatus.Status != Unlocked {
+		// the session was ended with `bw lock` since the PIN was set
+		_ = bwm.ForgetPin()
+		return ErrPinExpired
+	}
+	// a fresh start for the next time
+	ws.AttemptsLeft = bwm.pinOptions.attempts()
+	err = bwm.savePin(ws)
+	if err != nil {
+		return fmt.Errorf("failed to unlock: %w", err)
+	}
+	err = bwm.saveSession()
+	if err != nil {
+		return fmt.Errorf("failed to unlock: %w", err)
+	}
+	return nil
+}
+
+// PinLock hides the vault behind the quick-unlock PIN: this manager forgets
+// the session and the decrypted items and reports the vault as locked. Unlike
+// Lock, the bw session itself stays valid, for UnlockWithPin and for anyone
+// else holding it, until `bw lock` or Lock ends it.
+func (bwm *Manager) PinLock() error {
+	if !bwm.HasPin() {
+		return ErrNoPin
+	}
+	bwm.token = ""
+	bwm.setItems(nil)
+	err := bwm.ForgetSession()
+	if err != nil {
+		return fmt.Errorf("failed to lock: %w", err)
+	}
+	bwm.VaultStatus.Status = Locked
+	return nil
+}
+
+// ForgetPin wipes the wrapped session key.
+func (bwm *Manager) ForgetPin() error {
+	if bwm.pinStore == nil {
+		return nil
+	}
+	return bwm.pinStore.Clear()
+}
+
+func (bwm *Manager) loadPin() (wrappedSession, error) {
+	var ws wrappedSession
+	if !bwm.PinEnabled() {
+		return ws, ErrNoPin
+	}
+	data, err := bwm.pinStore.Load()
+	if errors.Is(err, ErrNoSession) {
+		return ws, ErrNoPin
+	}
+	if err != nil {
+		return ws, fmt.Errorf("failed to load PIN: %w", err)
+	}
+	err = json.Unmarshal([]byte(data), &ws)
+	if err != nil {
+		return ws, fmt.Errorf("failed to load PIN: %w", err)
+	}
+	if ws.AttemptsLeft <= 0 || (!ws.Expires.IsZero() && time.Now().After(ws.Expires)) {
+		_ = bwm.ForgetPin()
+		return ws, ErrPinExpired
+	}
+	return ws, nil
+}
+
+func (bwm *Manager) savePin(ws wrappedSession) error {
+	data, err := json.Marshal(ws)
+	if err != nil {
+		return err
+	}
+	return bwm.pinStore.Save(string(data))
+}
+
+// pinCipher derives the key wrapping the session key from the PIN.
+func pinCipher(pin string, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(pin), salt, pinArgonTime, pinArgonMemory, pinArgonThreads, pinKeyLength)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,127 @@
+package bw
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrRepromptUnavailable is returned when a secret of an item with master
+// password re-prompt is asked for and there is no way to ask for the master
+// password.
+var ErrRepromptUnavailable = errors.New("item requires the master password but no pinentry is configured to ask for it")
+
+// RepromptFunc asks for and checks the master password before a secret of
+// item is handed out.
+type RepromptFunc func(item Item) error
+
+// unprotectedFields are the fields Bitwarden shows without a re-prompt. Each
+// must be one of the well-known names of Item.FieldValue, so it can never
+// resolve to a hidden custom field of the same name.
+var unprotectedFields = map[string]bool{
+	"name":     true,
+	"username": true,
+	"uri":      true,
+	"url":      true,
+}
+
+// SetReprompt sets how master password re-prompts are answered outside the
+// TUI. Without one, protected secrets are refused.
+func (bwm *Manager) SetReprompt(f RepromptFunc) {
+	bwm.reprompt = f
+}
+
+// CheckReprompt must pass before field of item is handed out, or the whole
+// item if field is empty. Items with master password re-prompt need the
+// master password for anything but their name, username and URIs. It returns
+// the item to hand out, which is revealed by the agent if there is one.
+func (bwm *Manager) CheckReprompt(item Item, field string) (Item, error) {
+	if item.Reprompt == 0 || (field != "" && unprotectedFields[strings.ToLower(field)]) {
+		return item, nil
+	}
+	if bwm.agent != nil {
+		// the agent asks for the master password itself
+		return bwm.RevealItem(item, "")
+	}
+	if bwm.reprompt == nil {
+		return Item{}, fmt.Errorf("%w: %q", ErrRepromptUnavailable, item.Name)
+	}
+	if err := bwm.reprompt(item); err != nil {
+		return Item{}, err
+	}
+	return item, nil
+}
+
+// Redacted returns item without anything but its name, username, URIs and
+// where it is filed if it has master password re-prompt. Agents hand out
+// items like this, see RevealItems.
+func (item Item) Redacted() Item {
+	if item.Reprompt == 0 {
+		return item
+	}
+	return Item{
+		Object:         item.Object,
+		ID:             item.ID,
+		OrganizationID: item.OrganizationID,
+		FolderID:       item.FolderID,
+		Type:           item.Type,
+		Reprompt:       item.Reprompt,
+		Name:           item.Name,
+		Favorite:       item.Favorite,
+		Login:          ItemLogin{Username: item.Login.Username, URIs: item.Login.URIs},
+		CollectionIDs:  item.CollectionIDs,
+		RevisionDate:   item.RevisionDate,
+		CreationDate:   item.CreationDate,
+		DeletedDate:    item.DeletedDate,
+	}
+}
+
+// IsRedacted reports whether item was handed out redacted by an agent, see
+// RevealItems.
+func (bwm *Manager) IsRedacted(item Item) bool {
+	return bwm.agent != nil && item.Reprompt != 0
+}
+
+// RevealItem is RevealItems for a single item.
+func (bwm *Manager) RevealItem(item Item, pw string) (Item, error) {
+	items, err := bwm.RevealItems([]Item{item}, pw)
+	if err != nil {
+		return Item{}, err
+	}
+	return items[0], nil
+}
+
+// RevealItems returns items in full. An agent hands out items with master
+// password re-prompt redacted, and only reveals them once pw is the master
+// password, or, with an empty pw, it asked for the master password itself.
+// Without an agent the items are returned as they are.
+func (bwm *Manager) RevealItems(items []Item, pw string) ([]Item, error) {
+	if bwm.agent == nil {
+		return items, nil
+	}
+	var ids []string
+	for _, item := range items {
+		if item.Reprompt != 0 {
+			ids = append(ids, item.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return items, nil
+	}
+	revealed, err := bwm.agent.Reveal(ids, pw)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]Item, len(revealed))
+	for _, item := range revealed {
+		byID[item.ID] = item
+	}
+	out := make([]Item, len(items))
+	for i, item := range items {
+		if full, ok := byID[item.ID]; ok {
+			item = full
+		}
+		out[i] = item
+	}
+	return out, nil
+}
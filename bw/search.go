@@ -0,0 +1,363 @@
+package bw
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sahilm/fuzzy"
+)
+
+// SearchField identifies which part of an item a search term matched.
+type SearchField int
+
+const (
+	SearchName SearchField = iota
+	SearchUsername
+	SearchURI
+	SearchNotes
+	SearchFolder
+	SearchOrganization
+	SearchCustomField
+)
+
+func (f SearchField) String() string {
+	switch f {
+	case SearchName:
+		return "name"
+	case SearchUsername:
+		return "user"
+	case SearchURI:
+		return "url"
+	case SearchNotes:
+		return "notes"
+	case SearchFolder:
+		return "folder"
+	case SearchOrganization:
+		return "org"
+	case SearchCustomField:
+		return "field"
+	default:
+		return "unknown"
+	}
+}
+
+// weight is added to the fuzzy score of a match so that e.g. a name match
+// ranks above an equally good match in the notes.
+func (f SearchField) weight() int {
+	switch f {
+	case SearchName:
+		return 30
+	case SearchUsername:
+		return 20
+	case SearchURI:
+		return 15
+	case SearchFolder, SearchOrganization:
+		return 10
+	case SearchCustomField:
+		return 5
+	default:
+		return 0
+	}
+}
+
+// Qualifier is a `key:value` term of a search query that restricts the
+// results instead of ranking them.
+type Qualifier struct {
+	Key   string
+	Value string
+}
+
+// Query is a parsed search query.
+type Query struct {
+	Terms      []string
+	Qualifiers []Qualifier
+}
+
+func qualifierField(key string) (SearchField, bool) {
+	switch key {
+	case "name":
+		return SearchName, true
+	case "user", "username":
+		return SearchUsername, true
+	case "url", "uri":
+		return SearchURI, true
+	case "note", "notes":
+		return SearchNotes, true
+	case "folder":
+		return SearchFolder, true
+	case "org", "organization":
+		return SearchOrganization, true
+	case "field":
+		return SearchCustomField, true
+	default:
+		return 0, false
+	}
+}
+
+func isQualifierKey(key string) bool {
+	if _, ok := qualifierField(key); ok {
+		return true
+	}
+	return key == "type" || key == "is"
+}
+
+// splitQuery splits a query on whitespace, keeping double-quoted sections
+// (e.g. `folder:"my stuff"`) together.
+func splitQuery(s string) []string {
+	var tokens []string
+	var b strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (r == ' ' || r == '\t'):
+			if b.Len() > 0 {
+				tokens = append(tokens, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		tokens = append(tokens, b.String())
+	}
+	return tokens
+}
+
+// ParseQuery parses a search query such as
+// `github user:alice folder:infra type:login is:favorite`. Terms with an
+// unknown key, like URLs, are kept as plain search terms.
+func ParseQuery(s string) Query {
+	var q Query
+	for _, token := range splitQuery(s) {
+		key, value, ok := strings.Cut(token, ":")
+		key = strings.ToLower(key)
+		if ok && value != "" && isQualifierKey(key) {
+			q.Qualifiers = append(q.Qualifiers, Qualifier{Key: key, Value: value})
+			continue
+		}
+		q.Terms = append(q.Terms, token)
+	}
+	return q
+}
+
+// SearchMatch describes where in an item a search matched.
+type SearchMatch struct {
+	Field SearchField
+	Value string
+	// Indexes are the rune indexes of the matched characters in Value.
+	Indexes []int
+}
+
+type SearchResult struct {
+	// Position is the index of the item in the slice the index was built from.
+	Position int
+	Item     Item
+	Score    int
+	// Matches holds the best match for each search term and qualifier.
+	Matches []SearchMatch
+}
+
+// NameIndexes returns the rune indexes of the item name that were matched.
+func (r SearchResult) NameIndexes() []int {
+	var indexes []int
+	for _, m := range r.Matches {
+		if m.Field == SearchName {
+			indexes = append(indexes, m.Indexes...)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// Highlight returns the most relevant match outside of the item name, which
+// is what a list description should show.
+func (r SearchResult) Highlight() (SearchMatch, bool) {
+	for _, m := range r.Matches {
+		if m.Field != SearchName {
+			return m, true
+		}
+	}
+	return SearchMatch{}, false
+}
+
+type searchValue struct {
+	field SearchField
+	value string
+}
+
+type searchEntry struct {
+	item   Item
+	values []searchValue
+}
+
+// SearchIndex holds the searchable fields of a list of items.
+type SearchIndex struct {
+	entries []searchEntry
+}
+
+// NewSearchIndex indexes the given items. Folder and organization names are
+// resolved through the manager.
+func (bwm *Manager) NewSearchIndex(items []Item) *SearchIndex {
+	idx := SearchIndex{entries: make([]searchEntry, 0, len(items))}
+	for _, item := range items {
+		e := searchEntry{item: item}
+		add := func(field SearchField, value string) {
+			if value != "" {
+				e.values = append(e.values, searchValue{field, value})
+			}
+		}
+		add(SearchName, item.Name)
+		add(SearchUsername, item.Login.Username)
+		for _, uri := range item.Login.URIs {
+			add(SearchURI, uri.URI)
+		}
+		add(SearchNotes, item.Notes)
+		add(SearchFolder, bwm.FolderName(item.FolderID))
+		add(SearchOrganization, bwm.OrganizationName(item.OrganizationID))
+		for _, f := range item.Fields {
+			add(SearchCustomField, f.Name)
+		}
+		idx.entries = append(idx.entries, e)
+	}
+	return &idx
+}
+
+// Search returns the items matching the query, best match first. Every
+// qualifier must match as a case-insensitive substring of its field, and every
+// plain term must fuzzy-match at least one indexed field. Notes are only
+// matched by substring since fuzzy matching long text matches nearly anything.
+func (idx *SearchIndex) Search(query string) []SearchResult {
+	q := ParseQuery(query)
+	results := make([]SearchResult, 0, len(idx.entries))
+	for pos, e := range idx.entries {
+		result, ok := e.match(q)
+		if !ok {
+			continue
+		}
+		result.Position = pos
+		results = append(results, result)
+	}
+	if len(q.Terms) > 0 {
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].Score > results[j].Score
+		})
+	}
+	return results
+}
+
+func (e searchEntry) match(q Query) (SearchResult, bool) {
+	result := SearchResult{Item: e.item}
+	for _, qual := range q.Qualifiers {
+		m, ok := e.matchQualifier(qual)
+		if !ok {
+			return result, false
+		}
+		if m.Value != "" {
+			result.Matches = append(result.Matches, m)
+		}
+	}
+	var termMatches []SearchMatch
+	for _, term := range q.Terms {
+		m, score, ok := e.matchTerm(term)
+		if !ok {
+			return result, false
+		}
+		result.Score += score
+		termMatches = append(termMatches, m)
+	}
+	// plain term matches are more relevant for highlighting than qualifiers
+	result.Matches = append(termMatches, result.Matches...)
+	return result, true
+}
+
+func (e searchEntry) matchQualifier(qual Qualifier) (SearchMatch, bool) {
+	switch qual.Key {
+	case "type":
+		t, err := ParseItemType(qual.Value)
+		return SearchMatch{}, err == nil && e.item.Type == t
+	case "is":
+		switch strings.ToLower(qual.Value) {
+		case "favorite", "favourite", "fav":
+			return SearchMatch{}, e.item.Favorite
+		case "reprompt":
+			return SearchMatch{}, e.item.Reprompt != 0
+		default:
+			return SearchMatch{}, false
+		}
+	}
+	field, _ := qualifierField(qual.Key)
+	for _, v := range e.values {
+		if v.field != field {
+			continue
+		}
+		if indexes, ok := substringIndexes(v.value, qual.Value); ok {
+			return SearchMatch{Field: field, Value: v.value, Indexes: indexes}, true
+		}
+	}
+	return SearchMatch{}, false
+}
+
+func (e searchEntry) matchTerm(term string) (SearchMatch, int, bool) {
+	var best SearchMatch
+	bestScore := 0
+	found := false
+	for _, v := range e.values {
+		var indexes []int
+		var score int
+		if v.field == SearchNotes {
+			var ok bool
+			indexes, ok = substringIndexes(v.value, term)
+			if !ok {
+				continue
+			}
+		} else {
+			matches := fuzzy.Find(term, []string{v.value})
+			if len(matches) == 0 {
+				continue
+			}
+			score = matches[0].Score
+			indexes = runeIndexes(v.value, matches[0].MatchedIndexes)
+		}
+		score += v.field.weight()
+		if !found || score > bestScore {
+			best = SearchMatch{Field: v.field, Value: v.value, Indexes: indexes}
+			bestScore = score
+			found = true
+		}
+	}
+	return best, bestScore, found
+}
+
+// substringIndexes returns the rune indexes of the first case-insensitive
+// occurrence of sub in s.
+func substringIndexes(s string, sub string) ([]int, bool) {
+	runes := []rune(strings.ToLower(s))
+	subRunes := []rune(strings.ToLower(sub))
+	if len(runes) != utf8.RuneCountInString(s) {
+		// lowercasing changed the length, fall back to a plain check
+		return nil, strings.Contains(strings.ToLower(s), strings.ToLower(sub))
+	}
+	for i := 0; i+len(subRunes) <= len(runes); i++ {
+		if string(runes[i:i+len(subRunes)]) == string(subRunes) {
+			indexes := make([]int, len(subRunes))
+			for j := range indexes {
+				indexes[j] = i + j
+			}
+			return indexes, true
+		}
+	}
+	return nil, false
+}
+
+// runeIndexes converts the byte offsets reported by fuzzy into rune indexes.
+func runeIndexes(s string, byteIndexes []int) []int {
+	indexes := make([]int, 0, len(byteIndexes))
+	for _, b := range byteIndexes {
+		indexes = append(indexes, utf8.RuneCountInString(s[:b]))
+	}
+	return indexes
+}
@@ -0,0 +1,103 @@
+package bw
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SecretRefScheme starts secret references like bw://Prod DB/password.
+const SecretRefScheme = "bw://"
+
+var ErrInvalidSecretRef = errors.New("invalid secret reference")
+
+// SecretRef points at a field of a vault item: bw://ITEM/FIELD for the fields
+// FieldValue knows, or bw://ITEM/fields/NAME for a custom field only. ITEM
+// is an item ID or name as accepted by FindItem. Slashes in names are written
+// as %2F.
+type SecretRef struct {
+	Item  string
+	Field string
+	// Custom restricts Field to the item's custom fields.
+	Custom bool
+}
+
+// IsSecretRef reports whether s looks like a secret reference.
+func IsSecretRef(s string) bool {
+	return strings.HasPrefix(s, SecretRefScheme)
+}
+
+func ParseSecretRef(s string) (SecretRef, error) {
+	if !IsSecretRef(s) {
+		return SecretRef{}, fmt.Errorf("%w %q: must start with %s", ErrInvalidSecretRef, s, SecretRefScheme)
+	}
+	rest := strings.TrimPrefix(s, SecretRefScheme)
+	var ref SecretRef
+	var item, field string
+	if i := strings.LastIndex(rest, "/fields/"); i >= 0 {
+		item, field = rest[:i], rest[i+len("/fields/"):]
+		ref.Custom = true
+	} else if i := strings.LastIndex(rest, "/"); i >= 0 {
+		item, field = rest[:i], rest[i+1:]
+	}
+	var err error
+	ref.Item, err = url.PathUnescape(item)
+	if err != nil {
+		return SecretRef{}, fmt.Errorf("%w %q: %w", ErrInvalidSecretRef, s, err)
+	}
+	ref.Field, err = url.PathUnescape(field)
+	if err != nil {
+		return SecretRef{}, fmt.Errorf("%w %q: %w", ErrInvalidSecretRef, s, err)
+	}
+	if ref.Item == "" || ref.Field == "" {
+		return SecretRef{}, fmt.Errorf("%w %q: expected %sITEM/FIELD", ErrInvalidSecretRef, s, SecretRefScheme)
+	}
+	return ref, nil
+}
+
+func (r SecretRef) String() string {
+	item := strings.ReplaceAll(url.PathEscape(r.Item), "%20", " ")
+	field := strings.ReplaceAll(url.PathEscape(r.Field), "%20", " ")
+	if r.Custom {
+		return SecretRefScheme + item + "/fields/" + field
+	}
+	return SecretRefScheme + item + "/" + field
+}
+
+// ResolveSecretRef returns the value the reference points at.
+func (bwm *Manager) ResolveSecretRef(ref SecretRef) (string, error) {
+	item, err := bwm.FindItem(ref.Item)
+	if err != nil {
+		return "", err
+	}
+	item, err = bwm.CheckReprompt(item, ref.Field)
+	if err != nil {
+		return "", err
+	}
+	if !ref.Custom {
+		return item.FieldValue(ref.Field)
+	}
+	for _, f := range item.Fields {
+		if strings.EqualFold(f.Name, ref.Field) {
+			return f.Value, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q has no custom field %q", ErrFieldNotFound, item.Name, ref.Field)
+}
+
+// ResolveValue returns value, or what it points at if it is a secret
+// reference.
+func (bwm *Manager) ResolveValue(value string) (string, error) {
+	if !IsSecretRef(value) {
+		return value, nil
+	}
+	ref, err := ParseSecretRef(value)
+	if err != nil {
+		return "", err
+	}
+	if bwm.VaultStatus.Status != Unlocked {
+		return "", ErrLocked
+	}
+	return bwm.ResolveSecretRef(ref)
+}
@@ -0,0 +1,276 @@
+package bw
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Secret Service attributes that are derived from logins rather than stored
+// as custom fields.
+const (
+	SecretAttrUsername = "username"
+	SecretAttrURI      = "uri"
+	SecretAttrServer   = "server"
+	SecretAttrProtocol = "protocol"
+)
+
+// SecretServiceMarker is the custom field marking items stored through the
+// Secret Service. Only those may be replaced, changed, renamed or deleted by
+// its clients; the rest of the vault is read-only to them.
+const SecretServiceMarker = "gobw:secret-service"
+
+// ErrNotSecretServiceItem is returned when a Secret Service client tries to
+// change an item it did not store.
+var ErrNotSecretServiceItem = errors.New("item was not stored through the Secret Service")
+
+// secretAttributeAliases maps other common spellings of the derived
+// attributes to their names.
+var secretAttributeAliases = map[string]string{
+	"user":   SecretAttrUsername,
+	"url":    SecretAttrURI,
+	"host":   SecretAttrServer,
+	"domain": SecretAttrServer,
+}
+
+// secretAttributeName resolves aliases of the derived attributes.
+func secretAttributeName(name string) string {
+	if alias, ok := secretAttributeAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// SecretAttributes returns the lookup attributes of an item for the Secret
+// Service: its text custom fields, and for logins the username and the URI,
+// server and protocol of the first URI. Hidden fields are secrets and never
+// attributes.
+func (item Item) SecretAttributes() map[string]string {
+	attrs := make(map[string]string)
+	for _, f := range item.Fields {
+		if f.Type == FieldText && f.Name != "" {
+			attrs[f.Name] = f.Value
+		}
+	}
+	if item.Type != Login {
+		return attrs
+	}
+	if item.Login.Username != "" {
+		attrs[SecretAttrUsername] = item.Login.Username
+	}
+	if len(item.Login.URIs) > 0 {
+		uri := item.Login.URIs[0].URI
+		attrs[SecretAttrURI] = uri
+		if u, err := normalizeURL(uri); err == nil && u.Hostname() != "" {
+			attrs[SecretAttrServer] = strings.ToLower(u.Hostname())
+			if strings.Contains(uri, "://") {
+				attrs[SecretAttrProtocol] = u.Scheme
+			}
+		}
+	}
+	return attrs
+}
+
+// SecretServiceOwned reports whether item was stored through the Secret
+// Service.
+func (item Item) SecretServiceOwned() bool {
+	for _, f := range item.Fields {
+		if f.Name == SecretServiceMarker && f.Type == FieldBoolean {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesSecretAttributes reports whether item has all of attrs. The URI,
+// server and protocol match any URI of a login, not only the first.
+func (item Item) MatchesSecretAttributes(attrs map[string]string) bool {
+	fields := item.SecretAttributes()
+	for name, value := range attrs {
+		name = secretAttributeName(name)
+		if fields[name] == value {
+			continue
+		}
+		if !item.matchesLoginURI(name, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (item Item) matchesLoginURI(name string, value string) bool {
+	if item.Type != Login {
+		return false
+	}
+	for _, uri := range item.Login.URIs {
+		u, err := normalizeURL(uri.URI)
+		if err != nil {
+			continue
+		}
+		switch name {
+		case SecretAttrURI:
+			if strings.TrimSpace(uri.URI) == value {
+				return true
+			}
+		case SecretAttrServer:
+			if strings.EqualFold(u.Hostname(), value) {
+				return true
+			}
+		case SecretAttrProtocol:
+			if strings.Contains(uri.URI, "://") && strings.EqualFold(u.Scheme, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SecretValue returns what the Secret Service hands out as the secret of an
+// item: the password of logins, the private key of SSH keys and the notes of
+// everything else.
+func (item Item) SecretValue() string {
+	switch item.Type {
+	case Login:
+		return item.Login.Password
+	case SSHKey:
+		return item.SSHKey.PrivateKey
+	default:
+		return item.Notes
+	}
+}
+
+// secretServiceItem returns the item with the given ID if it was stored
+// through the Secret Service.
+func (bwm *Manager) secretServiceItem(id string) (Item, error) {
+	for _, item := range bwm.itemList() {
+		if item.ID != id {
+			continue
+		}
+		if !item.SecretServiceOwned() {
+			return Item{}, fmt.Errorf("%w: %q", ErrNotSecretServiceItem, item.Name)
+		}
+		return item, nil
+	}
+	return Item{}, ErrItemNotFound
+}
+
+// SetSecretValue replaces the secret of an item stored through the Secret
+// Service, as returned by SecretValue.
+func (bwm *Manager) SetSecretValue(id string, value string) (Item, error) {
+	if _, err := bwm.secretServiceItem(id); err != nil {
+		return Item{}, fmt.Errorf("failed to set secret: %w", err)
+	}
+	item, err := bwm.editItem(id, func(raw map[string]any) {
+		setSecretValue(raw, value)
+	})
+	if err != nil {
+		return Item{}, fmt.Errorf("failed to set secret: %w", err)
+	}
+	return item, nil
+}
+
+func setSecretValue(raw map[string]any, value string) {
+	var key, field string
+	switch t, _ := raw["type"].(float64); ItemType(t) {
+	case Login:
+		key, field = "login", "password"
+	case SSHKey:
+		key, field = "sshKey", "privateKey"
+	default:
+		raw["notes"] = value
+		return
+	}
+	obj, _ := raw[key].(map[string]any)
+	if obj == nil {
+		obj = make(map[string]any)
+		raw[key] = obj
+	}
+	obj[field] = value
+}
+
+// RenameSecretItem changes the name of an item stored through the Secret
+// Service.
+func (bwm *Manager) RenameSecretItem(id string, name string) (Item, error) {
+	if _, err := bwm.secretServiceItem(id); err != nil {
+		return Item{}, fmt.Errorf("failed to rename item: %w", err)
+	}
+	item, err := bwm.editItem(id, func(raw map[string]any) {
+		raw["name"] = name
+	})
+	if err != nil {
+		return Item{}, fmt.Errorf("failed to rename item: %w", err)
+	}
+	return item, nil
+}
+
+// DeleteSecretItem moves an item stored through the Secret Service to the
+// trash.
+func (bwm *Manager) DeleteSecretItem(id string) error {
+	if _, err := bwm.secretServiceItem(id); err != nil {
+		return fmt.Errorf("failed to delete item: %w", err)
+	}
+	return bwm.DeleteItem(id)
+}
+
+// StoreSecretItem saves a secret stored through the Secret Service. With
+// replace, an item stored through the Secret Service before that has all of
+// attrs gets the new label and secret. Otherwise a new item is created: a
+// login if the attributes name a username, URI or server, or a secure note
+// holding the secret. Attributes that do not map onto the login are kept as
+// text custom fields, and SecretServiceMarker marks the item.
+func (bwm *Manager) StoreSecretItem(label string, attrs map[string]string, value string, replace bool) (Item, error) {
+	if replace && len(attrs) > 0 {
+		for _, item := range bwm.itemList() {
+			if !item.SecretServiceOwned() || !item.MatchesSecretAttributes(attrs) {
+				continue
+			}
+			item, err := bwm.editItem(item.ID, func(raw map[string]any) {
+				raw["name"] = label
+				setSecretValue(raw, value)
+			})
+			if err != nil {
+				return Item{}, fmt.Errorf("failed to replace secret: %w", err)
+			}
+			return item, nil
+		}
+	}
+	item := Item{Name: label, Type: SecureNote, Notes: value}
+	var protocol, server string
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		attr := attrs[name]
+		switch secretAttributeName(name) {
+		case SecretAttrUsername:
+			item.Login.Username = attr
+		case SecretAttrURI:
+			item.Login.URIs = append([]ItemLoginURI{{Match: MatchDefault, URI: attr}}, item.Login.URIs...)
+		case SecretAttrServer:
+			server = attr
+		case SecretAttrProtocol:
+			protocol = attr
+		default:
+			item.Fields = append(item.Fields, ItemField{Name: name, Value: attr, Type: FieldText})
+		}
+	}
+	if server != "" {
+		uri := server
+		if protocol != "" {
+			uri = protocol + "://" + server
+		}
+		item.Login.URIs = append(item.Login.URIs, ItemLoginURI{Match: MatchDefault, URI: uri})
+	} else if protocol != "" {
+		item.Fields = append(item.Fields, ItemField{Name: SecretAttrProtocol, Value: protocol, Type: FieldText})
+	}
+	item.Fields = append(item.Fields, ItemField{Name: SecretServiceMarker, Value: "true", Type: FieldBoolean})
+	if item.Login.Username != "" || len(item.Login.URIs) > 0 {
+		item.Type = Login
+		item.Notes = ""
+		item.Login.Password = value
+	}
+	return bwm.CreateItem(item)
+}
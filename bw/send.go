@@ -0,0 +1,262 @@
+package bw
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// SendType is the kind of a Bitwarden Send.
+type SendType int
+
+const (
+	SendText SendType = 0
+	SendFile SendType = 1
+)
+
+func (t SendType) String() string {
+	switch t {
+	case SendText:
+		return "Text"
+	case SendFile:
+		return "File"
+	default:
+		return "Unknown"
+	}
+}
+
+var ErrSendNotFound = errors.New("send not found")
+
+// SendTextData is the content of a text Send.
+type SendTextData struct {
+	Text string `json:"text"`
+	// Hidden makes recipients click to reveal the text.
+	Hidden bool `json:"hidden"`
+}
+
+// SendFileData describes the file of a file Send.
+type SendFileData struct {
+	ID       string `json:"id,omitempty"`
+	FileName string `json:"fileName"`
+	SizeName string `json:"sizeName,omitempty"`
+}
+
+// Send is a text or file shared through a link with anyone, as listed by
+// `bw send list`.
+type Send struct {
+	ID             string        `json:"id"`
+	AccessID       string        `json:"accessId"`
+	AccessURL      string        `json:"accessUrl"`
+	Name           string        `json:"name"`
+	Notes          string        `json:"notes"`
+	Type           SendType      `json:"type"`
+	Text           *SendTextData `json:"text"`
+	File           *SendFileData `json:"file"`
+	MaxAccessCount *int          `json:"maxAccessCount"`
+	AccessCount    int           `json:"accessCount"`
+	RevisionDate   time.Time     `json:"revisionDate"`
+	DeletionDate   time.Time     `json:"deletionDate"`
+	ExpirationDate *time.Time    `json:"expirationDate"`
+	PasswordSet    bool          `json:"passwordSet"`
+	Disabled       bool          `json:"disabled"`
+	HideEmail      bool          `json:"hideEmail"`
+}
+
+// Expired reports whether the Send can no longer be accessed, because it
+// expired, reached its maximum access count or was disabled.
+func (s Send) Expired(now time.Time) bool {
+	if s.Disabled || !now.Before(s.DeletionDate) {
+		return true
+	}
+	if s.ExpirationDate != nil && !now.Before(*s.ExpirationDate) {
+		return true
+	}
+	return s.MaxAccessCount != nil && s.AccessCount >= *s.MaxAccessCount
+}
+
+// MaxSendDeletion is how far in the future Bitwarden lets the deletion date of
+// a Send be.
+const MaxSendDeletion = 31 * 24 * time.Hour
+
+// SendOptions describes a new Send. Exactly one of Text and File is used:
+// the Send is a file Send if File, a path, is set.
+type SendOptions struct {
+	Name   string
+	Notes  string
+	Text   string
+	Hidden bool
+	File   string
+	// DeletionDate is when the server deletes the Send, at most
+	// MaxSendDeletion from now.
+	DeletionDate time.Time
+	// ExpirationDate, if set, is when the Send stops being accessible.
+	ExpirationDate time.Time
+	// MaxAccessCount, if positive, limits how often the Send can be opened.
+	MaxAccessCount int
+	// Password, if set, is asked from recipients.
+	Password  string
+	HideEmail bool
+}
+
+// sendRequest is the JSON `bw send create` takes.
+type sendRequest struct {
+	Name           string        `json:"name"`
+	Notes          string        `json:"notes,omitempty"`
+	Type           SendType      `json:"type"`
+	Text           *SendTextData `json:"text"`
+	File           *SendFileData `json:"file"`
+	MaxAccessCount *int          `json:"maxAccessCount"`
+	DeletionDate   time.Time     `json:"deletionDate"`
+	ExpirationDate *time.Time    `json:"expirationDate"`
+	Password       *string       `json:"password"`
+	Disabled       bool          `json:"disabled"`
+	HideEmail      bool          `json:"hideEmail"`
+}
+
+// ReceivedSend is a Send opened through its link with `bw send receive`.
+type ReceivedSend struct {
+	ID             string        `json:"id"`
+	Name           string        `json:"name"`
+	Type           SendType      `json:"type"`
+	Text           *SendTextData `json:"text"`
+	File           *SendFileData `json:"file"`
+	ExpirationDate *time.Time    `json:"expirationDate"`
+	CreatorEmail   string        `json:"creatorIdentifier"`
+}
+
+// ListSends returns the Sends of the account.
+func (bwm *Manager) ListSends() ([]Send, error) {
+	if bwm.VaultStatus.Status != Unlocked {
+		return nil, ErrLocked
+	}
+	out, err := exec.Command("bw", "send", "list", "--session", bwm.token).Output() // #nosec G204
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sends: %w", err)
+	}
+	var sends []Send
+	err = json.Unmarshal(out, &sends)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sends: %w", err)
+	}
+	return sends, nil
+}
+
+// CreateSend creates a Send and returns it with its link.
+func (bwm *Manager) CreateSend(opts SendOptions) (Send, error) {
+	if bwm.VaultStatus.Status != Unlocked {
+		return Send{}, ErrLocked
+	}
+	req := sendRequest{
+		Name:         opts.Name,
+		Notes:        opts.Notes,
+		DeletionDate: opts.DeletionDate.UTC(),
+		HideEmail:    opts.HideEmail,
+	}
+	args := []string{"send", "create", "--session", bwm.token}
+	if opts.File != "" {
+		if _, err := os.Stat(opts.File); err != nil {
+			return Send{}, fmt.Errorf("failed to create send: %w", err)
+		}
+		req.Type = SendFile
+		req.File = &SendFileData{FileName: filepath.Base(opts.File)}
+		args = append(args, "--file", opts.File)
+		if req.Name == "" {
+			req.Name = req.File.FileName
+		}
+	} else {
+		req.Type = SendText
+		req.Text = &SendTextData{Text: opts.Text, Hidden: opts.Hidden}
+	}
+	if req.Name == "" {
+		return Send{}, errors.New("failed to create send: a name is required")
+	}
+	if !opts.ExpirationDate.IsZero() {
+		expiration := opts.ExpirationDate.UTC()
+		req.ExpirationDate = &expiration
+	}
+	if opts.MaxAccessCount > 0 {
+		req.MaxAccessCount = &opts.MaxAccessCount
+	}
+	if opts.Password != "" {
+		req.Password = &opts.Password
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return Send{}, fmt.Errorf("failed to create send: %w", err)
+	}
+	// the request goes in on stdin, keeping the text and password out of the
+	// process list
+	cmd := exec.Command("bw", args...) // #nosec G204
+	cmd.Stdin = bytes.NewBufferString(base64.StdEncoding.EncodeToString(data))
+	out, err := cmd.Output()
+	if err != nil {
+		return Send{}, fmt.Errorf("failed to create send: %w", err)
+	}
+	var send Send
+	err = json.Unmarshal(out, &send)
+	if err != nil {
+		return Send{}, fmt.Errorf("failed to create send: %w", err)
+	}
+	return send, nil
+}
+
+// DeleteSend deletes the Send with the given ID.
+func (bwm *Manager) DeleteSend(id string) error {
+	if bwm.VaultStatus.Status != Unlocked {
+		return ErrLocked
+	}
+	out, err := exec.Command("bw", "send", "delete", id, "--session", bwm.token).CombinedOutput() // #nosec G204
+	if err != nil {
+		if bytes.Contains(out, []byte("Not found")) {
+			return fmt.Errorf("%w: %s", ErrSendNotFound, id)
+		}
+		return fmt.Errorf("failed to delete send: %w", err)
+	}
+	return nil
+}
+
+// sendPasswordEnv passes the password of a Send to `bw send receive`
+// without putting it on the command line.
+const sendPasswordEnv = "GOBW_SEND_PASSWORD"
+
+// receiveCommand returns `bw send receive` for url with the remaining args.
+// Receiving needs neither a login nor an unlocked vault.
+func receiveCommand(url string, password string, args ...string) *exec.Cmd {
+	args = append([]string{"send", "receive", url}, args...)
+	cmd := exec.Command("bw", args...) // #nosec G204
+	if password != "" {
+		cmd.Args = append(cmd.Args, "--passwordenv", sendPasswordEnv)
+		cmd.Env = append(os.Environ(), sendPasswordEnv+"="+password)
+	}
+	return cmd
+}
+
+// ReceiveSend opens the Send behind a link and returns it, with the text of
+// text Sends. The content of file Sends is fetched with DownloadSend.
+func ReceiveSend(url string, password string) (ReceivedSend, error) {
+	out, err := receiveCommand(url, password, "--obj").Output()
+	if err != nil {
+		return ReceivedSend{}, fmt.Errorf("failed to receive send: %w", err)
+	}
+	var send ReceivedSend
+	err = json.Unmarshal(out, &send)
+	if err != nil {
+		return ReceivedSend{}, fmt.Errorf("failed to receive send: %w", err)
+	}
+	return send, nil
+}
+
+// DownloadSend saves the file of the file Send behind a link to path.
+func DownloadSend(url string, password string, path string) error {
+	err := receiveCommand(url, password, "--output", path).Run()
+	if err != nil {
+		return fmt.Errorf("failed to download send: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package bw
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SessionStore persists the session key returned by `bw unlock` so it can be
+// reused by later invocations without exporting BW_SESSION.
+type SessionStore interface {
+	Load() (string, error)
+	Save(token string) error
+	Clear() error
+}
+
+var (
+	ErrNoSession          = errors.New("no stored session")
+	ErrUnsupportedStore   = errors.New("session store not supported on this platform")
+	ErrUnknownSessionKind = errors.New("unknown session store")
+)
+
+const sessionKeyDescription = "gobw:session"
+
+// NewSessionStore returns the session store for the given kind. An empty kind
+// or "none" disables session persistence and returns a nil store.
+func NewSessionStore(kind string, ttl time.Duration) (SessionStore, error) {
+	switch kind {
+	case "", "none":
+		return nil, nil //nolint:nilnil // a nil store means persistence is disabled
+	case "keyring":
+		store, err := NewKeyringSessionStore(ttl)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "file":
+		store, err := NewFileSessionStore(ttl)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSessionKind, kind)
+	}
+}
+
+type fileSession struct {
+	Session string    `json:"session"`
+	Expires time.Time `json:"expires"`
+}
+
+// FileSessionStore keeps the session key in a 0600 file under
+// $XDG_RUNTIME_DIR, which is a per-user tmpfs on most Linux systems.
+type FileSessionStore struct {
+	path string
+	ttl  time.Duration
+}
+
+func NewFileSessionStore(ttl time.Duration) (*FileSessionStore, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return nil, fmt.Errorf("%w: XDG_RUNTIME_DIR is not set", ErrUnsupportedStore)
+	}
+	return &FileSessionStore{
+		path: filepath.Join(dir, "gobw", "session"),
+		ttl:  ttl,
+	}, nil
+}
+
+func (s *FileSessionStore) Load() (string, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNoSession
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load session: %w", err)
+	}
+	var fs fileSession
+	err = json.Unmarshal(data, &fs)
+	if err != nil {
+		return "", fmt.Errorf("failed to load session: %w", err)
+	}
+	if fs.Session == "" || (!fs.Expires.IsZero() && time.Now().After(fs.Expires)) {
+		_ = s.Clear()
+		return "", ErrNoSession
+	}
+	return fs.Session, nil
+}
+
+func (s *FileSessionStore) Save(token string) error {
+	fs := fileSession{Session: token}
+	if s.ttl > 0 {
+		fs.Expires = time.Now().Add(s.ttl)
+	}
+	data, err := json.Marshal(fs)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	err = os.MkdirAll(filepath.Dir(s.path), 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	err = os.WriteFile(s.path, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	// WriteFile does not change the mode of an existing file.
+	err = os.Chmod(s.path, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+func (s *FileSessionStore) Clear() error {
+	err := os.Remove(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to clear session: %w", err)
+	}
+	return nil
+}
+
+// MemorySessionStore keeps a value for as long as the process runs.
+type MemorySessionStore struct {
+	value string
+}
+
+func (s *MemorySessionStore) Load() (string, error) {
+	if s.value == "" {
+		return "", ErrNoSession
+	}
+	return s.value, nil
+}
+
+func (s *MemorySessionStore) Save(value string) error {
+	s.value = value
+	return nil
+}
+
+func (s *MemorySessionStore) Clear() error {
+	s.value = ""
+	return nil
+}
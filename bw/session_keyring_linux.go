@@ -0,0 +1,86 @@
+//go:build linux
+
+package bw
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// KeyringSessionStore keeps the session key in the Linux kernel user keyring.
+// The kernel enforces the TTL by expiring the key.
+type KeyringSessionStore struct {
+	description string
+	ttl         time.Duration
+}
+
+func NewKeyringSessionStore(ttl time.Duration) (*KeyringSessionStore, error) {
+	return newKeyringStore(sessionKeyDescription, ttl)
+}
+
+func newKeyringStore(description string, ttl time.Duration) (*KeyringSessionStore, error) {
+	return &KeyringSessionStore{description: description, ttl: ttl}, nil
+}
+
+func (s *KeyringSessionStore) find() (int, error) {
+	id, err := unix.KeyctlSearch(unix.KEY_SPEC_USER_KEYRING, "user", s.description, 0)
+	if errors.Is(err, unix.ENOKEY) || errors.Is(err, unix.EKEYEXPIRED) || errors.Is(err, unix.EKEYREVOKED) {
+		return 0, ErrNoSession
+	}
+	return id, err
+}
+
+func (s *KeyringSessionStore) Load() (string, error) {
+	id, err := s.find()
+	if errors.Is(err, ErrNoSession) {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load session: %w", err)
+	}
+	size, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, nil, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to load session: %w", err)
+	}
+	buf := make([]byte, size)
+	n, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, buf, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to load session: %w", err)
+	}
+	if n < len(buf) {
+		buf = buf[:n]
+	}
+	return string(buf), nil
+}
+
+func (s *KeyringSessionStore) Save(token string) error {
+	id, err := unix.AddKey("user", s.description, []byte(token), unix.KEY_SPEC_USER_KEYRING)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if s.ttl > 0 {
+		_, err = unix.KeyctlInt(unix.KEYCTL_SET_TIMEOUT, id, int(s.ttl.Seconds()), 0, 0)
+		if err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *KeyringSessionStore) Clear() error {
+	id, err := s.find()
+	if errors.Is(err, ErrNoSession) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to clear session: %w", err)
+	}
+	_, err = unix.KeyctlInt(unix.KEYCTL_UNLINK, id, unix.KEY_SPEC_USER_KEYRING, 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to clear session: %w", err)
+	}
+	return nil
+}
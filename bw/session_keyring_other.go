@@ -0,0 +1,22 @@
+//go:build !linux
+
+package bw
+
+import (
+	"time"
+)
+
+// KeyringSessionStore is only available on Linux.
+type KeyringSessionStore struct{}
+
+func NewKeyringSessionStore(_ time.Duration) (*KeyringSessionStore, error) {
+	return nil, ErrUnsupportedStore
+}
+
+func newKeyringStore(_ string, _ time.Duration) (*KeyringSessionStore, error) {
+	return nil, ErrUnsupportedStore
+}
+
+func (s *KeyringSessionStore) Load() (string, error) { return "", ErrUnsupportedStore }
+func (s *KeyringSessionStore) Save(_ string) error   { return ErrUnsupportedStore }
+func (s *KeyringSessionStore) Clear() error          { return ErrUnsupportedStore }
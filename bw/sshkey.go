@@ -0,0 +1,143 @@
+package bw
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// maxSSHKeyAttachmentSize bounds the attachments downloaded as key
+// candidates; private keys are a few kilobytes at most.
+const maxSSHKeyAttachmentSize = 64 * 1024
+
+// privateKeyPattern finds PEM and OpenSSH private key blocks in free text.
+var privateKeyPattern = regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----.*?-----END [A-Z0-9 ]*PRIVATE KEY-----`)
+
+// ErrSSHKeyPassphrase is reported for encrypted keys none of the item's
+// secrets open.
+var ErrSSHKeyPassphrase = errors.New("key is encrypted and the item holds no matching passphrase")
+
+// VaultSSHKey is a private key found in a vault item.
+type VaultSSHKey struct {
+	Item Item
+	// Source says where in the item the key is: "ssh key", "notes", the name
+	// of a custom field or of an attachment.
+	Source string
+	// Key is the parsed private key as returned by ssh.ParseRawPrivateKey.
+	Key any
+	// Err is set instead of Key when the key could not be read, e.g. because
+	// it is encrypted with a passphrase the item does not hold.
+	Err error
+}
+
+// Comment names the key the way ssh-add -L shows it.
+func (k VaultSSHKey) Comment() string {
+	if k.Source == sshKeySourceItem {
+		return k.Item.Name
+	}
+	return fmt.Sprintf("%s (%s)", k.Item.Name, k.Source)
+}
+
+const (
+	sshKeySourceItem  = "ssh key"
+	sshKeySourceNotes = "notes"
+)
+
+// SSHKeys collects the private keys in the loaded items: SSH key items, key
+// blocks pasted into notes or custom fields, and attachments that look like
+// key files. Encrypted keys are opened with the item's password or a custom
+// field named "passphrase". An agent hands out SSH key items with master
+// password re-prompt once the password is entered; keys in other items with
+// re-prompt stay hidden then.
+func (bwm *Manager) SSHKeys() ([]VaultSSHKey, error) {
+	var keys []VaultSSHKey
+	for _, item := range bwm.itemList() {
+		if bwm.IsRedacted(item) && item.Type == SSHKey {
+			revealed, err := bwm.CheckReprompt(item, "")
+			if err != nil {
+				keys = append(keys, VaultSSHKey{Item: item, Source: sshKeySourceItem, Err: err})
+				continue
+			}
+			item = revealed
+		}
+		passphrases := sshKeyPassphrases(item)
+		add := func(source string, pemBytes []byte) {
+			key, err := parseSSHKey(pemBytes, passphrases)
+			keys = append(keys, VaultSSHKey{Item: item, Source: source, Key: key, Err: err})
+		}
+		if item.Type == SSHKey && item.SSHKey.PrivateKey != "" {
+			add(sshKeySourceItem, []byte(item.SSHKey.PrivateKey))
+		}
+		for _, block := range privateKeyPattern.FindAllString(item.Notes, -1) {
+			add(sshKeySourceNotes, []byte(block))
+		}
+		for _, f := range item.Fields {
+			for _, block := range privateKeyPattern.FindAllString(f.Value, -1) {
+				add(f.Name, []byte(block))
+			}
+		}
+		for _, a := range item.Attachments {
+			if !isSSHKeyFileName(a.FileName) {
+				continue
+			}
+			if size, err := strconv.Atoi(a.Size); err == nil && size > maxSSHKeyAttachmentSize {
+				continue
+			}
+			data, err := bwm.Attachment(item.ID, a.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !privateKeyPattern.Match(data) {
+				continue
+			}
+			add(a.FileName, data)
+		}
+	}
+	return keys, nil
+}
+
+// isSSHKeyFileName reports whether an attachment is named like a private
+// key, e.g. id_ed25519 or deploy.pem, but not like a public key.
+func isSSHKeyFileName(name string) bool {
+	name = strings.ToLower(name)
+	switch path.Ext(name) {
+	case ".pub":
+		return false
+	case ".pem", ".key":
+		return true
+	}
+	return strings.HasPrefix(name, "id_")
+}
+
+func sshKeyPassphrases(item Item) []string {
+	var passphrases []string
+	for _, f := range item.Fields {
+		if strings.EqualFold(f.Name, "passphrase") && f.Value != "" {
+			passphrases = append(passphrases, f.Value)
+		}
+	}
+	if item.Login.Password != "" {
+		passphrases = append(passphrases, item.Login.Password)
+	}
+	return passphrases
+}
+
+func parseSSHKey(pemBytes []byte, passphrases []string) (any, error) {
+	key, err := ssh.ParseRawPrivateKey(pemBytes)
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return key, err
+	}
+	for _, passphrase := range passphrases {
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+		if err == nil {
+			return key, nil
+		}
+	}
+	return nil, ErrSSHKeyPassphrase
+}
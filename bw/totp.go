@@ -0,0 +1,115 @@
+package bw
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // #nosec G505 -- SHA-1 is what RFC 6238 and most issuers use
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNoTOTP = errors.New("item has no TOTP secret")
+
+const steamAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+
+type totpParams struct {
+	secret    []byte
+	digits    int
+	period    int
+	algorithm func() hash.Hash
+	steam     bool
+}
+
+func parseTOTP(s string) (totpParams, error) {
+	p := totpParams{digits: 6, period: 30, algorithm: sha1.New}
+	secret := strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(secret, "steam://"):
+		p.steam = true
+		p.digits = 5
+		secret = strings.TrimPrefix(secret, "steam://")
+	case strings.HasPrefix(secret, "otpauth://"):
+		u, err := url.Parse(secret)
+		if err != nil {
+			return p, fmt.Errorf("invalid otpauth URI: %w", err)
+		}
+		q := u.Query()
+		secret = q.Get("secret")
+		if d := q.Get("digits"); d != "" {
+			p.digits, err = strconv.Atoi(d)
+			if err != nil || p.digits < 1 || p.digits > 10 {
+				return p, fmt.Errorf("invalid otpauth digits: %q", d)
+			}
+		}
+		if d := q.Get("period"); d != "" {
+			p.period, err = strconv.Atoi(d)
+			if err != nil || p.period < 1 {
+				return p, fmt.Errorf("invalid otpauth period: %q", d)
+			}
+		}
+		switch strings.ToUpper(q.Get("algorithm")) {
+		case "", "SHA1":
+		case "SHA256":
+			p.algorithm = sha256.New
+		case "SHA512":
+			p.algorithm = sha512.New
+		default:
+			return p, fmt.Errorf("unsupported otpauth algorithm: %q", q.Get("algorithm"))
+		}
+	}
+	secret = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(secret))
+	secret = strings.TrimRight(secret, "=")
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return p, fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	p.secret = key
+	return p, nil
+}
+
+// TOTP computes the current code for a TOTP secret as stored in a login. The
+// secret may be a bare base32 key, an otpauth:// URI or a steam:// key. It
+// also returns how long the code remains valid.
+func TOTP(secret string, now time.Time) (string, time.Duration, error) {
+	if secret == "" {
+		return "", 0, ErrNoTOTP
+	}
+	p, err := parseTOTP(secret)
+	if err != nil {
+		return "", 0, err
+	}
+	counter := now.Unix() / int64(p.period)
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(p.algorithm, p.secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	var out string
+	if p.steam {
+		b := make([]byte, p.digits)
+		for i := range b {
+			b[i] = steamAlphabet[code%uint32(len(steamAlphabet))]
+			code /= uint32(len(steamAlphabet))
+		}
+		out = string(b)
+	} else {
+		mod := uint64(1)
+		for i := 0; i < p.digits; i++ {
+			mod *= 10
+		}
+		out = fmt.Sprintf("%0*d", p.digits, uint64(code)%mod)
+	}
+	remaining := time.Duration(int64(p.period)-now.Unix()%int64(p.period)) * time.Second
+	return out, remaining, nil
+}
@@ -0,0 +1,336 @@
+package bw
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// URIMatch is the match detection setting of a login URI.
+type URIMatch int
+
+const (
+	// MatchDefault means the URI has no explicit setting and uses the
+	// default, which is base domain matching.
+	MatchDefault    URIMatch = -1
+	MatchBaseDomain URIMatch = 0
+	MatchHost       URIMatch = 1
+	MatchStartsWith URIMatch = 2
+	MatchExact      URIMatch = 3
+	MatchRegex      URIMatch = 4
+	MatchNever      URIMatch = 5
+)
+
+func (m URIMatch) String() string {
+	switch m {
+	case MatchDefault:
+		return "Default"
+	case MatchBaseDomain:
+		return "Base domain"
+	case MatchHost:
+		return "Host"
+	case MatchStartsWith:
+		return "Starts with"
+	case MatchExact:
+		return "Exact"
+	case MatchRegex:
+		return "Regular expression"
+	case MatchNever:
+		return "Never"
+	default:
+		return "Unknown"
+	}
+}
+
+func (m *URIMatch) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*m = MatchDefault
+		return nil
+	}
+	var i int
+	err := json.Unmarshal(data, &i)
+	if err != nil {
+		return err
+	}
+	*m = URIMatch(i)
+	return nil
+}
+
+func (m URIMatch) MarshalJSON() ([]byte, error) {
+	if m == MatchDefault {
+		return []byte("null"), nil
+	}
+	return json.Marshal(int(m))
+}
+
+// normalizeURL parses a URL, assuming http:// when there is no scheme like
+// the Bitwarden clients do.
+func normalizeURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	return url.Parse(raw)
+}
+
+// baseDomain returns the registrable domain of a host ("example.co.uk" for
+// "www.example.co.uk"). IP addresses and single-label hosts like localhost
+// are returned as is.
+func baseDomain(host string) string {
+	host = strings.ToLower(host)
+	if net.ParseIP(host) != nil || !strings.Contains(host, ".") {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// URIMatcher matches login URIs against a URL the way the Bitwarden clients
+// do for autofill.
+type URIMatcher struct {
+	target  string
+	url     *url.URL
+	domains map[string]bool
+}
+
+// NewURIMatcher prepares matching against target. Base domain matches also
+// accept any domain listed as equivalent to the target's domain.
+func NewURIMatcher(target string, equivalentDomains [][]string) (*URIMatcher, error) {
+	u, err := normalizeURL(target)
+	if err != nil {
+		return nil, err
+	}
+	m := URIMatcher{
+		target:  target,
+		url:     u,
+		domains: make(map[string]bool),
+	}
+	domain := baseDomain(u.Hostname())
+	m.domains[domain] = true
+	for _, set := range equivalentDomains {
+		found := false
+		for _, d := range set {
+			if strings.EqualFold(d, domain) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		for _, d := range set {
+			m.domains[strings.ToLower(d)] = true
+		}
+	}
+	return &m, nil
+}
+
+// Match reports whether the login URI matches the target URL.
+func (m *URIMatcher) Match(uri ItemLoginURI) bool {
+	if uri.URI == "" {
+		return false
+	}
+	switch uri.Match {
+	case MatchDefault, MatchBaseDomain:
+		u, err := normalizeURL(uri.URI)
+		if err != nil {
+			return false
+		}
+		return m.domains[baseDomain(u.Hostname())]
+	case MatchHost:
+		u, err := normalizeURL(uri.URI)
+		if err != nil {
+			return false
+		}
+		return u.Host != "" && strings.EqualFold(u.Host, m.url.Host)
+	case MatchStartsWith:
+		return strings.HasPrefix(m.target, uri.URI)
+	case MatchExact:
+		return m.target == uri.URI
+	case MatchRegex:
+		re, err := regexp.Compile("(?i)" + uri.URI)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(m.target)
+	default:
+		return false
+	}
+}
+
+// MatchURI returns the login items with at least one URI matching the given
+// URL.
+func (bwm *Manager) MatchURI(target string) ([]Item, error) {
+	items, err := bwm.GetList()
+	if err != nil {
+		return nil, err
+	}
+	equivalent := append(GlobalEquivalentDomains(), bwm.equivalentDomains...)
+	m, err := NewURIMatcher(target, equivalent)
+	if err != nil {
+		return nil, err
+	}
+	var matched []Item
+	for _, item := range items {
+		if item.Type != Login {
+			continue
+		}
+		for _, uri := range item.Login.URIs {
+			if m.Match(uri) {
+				matched = append(matched, item)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+// GlobalEquivalentDomains returns a subset of the equivalent domain sets the
+// Bitwarden server ships, for the services most likely to be in a vault.
+func GlobalEquivalentDomains() [][]string {
+	return [][]string{
+		{"youtube.com", "google.com", "gmail.com"},
+		{"apple.com", "icloud.com"},
+		{"ameritrade.com", "tdameritrade.com"},
+		{"bankofamerica.com", "bofa.com", "mbna.com", "usecfo.com"},
+		{"sprint.com", "sprintpcs.com", "nextel.com"},
+		{"wellsfargo.com", "wf.com"},
+		{"mymerrill.com", "ml.com", "merrilledge.com"},
+		{"accountonline.com", "citi.com", "citibank.com", "citicards.com", "citibankonline.com"},
+		{"cnet.com", "cnettv.com", "com.com", "download.com", "news.com", "search.com", "upload.com"},
+		{"bananarepublic.com", "gap.com", "oldnavy.com", "piperlime.com"},
+		{"bing.com", "hotmail.com", "live.com", "microsoft.com", "msn.com", "passport.net", "windows.com", "microsoftonline.com", "office.com", "office365.com", "microsoftstore.com", "xbox.com", "azure.com", "windowsazure.com"},
+		{"ua2go.com", "ual.com", "united.com", "unitedwifi.com"},
+		{"overture.com", "yahoo.com"},
+		{"zonealarm.com", "zonelabs.com"},
+		{"paypal.com", "paypal-search.com"},
+		{"avon.com", "youravon.com"},
+		{"diapers.com", "soap.com", "wag.com", "yoyo.com", "beautybar.com", "casa.com", "afterschool.com", "vine.com", "bookworm.com", "look.com", "vinemarket.com"},
+		{"1800contacts.com", "800contacts.com"},
+		{"amazon.com", "amazon.ae", "amazon.ca", "amazon.co.uk", "amazon.com.au", "amazon.com.br", "amazon.com.mx", "amazon.com.tr", "amazon.de", "amazon.es", "amazon.fr", "amazon.in", "amazon.it", "amazon.nl", "amazon.pl", "amazon.sa", "amazon.se", "amazon.sg"},
+		{"cox.com", "cox.net", "coxbusiness.com"},
+		{"mynortonaccount.com", "norton.com"},
+		{"verizon.com", "verizon.net"},
+		{"rakuten.com", "buy.com"},
+		{"siriusxm.com", "sirius.com"},
+		{"ea.com", "origin.com", "play4free.com", "tiberiumalliance.com"},
+		{"37signals.com", "basecamp.com", "basecamphq.com", "highrisehq.com"},
+		{"steampowered.com", "steamcommunity.com", "steamgames.com"},
+		{"chart.io", "chartio.com"},
+		{"gotomeeting.com", "citrixonline.com"},
+		{"gogoair.com", "gogoinflight.com"},
+		{"mysql.com", "oracle.com"},
+		{"discover.com", "discovercard.com"},
+		{"dcu.org", "dcu-online.org"},
+		{"healthcare.gov", "cuidadodesalud.gov", "cms.gov"},
+		{"pepco.com", "pepcoholdings.com"},
+		{"century21.com", "21online.com"},
+		{"comcast.com", "comcast.net", "xfinity.com"},
+		{"cricketwireless.com", "aiowireless.com"},
+		{"mandtbank.com", "mtb.com"},
+		{"dropbox.com", "getdropbox.com"},
+		{"snapfish.com", "snapfish.ca"},
+		{"alibaba.com", "aliexpress.com", "aliyun.com", "net.cn"},
+		{"playstation.com", "sonyentertainmentnetwork.com"},
+		{"mercadolivre.com", "mercadolivre.com.br", "mercadolibre.com", "mercadolibre.com.ar", "mercadolibre.com.mx"},
+		{"zendesk.com", "zopim.com"},
+		{"autodesk.com", "tinkercad.com"},
+		{"railnation.ru", "railnation.de", "rail-nation.com", "railnation.gr", "railnation.us", "trucknation.de", "traviangames.com"},
+		{"wpcu.coop", "wpcuonline.com"},
+		{"mathletics.com", "mathletics.com.au", "mathletics.co.uk"},
+		{"discountbank.co.il", "telebank.co.il"},
+		{"mi.com", "xiaomi.com"},
+		{"facebook.com", "messenger.com"},
+		{"postepay.it", "poste.it"},
+		{"skysports.com", "skybet.com", "skyvegas.com"},
+		{"disneymoviesanywhere.com", "go.com", "disney.com", "dadt.com", "disneyplus.com"},
+		{"pokemon-gl.com", "pokemon.com"},
+		{"myuv.com", "uvvu.com"},
+		{"bank-yahav.co.il", "bankhapoalim.co.il"},
+		{"mdsol.com", "imedidata.com"},
+		{"sears.com", "shld.net"},
+		{"xiami.com", "alipay.com"},
+		{"belkin.com", "seedonk.com"},
+		{"turbotax.com", "intuit.com"},
+		{"shopify.com", "myshopify.com"},
+		{"ebay.com", "ebay.at", "ebay.be", "ebay.ca", "ebay.ch", "ebay.cn", "ebay.co.jp", "ebay.co.th", "ebay.co.uk", "ebay.com.au", "ebay.com.hk", "ebay.com.my", "ebay.com.sg", "ebay.com.tw", "ebay.de", "ebay.es", "ebay.fr", "ebay.ie", "ebay.in", "ebay.it", "ebay.nl", "ebay.ph", "ebay.pl"},
+		{"techdata.com", "techdata.ch"},
+		{"schwab.com", "schwabplan.com"},
+		{"tesla.com", "teslamotors.com"},
+		{"morganstanley.com", "morganstanleyclientserv.com", "stockplanconnect.com", "ms.com"},
+		{"taxact.com", "taxactonline.com"},
+		{"mediawiki.org", "wikibooks.org", "wikidata.org", "wikimedia.org", "wikinews.org", "wikipedia.org", "wikiquote.org", "wikisource.org", "wikiversity.org", "wikivoyage.org", "wiktionary.org"},
+		{"airbnb.at", "airbnb.be", "airbnb.ca", "airbnb.ch", "airbnb.cl", "airbnb.co.cr", "airbnb.co.id", "airbnb.co.in", "airbnb.co.kr", "airbnb.co.nz", "airbnb.co.uk", "airbnb.co.ve", "airbnb.com", "airbnb.com.ar", "airbnb.com.au", "airbnb.com.bo", "airbnb.com.br", "airbnb.de", "airbnb.es", "airbnb.fr", "airbnb.it", "airbnb.jp", "airbnb.nl"},
+		{"eventbrite.at", "eventbrite.be", "eventbrite.ca", "eventbrite.ch", "eventbrite.cl", "eventbrite.co", "eventbrite.co.nz", "eventbrite.co.uk", "eventbrite.com", "eventbrite.com.au", "eventbrite.de", "eventbrite.es", "eventbrite.fr", "eventbrite.ie", "eventbrite.it", "eventbrite.nl"},
+		{"stackexchange.com", "superuser.com", "stackoverflow.com", "serverfault.com", "mathoverflow.net", "askubuntu.com", "stackapps.com"},
+		{"docusign.com", "docusign.net"},
+		{"envato.com", "themeforest.net", "codecanyon.net", "videohive.net", "audiojungle.net", "graphicriver.net", "photodune.net", "3docean.net"},
+		{"x10hosting.com", "x10premium.com"},
+		{"dnsomatic.com", "opendns.com", "umbrella.com"},
+		{"cagreatamerica.com", "canadaswonderland.com", "carowinds.com", "cedarfair.com", "cedarpoint.com", "dorneypark.com", "kingsdominion.com", "knotts.com", "miadventure.com", "schlitterbahn.com", "valleyfair.com", "visitkingsisland.com", "worldsoffun.com"},
+		{"ubnt.com", "ui.com"},
+		{"discordapp.com", "discord.com"},
+		{"netcup.de", "netcup.eu", "customercontrolpanel.de"},
+		{"yandex.com", "ya.ru", "yandex.az", "yandex.by", "yandex.co.il", "yandex.com.am", "yandex.com.ge", "yandex.com.tr", "yandex.ee", "yandex.fi", "yandex.fr", "yandex.kg", "yandex.kz", "yandex.lt", "yandex.lv", "yandex.md", "yandex.pl", "yandex.ru", "yandex.tj", "yandex.tm", "yandex.ua", "yandex.uz"},
+		{"sonyentertainmentnetwork.com", "sony.com"},
+		{"proton.me", "protonmail.com", "protonvpn.com"},
+		{"ubisoft.com", "ubi.com"},
+		{"transferwise.com", "wise.com"},
+		{"takeaway.com", "just-eat.dk", "just-eat.no", "just-eat.fr", "just-eat.ch", "lieferando.de", "lieferando.at", "thuisbezorgd.nl", "pyszne.pl"},
+		{"atlassian.com", "bitbucket.org", "trello.com", "statuspage.io", "atlassian.net", "jira.com"},
+		{"pinterest.com", "pinterest.com.au", "pinterest.cl", "pinterest.de", "pinterest.dk", "pinterest.es", "pinterest.fr", "pinterest.co.uk", "pinterest.jp", "pinterest.co.kr", "pinterest.nz", "pinterest.pt", "pinterest.se"},
+	}
+}
+
+// ErrNotLaunchable is returned for URIs that cannot or may not be opened on
+// this machine, like the androidapp:// URIs of mobile apps or file: URIs.
+var ErrNotLaunchable = errors.New("URI cannot be launched")
+
+var uriSchemeRe = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+
+// LaunchURI returns a login URI as it should be opened. URIs without a scheme
+// get https:// rather than the http:// URI matching assumes, as a browser
+// should not be sent to a login page over plain HTTP; "host:port" is taken
+// as a host, not as a scheme.
+//
+// Only http and https URIs and those with one of schemes, in lower case, are
+// opened. Login URIs may come from items shared by an organization, and one
+// should not be able to open local files or run protocol handlers with a
+// single key.
+func LaunchURI(raw string, schemes []string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("%w: empty URI", ErrNotLaunchable)
+	}
+	scheme := strings.TrimSuffix(uriSchemeRe.FindString(raw), ":")
+	if scheme != "" {
+		rest := strings.TrimPrefix(raw, scheme+":")
+		port, _, _ := strings.Cut(rest, "/")
+		if _, err := strconv.Atoi(port); err != nil {
+			scheme = strings.ToLower(scheme)
+			switch scheme {
+			case "http", "https":
+				return raw, nil
+			case "androidapp", "iosapp":
+				return "", fmt.Errorf("%w: %s", ErrNotLaunchable, raw)
+			}
+			for _, s := range schemes {
+				if s == scheme {
+					return raw, nil
+				}
+			}
+			return "", fmt.Errorf("%w: %s URIs are not opened unless launchCommands has an entry for %q", ErrNotLaunchable, scheme, scheme)
+		}
+	}
+	return "https://" + raw, nil
+}
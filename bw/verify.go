@@ -0,0 +1,183 @@
+package bw
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ErrCannotVerify is returned by VerifyPassword when the CLI's data file has
+// no master password hash to compare against.
+var ErrCannotVerify = errors.New("cannot check the master password: no password hash in the bw data file")
+
+// KDF types of Bitwarden accounts.
+const (
+	kdfPBKDF2   = 0
+	kdfArgon2id = 1
+)
+
+// The largest KDF settings Bitwarden accepts. Settings may come from files
+// anyone can craft, so they are checked before deriving keys.
+const (
+	maxPBKDF2Iterations = 2000000
+	maxArgon2Iterations = 10
+	maxKDFMemoryMiB     = 1024
+	maxKDFParallelism   = 16
+)
+
+// ErrUnsupportedKDF is returned for KDF types and settings Bitwarden does not
+// use.
+var ErrUnsupportedKDF = errors.New("unsupported KDF settings")
+
+type kdfConfig struct {
+	Type        int `json:"kdfType"`
+	Iterations  int `json:"iterations"`
+	Memory      int `json:"memory"`
+	Parallelism int `json:"parallelism"`
+}
+
+// localAuth is what the CLI keeps to check the master password without the
+// server: the account's KDF settings and the local master key hash.
+type localAuth struct {
+	kdf  kdfConfig
+	hash string
+}
+
+// dataFilePath returns the data file of the bw CLI.
+func dataFilePath() (string, error) {
+	if dir := os.Getenv("BITWARDENCLI_APPDATA_DIR"); dir != "" {
+		return filepath.Join(dir, "data.json"), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "Bitwarden CLI", "data.json"), nil
+}
+
+// readLocalAuth finds the KDF settings and the master key hash of the account
+// with userID, or of the active account if it is empty, in the bw data file.
+// Both the per-key layout of current CLIs and the per-account objects of
+// older ones are understood.
+func readLocalAuth(path string, userID string) (localAuth, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return localAuth{}, err
+	}
+	var state map[string]json.RawMessage
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return localAuth{}, err
+	}
+	for _, key := range []string{"global_account_activeAccountId", "activeUserId"} {
+		if raw, ok := state[key]; ok && userID == "" {
+			_ = json.Unmarshal(raw, &userID)
+		}
+	}
+	if userID == "" {
+		return localAuth{}, ErrCannotVerify
+	}
+
+	var auth localAuth
+	if raw, ok := state["user_"+userID+"_masterPassword_masterKeyHash"]; ok {
+		_ = json.Unmarshal(raw, &auth.hash)
+		if raw, ok := state["user_"+userID+"_kdfConfig_kdfConfig"]; ok {
+			_ = json.Unmarshal(raw, &auth.kdf)
+		}
+	} else if raw, ok := state[userID]; ok {
+		var account struct {
+			Profile struct {
+				KdfType        int    `json:"kdfType"`
+				KdfIterations  int    `json:"kdfIterations"`
+				KdfMemory      int    `json:"kdfMemory"`
+				KdfParallelism int    `json:"kdfParallelism"`
+				KeyHash        string `json:"keyHash"`
+			} `json:"profile"`
+		}
+		if json.Unmarshal(raw, &account) == nil {
+			p := account.Profile
+			auth = localAuth{kdfConfig{p.KdfType, p.KdfIterations, p.KdfMemory, p.KdfParallelism}, p.KeyHash}
+		}
+	}
+	if auth.hash == "" || auth.kdf.Iterations < 1 {
+		return localAuth{}, ErrCannotVerify
+	}
+	return auth, nil
+}
+
+// masterKey derives the master key of an account from its password and email.
+func masterKey(pw string, email string, kdf kdfConfig) ([]byte, error) {
+	return deriveKey(pw, strings.ToLower(strings.TrimSpace(email)), kdf)
+}
+
+// deriveKey derives a key from a password the way the Bitwarden clients do,
+// for master keys and password protected exports alike.
+func deriveKey(pw string, salt string, kdf kdfConfig) ([]byte, error) {
+	switch kdf.Type {
+	case kdfPBKDF2:
+		if kdf.Iterations < 1 || kdf.Iterations > maxPBKDF2Iterations {
+			return nil, fmt.Errorf("%w: PBKDF2 with %d iterations", ErrUnsupportedKDF, kdf.Iterations)
+		}
+		return pbkdf2.Key([]byte(pw), []byte(salt), kdf.Iterations, 32, sha256.New), nil
+	case kdfArgon2id:
+		if kdf.Iterations < 1 || kdf.Iterations > maxArgon2Iterations ||
+			kdf.Memory < 1 || kdf.Memory > maxKDFMemoryMiB ||
+			kdf.Parallelism < 1 || kdf.Parallelism > maxKDFParallelism {
+			return nil, fmt.Errorf("%w: Argon2id with %d iterations, %d MiB, %d threads", ErrUnsupportedKDF, kdf.Iterations, kdf.Memory, kdf.Parallelism)
+		}
+		hashed := sha256.Sum256([]byte(salt))
+		return argon2.IDKey([]byte(pw), hashed[:], uint32(kdf.Iterations), uint32(kdf.Memory*1024), uint8(kdf.Parallelism), 32), nil
+	default:
+		return nil, fmt.Errorf("%w: type %d", ErrUnsupportedKDF, kdf.Type)
+	}
+}
+
+// localMasterKeyHash is the hash the clients keep to check the master password
+// offline: one more PBKDF2 round over the master key than the server gets.
+func localMasterKeyHash(pw string, email string, kdf kdfConfig) (string, error) {
+	key, err := masterKey(pw, email, kdf)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(pbkdf2.Key(key, []byte(pw), 2, 32, sha256.New)), nil
+}
+
+// VerifyPassword re-prompts for the master password before sensitive actions.
+// The password is checked offline against the master key hash the CLI keeps,
+// so the session, other shells using it and the agent are left alone.
+func (bwm *Manager) VerifyPassword(pw string) error {
+	return CheckPassword(bwm.VaultStatus, pw)
+}
+
+// CheckPassword is VerifyPassword for the account of status. It does not
+// touch the manager, so it can run in the background on a copy of the status.
+func CheckPassword(status VaultStatus, pw string) error {
+	if status.Status != Unlocked {
+		return ErrLocked
+	}
+	path, err := dataFilePath()
+	if err != nil {
+		return fmt.Errorf("failed to verify password: %w", err)
+	}
+	auth, err := readLocalAuth(path, status.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to verify password: %w", err)
+	}
+	hash, err := localMasterKeyHash(pw, status.UserEmail, auth.kdf)
+	if err != nil {
+		return fmt.Errorf("failed to verify password: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(auth.hash)) != 1 {
+		return ErrInvalidPassword
+	}
+	return nil
+}
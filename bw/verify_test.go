@@ -0,0 +1,74 @@
+package bw
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeDataFile(t *testing.T, state map[string]any) {
+	t.Helper()
+	dir := t.TempDir()
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "data.json"), data, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BITWARDENCLI_APPDATA_DIR", dir)
+}
+
+func TestVerifyPassword(t *testing.T) {
+	const email = "Alice@Example.com"
+	pbkdf := kdfConfig{Type: kdfPBKDF2, Iterations: 1000}
+	argon := kdfConfig{Type: kdfArgon2id, Iterations: 1, Memory: 16, Parallelism: 2}
+	hash := func(kdf kdfConfig) string {
+		h, err := localMasterKeyHash("correct horse", "alice@example.com", kdf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	layouts := map[string]map[string]any{
+		"current": {
+			"global_account_activeAccountId":       "u1",
+			"user_u1_kdfConfig_kdfConfig":          pbkdf,
+			"user_u1_masterPassword_masterKeyHash": hash(pbkdf),
+		},
+		"current argon2id": {
+			"global_account_activeAccountId":       "u1",
+			"user_u1_kdfConfig_kdfConfig":          argon,
+			"user_u1_masterPassword_masterKeyHash": hash(argon),
+		},
+		"legacy": {
+			"activeUserId": "u1",
+			"u1": map[string]any{"profile": map[string]any{
+				"kdfType":       kdfPBKDF2,
+				"kdfIterations": pbkdf.Iterations,
+				"keyHash":       hash(pbkdf),
+			}},
+		},
+	}
+	for name, state := range layouts {
+		t.Run(name, func(t *testing.T) {
+			writeDataFile(t, state)
+			bwm := &Manager{VaultStatus: VaultStatus{Status: Unlocked, UserEmail: email}}
+			if err := bwm.VerifyPassword("correct horse"); err != nil {
+				t.Errorf("right password: %v", err)
+			}
+			if err := bwm.VerifyPassword("wrong"); !errors.Is(err, ErrInvalidPassword) {
+				t.Errorf("wrong password: got %v, want ErrInvalidPassword", err)
+			}
+		})
+	}
+
+	writeDataFile(t, map[string]any{"global_account_activeAccountId": "u1"})
+	bwm := &Manager{VaultStatus: VaultStatus{Status: Unlocked, UserEmail: email}}
+	if err := bwm.VerifyPassword("correct horse"); !errors.Is(err, ErrCannotVerify) {
+		t.Errorf("no hash: got %v, want ErrCannotVerify", err)
+	}
+}
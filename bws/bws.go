@@ -0,0 +1,107 @@
+// Package bws talks to Bitwarden Secrets Manager, the secrets store for
+// machines that is separate from the password vault, through the bws CLI.
+package bws
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoAccessToken is returned when no machine account access token is
+	// configured.
+	ErrNoAccessToken  = errors.New("no Secrets Manager access token")
+	ErrSecretNotFound = errors.New("secret not found")
+	// ErrProjectNotFound is returned when creating a secret in a project that
+	// does not exist or the access token cannot write to.
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrAmbiguousSecret is returned by FindSecret for a key several secrets
+	// share.
+	ErrAmbiguousSecret = errors.New("several secrets have this key")
+)
+
+// Project groups secrets a machine account may access.
+type Project struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organizationId"`
+	Name           string    `json:"name"`
+	CreationDate   time.Time `json:"creationDate"`
+	RevisionDate   time.Time `json:"revisionDate"`
+}
+
+// Secret is a key and value stored in Secrets Manager.
+type Secret struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organizationId"`
+	ProjectID      string    `json:"projectId"`
+	Key            string    `json:"key"`
+	Value          string    `json:"value"`
+	Note           string    `json:"note"`
+	CreationDate   time.Time `json:"creationDate"`
+	RevisionDate   time.Time `json:"revisionDate"`
+}
+
+// SecretInput is a new secret, or the changes to one. Empty fields are left
+// unchanged by EditSecret.
+type SecretInput struct {
+	Key       string
+	Value     string
+	Note      string
+	ProjectID string
+}
+
+// Backend is the Secrets Manager as far as gobw uses it. CLI implements it
+// with the bws binary.
+type Backend interface {
+	Projects() ([]Project, error)
+	// Secrets lists the secrets of a project, or all secrets the access
+	// token can read for an empty projectID.
+	Secrets(projectID string) ([]Secret, error)
+	Secret(id string) (Secret, error)
+	CreateSecret(in SecretInput) (Secret, error)
+	EditSecret(id string, in SecretInput) (Secret, error)
+	DeleteSecret(id string) error
+}
+
+// FindSecret returns the secret with the given ID or, failing that, the only
+// secret with the given key.
+func FindSecret(b Backend, query string) (Secret, error) {
+	secrets, err := b.Secrets("")
+	if err != nil {
+		return Secret{}, err
+	}
+	var found []Secret
+	for _, s := range secrets {
+		if s.ID == query {
+			return s, nil
+		}
+		if s.Key == query {
+			found = append(found, s)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Secret{}, ErrSecretNotFound
+	case 1:
+		return found[0], nil
+	default:
+		ids := make([]string, 0, len(found))
+		for _, s := range found {
+			ids = append(ids, s.ID)
+		}
+		return Secret{}, fmt.Errorf("%w: %q is the key of %s", ErrAmbiguousSecret, query, strings.Join(ids, ", "))
+	}
+}
+
+// ProjectName returns the name of the project with the given ID, or the ID
+// if it is not among projects.
+func ProjectName(projects []Project, id string) string {
+	for _, p := range projects {
+		if p.ID == id {
+			return p.Name
+		}
+	}
+	return id
+}
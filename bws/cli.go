@@ -0,0 +1,186 @@
+package bws
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// AccessTokenEnv is the variable bws reads the access token from, which
+// also takes precedence over a configured token.
+const AccessTokenEnv = "BWS_ACCESS_TOKEN"
+
+// CLI runs the bws binary. The access token is passed in the environment,
+// never on the command line; bws only takes secret values as arguments
+// though, so those are briefly visible in the process list when creating and
+// editing secrets.
+type CLI struct {
+	// Program is the bws binary, found in $PATH by default.
+	Program string
+	// AccessToken returns the machine account access token. It is asked for
+	// on every call, so it may come from a vault that is still locked when
+	// the CLI is set up.
+	AccessToken func() (string, error)
+	// ServerURL, if set, is a self-hosted server.
+	ServerURL string
+}
+
+func NewCLI(program string, accessToken func() (string, error), serverURL string) *CLI {
+	if program == "" {
+		program = "bws"
+	}
+	return &CLI{Program: program, AccessToken: accessToken, ServerURL: serverURL}
+}
+
+// statusNotFound is how bws reports a 404 from the server, as in "Received
+// error message from server: [404 Not Found] {"message":"Resource not
+// found.", ...}". Whether the secret or a project was not found depends on
+// the call.
+const statusNotFound = "[404 Not Found]"
+
+var errResourceNotFound = errors.New("resource not found")
+
+// run runs bws with args and decodes its JSON output into v, if given. A 404
+// from the server is returned as errResourceNotFound.
+func (c *CLI) run(v any, args ...string) error {
+	token := os.Getenv(AccessTokenEnv)
+	if token == "" && c.AccessToken != nil {
+		var err error
+		token, err = c.AccessToken()
+		if err != nil {
+			return err
+		}
+	}
+	if token == "" {
+		return ErrNoAccessToken
+	}
+	// the global options go first, as args may end in positional arguments
+	// after "--"
+	global := []string{"--output", "json", "--color", "no"}
+	if c.ServerURL != "" {
+		global = append(global, "--server-url", c.ServerURL)
+	}
+	cmd := exec.Command(c.Program, append(global, args...)...) // #nosec G204
+	cmd.Env = append(os.Environ(), AccessTokenEnv+"="+token)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, statusNotFound) {
+			return errResourceNotFound
+		}
+		if msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(out, v)
+}
+
+func (c *CLI) Projects() ([]Project, error) {
+	var projects []Project
+	err := c.run(&projects, "project", "list")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	return projects, nil
+}
+
+func (c *CLI) Secrets(projectID string) ([]Secret, error) {
+	args := []string{"secret", "list"}
+	if projectID != "" {
+		args = append(args, projectID)
+	}
+	var secrets []Secret
+	err := c.run(&secrets, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	return secrets, nil
+}
+
+func (c *CLI) Secret(id string) (Secret, error) {
+	var secret Secret
+	err := c.run(&secret, "secret", "get", id)
+	if errors.Is(err, errResourceNotFound) {
+		return Secret{}, fmt.Errorf("%w: %s", ErrSecretNotFound, id)
+	}
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to get secret: %w", err)
+	}
+	return secret, nil
+}
+
+func (c *CLI) CreateSecret(in SecretInput) (Secret, error) {
+	if in.Key == "" || in.ProjectID == "" {
+		return Secret{}, fmt.Errorf("failed to create secret: a key and a project are required")
+	}
+	args := []string{"secret", "create"}
+	if in.Note != "" {
+		args = append(args, "--note="+in.Note)
+	}
+	// generated values often start with "-", which bws would take for an
+	// option
+	args = append(args, "--", in.Key, in.Value, in.ProjectID)
+	var secret Secret
+	err := c.run(&secret, args...)
+	if errors.Is(err, errResourceNotFound) {
+		return Secret{}, fmt.Errorf("failed to create secret: %w: %s", ErrProjectNotFound, in.ProjectID)
+	}
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to create secret: %w", err)
+	}
+	return secret, nil
+}
+
+func (c *CLI) EditSecret(id string, in SecretInput) (Secret, error) {
+	args := []string{"secret", "edit"}
+	for _, flag := range []struct {
+		name  string
+		value string
+	}{
+		{"--key", in.Key},
+		{"--value", in.Value},
+		{"--note", in.Note},
+		{"--project-id", in.ProjectID},
+	} {
+		// joined with "=" so values starting with "-" are not taken for
+		// options
+		if flag.value != "" {
+			args = append(args, flag.name+"="+flag.value)
+		}
+	}
+	args = append(args, "--", id)
+	var secret Secret
+	err := c.run(&secret, args...)
+	if errors.Is(err, errResourceNotFound) {
+		// either the secret or the project it is moved to
+		if in.ProjectID != "" {
+			return Secret{}, fmt.Errorf("failed to edit secret: secret %s or project %s not found", id, in.ProjectID)
+		}
+		return Secret{}, fmt.Errorf("failed to edit secret: %w: %s", ErrSecretNotFound, id)
+	}
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to edit secret: %w", err)
+	}
+	return secret, nil
+}
+
+func (c *CLI) DeleteSecret(id string) error {
+	err := c.run(nil, "secret", "delete", id)
+	if errors.Is(err, errResourceNotFound) {
+		return fmt.Errorf("failed to delete secret: %w: %s", ErrSecretNotFound, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package bws
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeBWS is a stand-in bws logging its arguments to $FAKE_BWS_LOG. It knows
+// project p1 with secrets s1 and s2 sharing the key DB_PASSWORD, and answers
+// anything else the way bws reports a 404 from the server.
+const fakeBWS = `#!/bin/sh
+echo "$*" >>"$FAKE_BWS_LOG"
+[ "$1 $2 $3 $4" = "--output json --color no" ] || exit 2
+shift 4
+if [ "$BWS_ACCESS_TOKEN" != "TOKEN" ]; then
+	echo "Error: Access token is not in a valid format" >&2
+	exit 1
+fi
+notfound() {
+	echo 'Error: Received error message from server: [404 Not Found] {"message":"Resource not found.","validationErrors":null}' >&2
+	exit 1
+}
+case "$1 $2 $3" in
+"project list "*)
+	echo '[{"id":"p1","organizationId":"o1","name":"Backend"}]'
+	;;
+"secret list "*)
+	echo '[{"id":"s1","key":"DB_PASSWORD","value":"one","projectId":"p1"},{"id":"s2","key":"DB_PASSWORD","value":"two","projectId":"p1"}]'
+	;;
+"secret get s1")
+	echo '{"id":"s1","key":"DB_PASSWORD","value":"one","projectId":"p1"}'
+	;;
+"secret create --")
+	[ "$6" = "p1" ] || notfound
+	echo "{\"id\":\"s3\",\"key\":\"$4\",\"value\":\"$5\",\"projectId\":\"$6\"}"
+	;;
+"secret edit --value=-"*)
+	[ "$4 $5" = "-- s1" ] || notfound
+	echo "{\"id\":\"s1\",\"key\":\"DB_PASSWORD\",\"value\":\"${3#--value=}\",\"projectId\":\"p1\"}"
+	;;
+"secret delete s1")
+	;;
+*)
+	notfound
+	;;
+esac
+`
+
+// newTestCLI returns a CLI running the stand-in bws with token, and a
+// function returning the argument lists it was run with.
+func newTestCLI(t *testing.T, token string) (*CLI, func() []string) {
+	t.Helper()
+	dir := t.TempDir()
+	program := filepath.Join(dir, "bws")
+	err := os.WriteFile(program, []byte(fakeBWS), 0o700) // #nosec G306
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := filepath.Join(dir, "log")
+	t.Setenv("FAKE_BWS_LOG", log)
+	t.Setenv(AccessTokenEnv, "")
+	c := NewCLI(program, func() (string, error) { return token, nil }, "")
+	return c, func() []string {
+		data, _ := os.ReadFile(log) // #nosec G304
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+}
+
+func TestCLI(t *testing.T) {
+	c, calls := newTestCLI(t, "TOKEN")
+	projects, err := c.Projects()
+	if err != nil || len(projects) != 1 || projects[0].Name != "Backend" {
+		t.Errorf("Projects: got %+v, %v", projects, err)
+	}
+	secret, err := c.Secret("s1")
+	if err != nil || secret.Key != "DB_PASSWORD" || secret.Value != "one" {
+		t.Errorf("Secret: got %+v, %v", secret, err)
+	}
+	// generated values may look like options
+	secret, err = c.CreateSecret(SecretInput{Key: "API_KEY", Value: "-v", ProjectID: "p1"})
+	if err != nil || secret.ID != "s3" || secret.Key != "API_KEY" || secret.Value != "-v" {
+		t.Errorf("CreateSecret: got %+v, %v", secret, err)
+	}
+	secret, err = c.EditSecret("s1", SecretInput{Value: "-x"})
+	if err != nil || secret.Value != "-x" {
+		t.Errorf("EditSecret: got %+v, %v", secret, err)
+	}
+	if err := c.DeleteSecret("s1"); err != nil {
+		t.Errorf("DeleteSecret: %v", err)
+	}
+	for _, args := range calls() {
+		if !strings.HasPrefix(args, "--output json --color no ") {
+			t.Errorf("bws run without JSON output: %q", args)
+		}
+		if strings.Contains(args, "TOKEN") {
+			t.Errorf("access token on the command line: %q", args)
+		}
+	}
+}
+
+func TestCLINotFound(t *testing.T) {
+	c, _ := newTestCLI(t, "TOKEN")
+	if _, err := c.Secret("missing"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Secret: got %v, want %v", err, ErrSecretNotFound)
+	}
+	if err := c.DeleteSecret("missing"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("DeleteSecret: got %v, want %v", err, ErrSecretNotFound)
+	}
+	// a missing project is not a missing secret
+	_, err := c.CreateSecret(SecretInput{Key: "API_KEY", Value: "v", ProjectID: "missing"})
+	if !errors.Is(err, ErrProjectNotFound) || errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("CreateSecret: got %v, want %v", err, ErrProjectNotFound)
+	}
+	// other errors mentioning "not found" are passed on as they are
+	c.Program = filepath.Join(t.TempDir(), "bws")
+	err = os.WriteFile(c.Program, []byte("#!/bin/sh\necho 'Error: project p1 was not found in the state file' >&2\nexit 1\n"), 0o700) // #nosec G306
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Secret("s1"); err == nil || errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Secret: got %v, want the error bws printed", err)
+	}
+}
+
+func TestCLIAccessToken(t *testing.T) {
+	c, calls := newTestCLI(t, "")
+	if _, err := c.Projects(); !errors.Is(err, ErrNoAccessToken) {
+		t.Errorf("Projects: got %v, want %v", err, ErrNoAccessToken)
+	}
+	if args := calls(); len(args) != 1 || args[0] != "" {
+		t.Errorf("bws was run without an access token: %q", args)
+	}
+
+	// BWS_ACCESS_TOKEN takes precedence over the configured token
+	c.AccessToken = func() (string, error) { return "", errors.New("vault is locked") }
+	t.Setenv(AccessTokenEnv, "TOKEN")
+	if _, err := c.Projects(); err != nil {
+		t.Errorf("Projects: %v", err)
+	}
+}
+
+func TestFindSecret(t *testing.T) {
+	c, _ := newTestCLI(t, "TOKEN")
+	secret, err := FindSecret(c, "s1")
+	if err != nil || secret.ID != "s1" {
+		t.Errorf("FindSecret by ID: got %+v, %v", secret, err)
+	}
+	if _, err := FindSecret(c, "DB_PASSWORD"); !errors.Is(err, ErrAmbiguousSecret) {
+		t.Errorf("FindSecret by a shared key: got %v, want %v", err, ErrAmbiguousSecret)
+	}
+	if _, err := FindSecret(c, "NOPE"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("FindSecret: got %v, want %v", err, ErrSecretNotFound)
+	}
+}
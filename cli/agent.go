@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sapslaj/gobw/agent"
+	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/sshagent"
+)
+
+func (c *CLI) agent(args []string) error {
+	fs := c.flagSet("agent")
+	socket := fs.String("socket", agent.DefaultSocketPath(), "listen on the unix socket at `path`")
+	idle := fs.Duration("idle-timeout", 15*time.Minute, "lock the vault after no request for `duration`; 0 never locks")
+	poll := fs.Duration("poll", 10*time.Second, "check whether the vault is still unlocked every `interval`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *poll <= 0 || *idle < 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	// a locked vault is fine: the first client to unlock it unlocks it for
+	// all
+	switch c.bwm.VaultStatus.Status {
+	case bw.Unauthenticated:
+		return bw.ErrNotLoggedIn
+	case bw.Unlocked:
+		if err := c.load(); err != nil {
+			return err
+		}
+	}
+
+	srv, err := agent.NewServer(c.bwm, *idle)
+	if err != nil {
+		return err
+	}
+	l, err := sshagent.Listen(*socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(*socket)
+	defer l.Close()
+	fmt.Fprintf(c.stdout, "%s=%s; export %s;\n", agent.SocketEnv, shellQuote(*socket), agent.SocketEnv)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(l)
+	}()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	ticker := time.NewTicker(*poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-served:
+			return err
+		case <-signals:
+			return nil
+		case <-ticker.C:
+			idleLocked, err := srv.Refresh()
+			if err != nil {
+				fmt.Fprintln(c.stderr, "gobw:", err)
+			}
+			if idleLocked {
+				fmt.Fprintln(c.stderr, "idle for", *idle, "- locked the vault")
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Package cli implements the non-interactive gobw subcommands.
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/bws"
+	"github.com/sapslaj/gobw/config"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK        = 0
+	ExitError     = 1
+	ExitUsage     = 2
+	ExitNotFound  = 3
+	ExitAmbiguous = 4
+	ExitLocked    = 5
+)
+
+var errUsage = errors.New("usage error")
+
+type command struct {
+	usage string
+	run   func(c *CLI, args []string) error
+}
+
+func commands() map[string]command {
+	return map[string]command{
+		"list":              {"list [-folder F] [-org O] [-type T] [-url U] [-search S] [output flags]", (*CLI).list},
+		"get":               {"get [output flags] <field> <query>", (*CLI).get},
+		"show":              {"show [-reveal] [output flags] <query>", (*CLI).show},
+		"health":            {"health [-max-age-days N] [-breaches] [output flags]", (*CLI).health},
+		"import":            {"import [-format F] [-folder F] [-dry-run] [-duplicates] FILE|DIR", (*CLI).importItems},
+		"agent":             {"agent [-socket PATH] [-idle-timeout DURATION] [-poll DURATION]", (*CLI).agent},
+		"aws-credentials":   {"aws-credentials <query>", (*CLI).awsCredentials},
+		"kube-token":        {"kube-token [-api-version V] <query>", (*CLI).kubeToken},
+		"docker-credential": {"docker-credential [-folder F] get|store|erase|list", (*CLI).dockerCredential},
+		"export":            {"export [-format json|csv|encrypted_json|kdbx] [-cipher aes|chacha20] [-output FILE] [-force] [-folder F] [-org O] [-type T]", (*CLI).export},
+		"git-credential":    {"git-credential get|store|erase", (*CLI).gitCredential},
+		"inject":            {"inject [-i TEMPLATE] [-o FILE]", (*CLI).inject},
+		"secret-service":    {"secret-service [-poll DURATION]", (*CLI).secretService},
+		"secrets":           {"secrets projects|list|get|create|edit|delete [flags] [args]", (*CLI).secrets},
+		"send":              {"send list|create|receive|delete [flags] [args]", (*CLI).send},
+		"run":               {"run [-env NAME=VALUE]... [-env-file FILE]... [-no-mask] [-pass-session] -- COMMAND [ARGS]", (*CLI).run},
+		"ssh-agent":         {"ssh-agent [-socket PATH] [-confirm] [-poll DURATION]", (*CLI).sshAgent},
+	}
+}
+
+// IsCommand reports whether name is a known subcommand.
+func IsCommand(name string) bool {
+	_, ok := commands()[name]
+	return ok
+}
+
+// CLI holds the state shared by all subcommands.
+type CLI struct {
+	bwm    *bw.Manager
+	cfg    *config.Config
+	stdout io.Writer
+	stderr io.Writer
+	// stdin is opened lazily for answering prompts without a terminal.
+	stdin *bufio.Reader
+	// reprompted records when the master password was last given for items
+	// with master password re-prompt, by item ID.
+	reprompted map[string]time.Time
+}
+
+func New(bwm *bw.Manager, cfg *config.Config, stdout io.Writer, stderr io.Writer) *CLI {
+	c := &CLI{
+		bwm:        bwm,
+		cfg:        cfg,
+		stdout:     stdout,
+		stderr:     stderr,
+		reprompted: make(map[string]time.Time),
+	}
+	bwm.SetReprompt(c.reprompt)
+	return c
+}
+
+// Run executes the subcommand named by args[0] and returns the process exit
+// code.
+func (c *CLI) Run(args []string) int {
+	if len(args) == 0 {
+		c.usage()
+		return ExitUsage
+	}
+	cmd, ok := commands()[args[0]]
+	if !ok {
+		fmt.Fprintf(c.stderr, "unknown command %q\n", args[0])
+		c.usage()
+		return ExitUsage
+	}
+	err := cmd.run(c, args[1:])
+	if err == nil {
+		return ExitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitUsage
+	}
+	var exitStatus *exitStatusError
+	if errors.As(err, &exitStatus) {
+		return exitStatus.code
+	}
+	fmt.Fprintln(c.stderr, "gobw:", err)
+	return exitCode(err)
+}
+
+func exitCode(err error) int {
+	var ambiguous *bw.AmbiguousError
+	switch {
+	case errors.Is(err, errUsage):
+		return ExitUsage
+	case errors.As(err, &ambiguous), errors.Is(err, bws.ErrAmbiguousSecret):
+		return ExitAmbiguous
+	case errors.Is(err, bw.ErrItemNotFound), errors.Is(err, bw.ErrFieldNotFound), errors.Is(err, bw.ErrSendNotFound), errors.Is(err, bws.ErrSecretNotFound):
+		return ExitNotFound
+	case errors.Is(err, bw.ErrLocked), errors.Is(err, bw.ErrNotLoggedIn), errors.Is(err, bw.ErrInvalidPassword):
+		return ExitLocked
+	default:
+		return ExitError
+	}
+}
+
+func (c *CLI) usage() {
+	cmds := commands()
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(c.stderr, "Usage: gobw [flags] <command> [args]")
+	fmt.Fprintln(c.stderr, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(c.stderr, "  gobw %s\n", cmds[name].usage)
+	}
+}
+
+func (c *CLI) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: gobw %s\n", commands()[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// load makes sure the vault is unlocked and the items are loaded. A locked
+// vault is unlocked with pinentry if one is configured; nobody may be there
+// to answer on the terminal.
+func (c *CLI) load() error {
+	switch c.bwm.VaultStatus.Status {
+	case bw.Unlocked:
+	case bw.Unauthenticated:
+		return bw.ErrNotLoggedIn
+	default:
+		if c.cfg.Pinentry == "" {
+			return bw.ErrLocked
+		}
+		if err := c.unlock("gobw needs the vault unlocked."); err != nil {
+			return err
+		}
+	}
+	return c.bwm.UpdateList()
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+func (c *CLI) awsCredentials(args []string) error {
+	fs := c.flagSet("aws-credentials")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+	item, err := c.bwm.FindItem(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	item, err = c.bwm.CheckReprompt(item, "")
+	if err != nil {
+		return err
+	}
+	creds, err := item.AWSCredentials()
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(c.stdout).Encode(creds)
+}
+
+// kubeExecInfo is the part of $KUBERNETES_EXEC_INFO telling which
+// ExecCredential version kubectl wants.
+type kubeExecInfo struct {
+	APIVersion string `json:"apiVersion"`
+}
+
+func (c *CLI) kubeToken(args []string) error {
+	fs := c.flagSet("kube-token")
+	apiVersion := fs.String("api-version", "", "ExecCredential `version` (default from $KUBERNETES_EXEC_INFO, or "+bw.ExecCredentialV1+")")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if *apiVersion == "" {
+		if info := os.Getenv("KUBERNETES_EXEC_INFO"); info != "" {
+			var execInfo kubeExecInfo
+			if err := json.Unmarshal([]byte(info), &execInfo); err != nil {
+				return fmt.Errorf("invalid KUBERNETES_EXEC_INFO: %w", err)
+			}
+			*apiVersion = execInfo.APIVersion
+		}
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+	item, err := c.bwm.FindItem(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	item, err = c.bwm.CheckReprompt(item, "")
+	if err != nil {
+		return err
+	}
+	cred, err := item.ExecCredential(*apiVersion)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(c.stdout).Encode(cred)
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+var errNoRegistry = errors.New("no registry server URL given")
+
+// dockerCredential implements docker's credential helper protocol. Docker
+// reads errors from stdout, so they are reported there instead of stderr.
+func (c *CLI) dockerCredential(args []string) error {
+	fs := c.flagSet("docker-credential")
+	folder := fs.String("folder", "", "store registry logins in this `folder` (default from config, or Docker)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if *folder == "" {
+		*folder = c.cfg.DockerCredentialFolder
+	}
+	if *folder == "" {
+		*folder = bw.DefaultDockerCredentialFolder
+	}
+	err := c.dockerCredentialOp(fs.Arg(0), *folder)
+	if err != nil {
+		fmt.Fprintln(c.stdout, err)
+		return &exitStatusError{ExitError}
+	}
+	return nil
+}
+
+func (c *CLI) dockerCredentialOp(op string, folder string) error {
+	switch op {
+	case "get", "store", "erase", "list":
+	default:
+		return fmt.Errorf("unknown credential action %q", op)
+	}
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+	serverURL := strings.TrimSpace(string(input))
+	if serverURL == "" && (op == "get" || op == "erase") {
+		return errNoRegistry
+	}
+	switch op {
+	case "get":
+		item, err := c.bwm.FindDockerCredential(serverURL, folder)
+		if err != nil {
+			return err
+		}
+		item, err = c.bwm.CheckReprompt(item, "password")
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(c.stdout).Encode(bw.DockerCredential{
+			ServerURL: serverURL,
+			Username:  item.Login.Username,
+			Secret:    item.Login.Password,
+		})
+	case "store":
+		var cred bw.DockerCredential
+		if err := json.Unmarshal(input, &cred); err != nil {
+			return fmt.Errorf("invalid credential: %w", err)
+		}
+		_, err := c.bwm.StoreDockerCredential(cred, folder)
+		return err
+	case "erase":
+		return c.bwm.EraseDockerCredential(serverURL, folder)
+	default:
+		return json.NewEncoder(c.stdout).Encode(c.bwm.ListDockerCredentials(folder))
+	}
+}
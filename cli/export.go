@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/kdbx"
+)
+
+func (c *CLI) export(args []string) error {
+	fs := c.flagSet("export")
+	format := fs.String("format", "json", "export `format`: json, csv, encrypted_json or kdbx")
+	cipher := fs.String("cipher", "aes", "`cipher` of kdbx exports: aes or chacha20")
+	output := fs.String("output", "", "write the export to `file` (default bitwarden_export_<time>.<ext>)")
+	force := fs.Bool("force", false, "overwrite the output file if it exists")
+	folder := fs.String("folder", "", "only export items in the folder with this name or ID")
+	org := fs.String("org", "", "only export items owned by the organization with this name or ID")
+	itemType := fs.String("type", "", "only export items of this `type` (login, note, card, identity, sshkey)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	opts := bw.ExportOptions{
+		Filter: bw.ExportFilter{Folder: *folder, Organization: *org},
+	}
+	var err error
+	opts.Format, err = bw.ParseExportFormat(*format)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if opts.Format == bw.ExportKDBX {
+		kdbxOpts := kdbx.DefaultOptions()
+		switch strings.ToLower(*cipher) {
+		case "aes":
+		case "chacha20":
+			kdbxOpts.Cipher = kdbx.ChaCha20
+		default:
+			return fmt.Errorf("%w: unknown cipher %q", errUsage, *cipher)
+		}
+		opts.KeePass = &kdbxOpts
+	}
+	if *itemType != "" {
+		opts.Filter.Type, err = bw.ParseItemType(*itemType)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errUsage, err)
+		}
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+
+	// exports hold every secret in the clear, so the master password is
+	// always asked for again.
+	pw, err := c.promptMasterPassword("Exporting the vault needs the master password again.", false)
+	if err != nil {
+		return err
+	}
+	err = c.bwm.VerifyPassword(pw)
+	if err != nil {
+		return err
+	}
+	opts.MasterPassword = pw
+	if opts.Format.IsEncrypted() {
+		opts.Password, err = c.promptNewPassword("File password: ")
+		if err != nil {
+			return err
+		}
+	}
+
+	path := *output
+	if path == "" {
+		path = bw.DefaultExportPath(opts.Format, time.Now())
+	}
+	result, err := c.bwm.ExportFile(path, *force, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "exported %d items to %s\n", result.Exported, path)
+	if result.Skipped > 0 {
+		fmt.Fprintf(c.stderr, "skipped %d card, identity and SSH key items, which %s cannot hold\n", result.Skipped, opts.Format)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+// gitCredential implements git's credential helper protocol. Errors make git
+// fall back to its other helpers or to prompting.
+func (c *CLI) gitCredential(args []string) error {
+	fs := c.flagSet("git-credential")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	op := fs.Arg(0)
+	switch op {
+	case "get", "store", "erase":
+	default:
+		// git may add operations, which helpers are to ignore
+		return nil
+	}
+	req, err := bw.ReadGitCredential(os.Stdin)
+	if err != nil {
+		return err
+	}
+	switch op {
+	case "get":
+		if err := c.load(); err != nil {
+			return err
+		}
+		item, err := c.bwm.FindGitCredential(req)
+		if errors.Is(err, bw.ErrItemNotFound) {
+			// nothing to answer; git asks the next helper
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		item, err = c.bwm.CheckReprompt(item, "password")
+		if err != nil {
+			return err
+		}
+		req.Username = item.Login.Username
+		req.Password = item.Login.Password
+		return req.Write(c.stdout)
+	case "store":
+		if err := c.load(); err != nil {
+			return err
+		}
+		_, err := c.bwm.StoreGitCredential(req)
+		return err
+	default:
+		// git erases credentials the remote rejected. Vault items are
+		// never deleted on its behalf, a wrong password is fixed in the
+		// vault or replaced by the next store.
+		return nil
+	}
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+func (c *CLI) health(args []string) error {
+	fs := c.flagSet("health")
+	maxAge := fs.Int("max-age-days", 0, "report passwords older than this many `days` (default from config, or 365)")
+	breaches := fs.Bool("breaches", false, "check passwords against the breach API or hash file from the config")
+	of := addOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	out, err := of.output()
+	if err != nil {
+		return err
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+	opts := bw.DefaultHealthOptions()
+	opts.MaxPasswordAge = c.cfg.PasswordMaxAge()
+	opts.TwoFactorDomains = c.cfg.TwoFactorDomains
+	if *maxAge > 0 {
+		opts.MaxPasswordAge = time.Duration(*maxAge) * 24 * time.Hour
+	}
+	if *breaches {
+		source, err := bw.OpenBreachSource(c.cfg.BreachAPIURL, c.cfg.BreachHashFile)
+		if err != nil {
+			return err
+		}
+		defer source.Close()
+		opts.Breaches = source
+	}
+	report, err := c.bwm.HealthReport(opts)
+	if err != nil {
+		return err
+	}
+
+	switch out.format {
+	case formatJSON:
+		return out.writeJSON(c.stdout, report)
+	case formatTemplate:
+		return out.writeTemplate(c.stdout, report)
+	default:
+		c.healthPlain(report, *breaches)
+		return nil
+	}
+}
+
+func (c *CLI) healthPlain(report *bw.HealthReport, breaches bool) {
+	section := func(title string, n int) {
+		fmt.Fprintf(c.stdout, "%s (%d)\n", title, n)
+	}
+	section("Weak passwords", len(report.Weak))
+	for _, f := range report.Weak {
+		fmt.Fprintf(c.stdout, "  %s\t%s\tscore %d/4, cracked in %s\n", f.Item.ID, f.Item.Name, f.Score, f.CrackTime)
+	}
+	if breaches {
+		section("Breached passwords", len(report.Breached))
+		for _, f := range report.Breached {
+			fmt.Fprintf(c.stdout, "  %s\t%s\tseen %d times\n", f.Item.ID, f.Item.Name, f.Count)
+		}
+	}
+	section("Reused passwords", len(report.Reused))
+	for _, f := range report.Reused {
+		names := make([]string, 0, len(f.Items))
+		for _, item := range f.Items {
+			names = append(names, item.Name)
+		}
+		fmt.Fprintf(c.stdout, "  %d items\t%s\n", len(f.Items), strings.Join(names, ", "))
+	}
+	section("Old passwords", len(report.Old))
+	for _, f := range report.Old {
+		fmt.Fprintf(c.stdout, "  %s\t%s\t%d days old\n", f.Item.ID, f.Item.Name, f.AgeDays)
+	}
+	section("Unsecured URIs", len(report.InsecureURIs))
+	for _, f := range report.InsecureURIs {
+		fmt.Fprintf(c.stdout, "  %s\t%s\t%s\n", f.Item.ID, f.Item.Name, f.URI)
+	}
+	section("Missing two-step login", len(report.MissingTwoFactor))
+	for _, f := range report.MissingTwoFactor {
+		fmt.Fprintf(c.stdout, "  %s\t%s\t%s supports TOTP\n", f.Item.ID, f.Item.Name, f.Domain)
+	}
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+func (c *CLI) importItems(args []string) error {
+	fs := c.flagSet("import")
+	formatNames := make([]string, 0, len(bw.ImportFormats()))
+	for _, f := range bw.ImportFormats() {
+		formatNames = append(formatNames, string(f))
+	}
+	format := fs.String("format", "", "import `format`: "+strings.Join(formatNames, ", ")+" (default guessed from the file)")
+	folder := fs.String("folder", "", "put the imported items under this `folder`")
+	dryRun := fs.Bool("dry-run", false, "only list what would be imported")
+	duplicates := fs.Bool("duplicates", false, "also import items that are already in the vault")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	path := fs.Arg(0)
+	var f bw.ImportFormat
+	var err error
+	if *format != "" {
+		f, err = bw.ParseImportFormat(*format)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errUsage, err)
+		}
+	} else {
+		f, err = bw.DetectImportFormat(path)
+		if err != nil {
+			return fmt.Errorf("%w: %w, use -format", errUsage, err)
+		}
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+
+	items, err := bw.ReadImport(f, path, "")
+	if errors.Is(err, bw.ErrImportPassword) {
+		var pw string
+		pw, err = c.promptPassword("File password: ")
+		if err != nil {
+			return err
+		}
+		items, err = bw.ReadImport(f, path, pw)
+	}
+	if err != nil {
+		return err
+	}
+	c.bwm.MarkDuplicates(items)
+
+	selected := make([]bw.ImportedItem, 0, len(items))
+	for _, item := range items {
+		if item.DuplicateOf == "" || *duplicates {
+			selected = append(selected, item)
+		}
+	}
+	if *dryRun {
+		c.importPreview(items, *folder)
+		return nil
+	}
+	result, err := c.bwm.Import(selected, bw.ImportOptions{Folder: *folder})
+	fmt.Fprintf(c.stderr, "imported %d items, created %d folders and %d attachments\n", result.Imported, result.Folders, result.Attachments)
+	if skipped := len(items) - len(selected); skipped > 0 {
+		fmt.Fprintf(c.stderr, "skipped %d items already in the vault, use -duplicates to import them anyway\n", skipped)
+	}
+	return err
+}
+
+func (c *CLI) importPreview(items []bw.ImportedItem, folder string) {
+	for _, item := range items {
+		line := fmt.Sprintf("%s\t%s\t%s", item.Item.Name, item.Item.Type, bw.JoinFolder(folder, item.Folder))
+		if item.DuplicateOf != "" {
+			existing, err := c.bwm.FindItem(item.DuplicateOf)
+			if err == nil {
+				line += fmt.Sprintf("\tduplicate of %q", existing.Name)
+			}
+		}
+		fmt.Fprintln(c.stdout, line)
+	}
+}
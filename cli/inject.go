@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/template"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+// injectFuncs are the template functions reading from the vault. Every
+// reference must name exactly one item; ambiguous names fail the whole
+// render.
+func (c *CLI) injectFuncs() template.FuncMap {
+	resolve := c.bwm.ResolveSecretRef
+	funcs := templateFuncs()
+	funcs["bw"] = func(item string, field string) (string, error) {
+		return resolve(bw.SecretRef{Item: item, Field: field})
+	}
+	funcs["bwField"] = func(item string, field string) (string, error) {
+		return resolve(bw.SecretRef{Item: item, Field: field, Custom: true})
+	}
+	funcs["totp"] = func(item string) (string, error) {
+		return resolve(bw.SecretRef{Item: item, Field: "totp"})
+	}
+	funcs["bwRef"] = func(s string) (string, error) {
+		ref, err := bw.ParseSecretRef(s)
+		if err != nil {
+			return "", err
+		}
+		return resolve(ref)
+	}
+	return funcs
+}
+
+func (c *CLI) inject(args []string) error {
+	fs := c.flagSet("inject")
+	in := fs.String("i", "", "read the template from `file` instead of stdin")
+	out := fs.String("o", "", "write the result to `file` instead of stdout, readable by you only")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	var src []byte
+	var err error
+	name := "stdin"
+	if *in == "" {
+		src, err = io.ReadAll(os.Stdin)
+	} else {
+		name = *in
+		src, err = os.ReadFile(*in)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read template: %w", err)
+	}
+	// the items are listed once and every reference resolves against them
+	if err := c.load(); err != nil {
+		return err
+	}
+	tmpl, err := template.New(name).Funcs(c.injectFuncs()).Option("missingkey=error").Parse(string(src))
+	if err != nil {
+		return fmt.Errorf("%w: invalid template: %w", errUsage, err)
+	}
+	// the result is rendered in memory first so a failure leaves no partial
+	// file
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, nil)
+	if err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+	if *out == "" {
+		_, err = buf.WriteTo(c.stdout)
+		return err
+	}
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	defer f.Close()
+	// a replaced file keeps its old mode otherwise
+	err = f.Chmod(0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	_, err = buf.WriteTo(f)
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return f.Close()
+}
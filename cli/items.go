@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+func matchesFolderOrOrg(id string, name string, query string) bool {
+	return id == query || strings.EqualFold(name, query)
+}
+
+func (c *CLI) list(args []string) error {
+	fs := c.flagSet("list")
+	folder := fs.String("folder", "", "only show items in the folder with this name or ID")
+	org := fs.String("org", "", "only show items owned by the organization with this name or ID")
+	itemType := fs.String("type", "", "only show items of this `type` (login, note, card, identity, sshkey)")
+	matchURL := fs.String("url", "", "only show logins with a URI matching `url`")
+	search := fs.String("search", "", "only show items matching this `query`, e.g. 'github user:alice is:favorite'")
+	of := addOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	out, err := of.output()
+	if err != nil {
+		return err
+	}
+	var wantType bw.ItemType
+	if *itemType != "" {
+		wantType, err = bw.ParseItemType(*itemType)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errUsage, err)
+		}
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+	items, err := c.bwm.GetList()
+	if err != nil {
+		return err
+	}
+
+	if *matchURL != "" {
+		items, err = c.bwm.MatchURI(*matchURL)
+		if err != nil {
+			return err
+		}
+	}
+	if *search != "" {
+		results := c.bwm.NewSearchIndex(items).Search(*search)
+		items = make([]bw.Item, 0, len(results))
+		for _, r := range results {
+			items = append(items, r.Item)
+		}
+	}
+
+	matched := make([]bw.Item, 0, len(items))
+	for _, item := range items {
+		if *folder != "" && !matchesFolderOrOrg(item.FolderID, c.bwm.FolderName(item.FolderID), *folder) {
+			continue
+		}
+		if *org != "" && !matchesFolderOrOrg(item.OrganizationID, c.bwm.OrganizationName(item.OrganizationID), *org) {
+			continue
+		}
+		if wantType != 0 && item.Type != wantType {
+			continue
+		}
+		matched = append(matched, item)
+	}
+
+	switch out.format {
+	case formatJSON:
+		return out.writeJSON(c.stdout, matched)
+	case formatTemplate:
+		for _, item := range matched {
+			if err := out.writeTemplate(c.stdout, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		for _, item := range matched {
+			fmt.Fprintf(c.stdout, "%s\t%s\t%s\n", item.ID, item.Name, item.Login.Username)
+		}
+		return nil
+	}
+}
+
+type getResult struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+func (c *CLI) get(args []string) error {
+	fs := c.flagSet("get")
+	of := addOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	out, err := of.output()
+	if err != nil {
+		return err
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+	item, err := c.bwm.FindItem(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	item, err = c.bwm.CheckReprompt(item, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	value, err := item.FieldValue(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	result := getResult{
+		ID:    item.ID,
+		Name:  item.Name,
+		Field: fs.Arg(0),
+		Value: value,
+	}
+
+	switch out.format {
+	case formatJSON:
+		return out.writeJSON(c.stdout, result)
+	case formatTemplate:
+		return out.writeTemplate(c.stdout, result)
+	default:
+		fmt.Fprintln(c.stdout, value)
+		return nil
+	}
+}
+
+func (c *CLI) show(args []string) error {
+	fs := c.flagSet("show")
+	reveal := fs.Bool("reveal", false, "show passwords and hidden fields in plain output")
+	of := addOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	out, err := of.output()
+	if err != nil {
+		return err
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+	item, err := c.bwm.FindItem(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	// plain output hides the secrets unless they are revealed
+	if *reveal || out.format != formatPlain {
+		item, err = c.bwm.CheckReprompt(item, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	switch out.format {
+	case formatJSON:
+		return out.writeJSON(c.stdout, item)
+	case formatTemplate:
+		return out.writeTemplate(c.stdout, item)
+	default:
+		return c.showPlain(item, *reveal)
+	}
+}
+
+func (c *CLI) showPlain(item bw.Item, reveal bool) error {
+	hide := func(s string) string {
+		if reveal || s == "" {
+			return s
+		}
+		return "•••"
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 1, ' ', 0)
+	row := func(label string, value string) {
+		if value != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", label, value)
+		}
+	}
+	row("Name", item.Name)
+	row("ID", item.ID)
+	row("Type", item.Type.String())
+	row("Folder", c.bwm.FolderName(item.FolderID))
+	row("Organization", c.bwm.OrganizationName(item.OrganizationID))
+	row("Username", item.Login.Username)
+	row("Password", hide(item.Login.Password))
+	if item.Login.TOTP != "" {
+		code, remaining, err := bw.TOTP(item.Login.TOTP, time.Now())
+		if err == nil {
+			row("TOTP", fmt.Sprintf("%s (%ds)", code, int(remaining.Seconds())))
+		}
+	}
+	for _, uri := range item.Login.URIs {
+		row("URI", uri.URI)
+	}
+	for _, f := range item.Fields {
+		if f.Type == bw.FieldHidden {
+			row(f.Name, hide(f.Value))
+		} else {
+			row(f.Name, f.Value)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if item.Notes != "" {
+		fmt.Fprintf(c.stdout, "Notes:\n%s\n", item.Notes)
+	}
+	return nil
+}
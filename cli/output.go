@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+type outputFormat string
+
+const (
+	formatPlain    outputFormat = "plain"
+	formatJSON     outputFormat = "json"
+	formatTemplate outputFormat = "template"
+)
+
+type outputFlags struct {
+	format   string
+	template string
+}
+
+func addOutputFlags(fs *flag.FlagSet) *outputFlags {
+	var o outputFlags
+	fs.StringVar(&o.format, "format", string(formatPlain), "output `format`: plain, json or template")
+	fs.StringVar(&o.template, "template", "", "Go text/template used for output, implies -format template")
+	return &o
+}
+
+type output struct {
+	format outputFormat
+	tmpl   *template.Template
+}
+
+func (o *outputFlags) output() (*output, error) {
+	out := output{format: outputFormat(o.format)}
+	if o.template != "" {
+		out.format = formatTemplate
+	}
+	switch out.format {
+	case formatPlain, formatJSON:
+	case formatTemplate:
+		if o.template == "" {
+			return nil, fmt.Errorf("%w: -format template requires -template", errUsage)
+		}
+		tmpl, err := template.New("output").Funcs(templateFuncs()).Parse(o.template)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid template: %w", errUsage, err)
+		}
+		out.tmpl = tmpl
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", errUsage, o.format)
+	}
+	return &out, nil
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"join": strings.Join,
+	}
+}
+
+func (o *output) writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeTemplate executes the template and terminates the output with a
+// newline so that per-item templates produce one line per item.
+func (o *output) writeTemplate(w io.Writer, v any) error {
+	var b strings.Builder
+	err := o.tmpl.Execute(&b, v)
+	if err != nil {
+		return err
+	}
+	s := b.String()
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	_, err = io.WriteString(w, s)
+	return err
+}
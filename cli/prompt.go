@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/pinentry"
+)
+
+// unlockAttempts is how often the master password is asked for before
+// giving up.
+const unlockAttempts = 3
+
+var errPasswordMismatch = errors.New("passwords do not match")
+
+// promptPassword asks for a secret on the controlling terminal without echoing
+// it. Without a terminal the next line of stdin is read instead, so scripts
+// can pipe the answer in.
+func (c *CLI) promptPassword(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		line, err := c.stdinLine()
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		return line, nil
+	}
+	defer tty.Close()
+	fmt.Fprint(tty, prompt)
+	pw, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return string(pw), nil
+}
+
+// promptNewPassword asks for a new password twice.
+func (c *CLI) promptNewPassword(prompt string) (string, error) {
+	pw, err := c.promptPassword(prompt)
+	if err != nil {
+		return "", err
+	}
+	again, err := c.promptPassword("Repeat " + strings.ToLower(prompt[:1]) + prompt[1:])
+	if err != nil {
+		return "", err
+	}
+	if pw != again {
+		return "", errPasswordMismatch
+	}
+	return pw, nil
+}
+
+func (c *CLI) stdinLine() (string, error) {
+	if c.stdin == nil {
+		c.stdin = bufio.NewReader(os.Stdin)
+	}
+	line, err := c.stdin.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// promptMasterPassword asks for the master password with the configured
+// pinentry, or on the terminal without one. desc says what it is needed for;
+// retry is set after a wrong password.
+func (c *CLI) promptMasterPassword(desc string, retry bool) (string, error) {
+	account := c.bwm.VaultStatus.UserEmail
+	if server := c.bwm.VaultStatus.ServerURL; server != "" {
+		account += " on " + server
+	}
+	if c.cfg.Pinentry == "" {
+		prompt := "Master password for " + account + ": "
+		if retry {
+			prompt = "Wrong password, try again: "
+		}
+		return c.promptPassword(prompt)
+	}
+	p := pinentry.Prompt{
+		Title:       "gobw",
+		Description: desc + "\n\nBitwarden account: " + account,
+		Prompt:      "Master password:",
+	}
+	if retry {
+		p.Error = "Wrong password, try again"
+	}
+	return pinentry.GetPin(c.cfg.Pinentry, p)
+}
+
+// unlock unlocks the vault with the master password from pinentry.
+func (c *CLI) unlock(desc string) error {
+	var err error
+	for attempt := 0; attempt < unlockAttempts; attempt++ {
+		var pw string
+		pw, err = c.promptMasterPassword(desc, attempt > 0)
+		if errors.Is(err, pinentry.ErrCanceled) {
+			return fmt.Errorf("%w: %w", bw.ErrLocked, err)
+		}
+		if err != nil {
+			return err
+		}
+		err = c.bwm.Unlock(pw)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, bw.ErrInvalidPassword) {
+			return err
+		}
+	}
+	return err
+}
+
+// reprompt asks for the master password with pinentry before a secret of an
+// item with master password re-prompt is handed out. Nobody may be at the
+// terminal, so it fails without a pinentry. The password covers the item for
+// the re-prompt grace period, so the agent and the Secret Service do not ask
+// on every read.
+func (c *CLI) reprompt(item bw.Item) error {
+	if c.cfg.Pinentry == "" {
+		return fmt.Errorf("%w: %q", bw.ErrRepromptUnavailable, item.Name)
+	}
+	if at, ok := c.reprompted[item.ID]; ok && time.Since(at) < c.cfg.RepromptGrace() {
+		return nil
+	}
+	desc := fmt.Sprintf("%q is protected by master password re-prompt.", item.Name)
+	var err error
+	for attempt := 0; attempt < unlockAttempts; attempt++ {
+		var pw string
+		pw, err = c.promptMasterPassword(desc, attempt > 0)
+		if errors.Is(err, pinentry.ErrCanceled) {
+			return fmt.Errorf("%w: %w", bw.ErrInvalidPassword, err)
+		}
+		if err != nil {
+			return err
+		}
+		err = c.bwm.VerifyPassword(pw)
+		if err == nil {
+			c.reprompted[item.ID] = time.Now()
+			return nil
+		}
+		if !errors.Is(err, bw.ErrInvalidPassword) {
+			return err
+		}
+	}
+	return err
+}
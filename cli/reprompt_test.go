@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/config"
+)
+
+const (
+	testEmail    = "alice@example.com"
+	testPassword = "correct horse"
+)
+
+// fakeAgent hands out a fixed unlocked vault like `gobw agent`: redacted, and
+// revealed once check passes, which the agent does with its own reprompt.
+type fakeAgent struct {
+	items []bw.Item
+	check bw.RepromptFunc
+}
+
+func (a *fakeAgent) Status() (bw.VaultStatus, string, error) {
+	return bw.VaultStatus{UserEmail: testEmail, UserID: "u1", Status: bw.Unlocked}, "SESSION", nil
+}
+
+func (a *fakeAgent) List() (bw.AgentList, error) {
+	items := make([]bw.Item, len(a.items))
+	for i, item := range a.items {
+		items[i] = item.Redacted()
+	}
+	return bw.AgentList{Items: items}, nil
+}
+
+func (a *fakeAgent) Reveal(ids []string, _ string) ([]bw.Item, error) {
+	var revealed []bw.Item
+	for _, item := range a.items {
+		for _, id := range ids {
+			if item.ID != id {
+				continue
+			}
+			if err := a.check(item); err != nil {
+				return nil, err
+			}
+			revealed = append(revealed, item)
+		}
+	}
+	return revealed, nil
+}
+
+func (a *fakeAgent) Unlock(string) error { return nil }
+func (a *fakeAgent) Lock() error         { return nil }
+func (a *fakeAgent) Sync() error         { return nil }
+func (a *fakeAgent) Reload() error       { return nil }
+
+// writeDataFile writes a bw data file with the master key hash of
+// testPassword, which VerifyPassword checks against.
+func writeDataFile(t *testing.T) {
+	t.Helper()
+	const iterations = 1000
+	key := pbkdf2.Key([]byte(testPassword), []byte(testEmail), iterations, 32, sha256.New)
+	hash := base64.StdEncoding.EncodeToString(pbkdf2.Key(key, []byte(testPassword), 2, 32, sha256.New))
+	data, err := json.Marshal(map[string]any{
+		"global_account_activeAccountId":       "u1",
+		"user_u1_kdfConfig_kdfConfig":          map[string]int{"kdfType": 0, "iterations": iterations},
+		"user_u1_masterPassword_masterKeyHash": hash,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	err = os.WriteFile(filepath.Join(dir, "data.json"), data, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BITWARDENCLI_APPDATA_DIR", dir)
+}
+
+// fakePinentry is a stand-in pinentry answering every GETPIN with $FAKE_PIN
+// and logging each request to $FAKE_PIN_LOG.
+const fakePinentry = `#!/bin/sh
+echo "OK Pleased to meet you"
+while read -r line; do
+	case "$line" in
+	GETPIN)
+		echo GETPIN >>"$FAKE_PIN_LOG"
+		echo "D $FAKE_PIN"
+		echo OK
+		;;
+	BYE)
+		echo OK
+		exit 0
+		;;
+	*)
+		echo OK
+		;;
+	esac
+done
+`
+
+// setupPinentry writes the stand-in pinentry, answering with pin, and
+// returns its path and a function counting the pins asked for.
+func setupPinentry(t *testing.T, pin string) (string, func() int) {
+	t.Helper()
+	dir := t.TempDir()
+	program := filepath.Join(dir, "pinentry")
+	err := os.WriteFile(program, []byte(fakePinentry), 0o700) // #nosec G306
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := filepath.Join(dir, "log")
+	t.Setenv("FAKE_PIN", pin)
+	t.Setenv("FAKE_PIN_LOG", log)
+	return program, func() int {
+		data, _ := os.ReadFile(log) // #nosec G304
+		return strings.Count(string(data), "GETPIN")
+	}
+}
+
+func newTestCLI(t *testing.T, pinentry string) (*CLI, *bytes.Buffer) {
+	t.Helper()
+	writeDataFile(t)
+	agent := &fakeAgent{items: []bw.Item{
+		{ID: "1", Type: bw.Login, Name: "Protected", Reprompt: 1, Login: bw.ItemLogin{Username: "alice", Password: "hunter2"},
+			Fields: []bw.ItemField{{Name: "name", Value: "hidden value", Type: bw.FieldHidden}}},
+		{ID: "2", Type: bw.Login, Name: "Plain", Login: bw.ItemLogin{Username: "bob", Password: "swordfish"}},
+	}}
+	bwm := bw.NewBWManager(bw.WithAgent(agent))
+	if err := bwm.UpdateStatus(); err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	c := New(bwm, &config.Config{Pinentry: pinentry}, &stdout, &bytes.Buffer{})
+	agent.check = c.reprompt
+	return c, &stdout
+}
+
+func TestRepromptWithoutPinentry(t *testing.T) {
+	c, stdout := newTestCLI(t, "")
+	if code := c.Run([]string{"get", "password", "Protected"}); code != ExitError {
+		t.Errorf("exit code: got %d, want %d", code, ExitError)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("the password was handed out: %q", stdout.String())
+	}
+	_, err := c.bwm.ResolveValue("bw://Protected/password")
+	if !errors.Is(err, bw.ErrRepromptUnavailable) {
+		t.Errorf("ResolveValue: got %v, want %v", err, bw.ErrRepromptUnavailable)
+	}
+
+	// unprotected fields and items need no password
+	stdout.Reset()
+	if code := c.Run([]string{"get", "username", "Protected"}); code != ExitOK || stdout.String() != "alice\n" {
+		t.Errorf("get username: got %d %q", code, stdout.String())
+	}
+	stdout.Reset()
+	if code := c.Run([]string{"get", "name", "Protected"}); code != ExitOK || stdout.String() != "Protected\n" {
+		t.Errorf("get name: got %d %q", code, stdout.String())
+	}
+	value, err := c.bwm.ResolveValue("bw://Protected/name")
+	if err != nil || value != "Protected" {
+		t.Errorf("ResolveValue of the name: got %q, %v", value, err)
+	}
+	stdout.Reset()
+	if code := c.Run([]string{"get", "password", "Plain"}); code != ExitOK || stdout.String() != "swordfish\n" {
+		t.Errorf("get password of an unprotected item: got %d %q", code, stdout.String())
+	}
+}
+
+func TestRepromptWrongPassword(t *testing.T) {
+	program, asked := setupPinentry(t, "wrong")
+	c, stdout := newTestCLI(t, program)
+	if code := c.Run([]string{"get", "password", "Protected"}); code != ExitLocked {
+		t.Errorf("exit code: got %d, want %d", code, ExitLocked)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("the password was handed out: %q", stdout.String())
+	}
+	if n := asked(); n != unlockAttempts {
+		t.Errorf("asked %d times, want %d", n, unlockAttempts)
+	}
+}
+
+func TestReprompt(t *testing.T) {
+	program, asked := setupPinentry(t, testPassword)
+	c, stdout := newTestCLI(t, program)
+	if code := c.Run([]string{"get", "password", "Protected"}); code != ExitOK || stdout.String() != "hunter2\n" {
+		t.Errorf("get password: got %d %q", code, stdout.String())
+	}
+	value, err := c.bwm.ResolveValue("bw://Protected/password")
+	if err != nil || value != "hunter2" {
+		t.Errorf("ResolveValue: got %q, %v", value, err)
+	}
+	// the second read falls into the grace period
+	if n := asked(); n != 1 {
+		t.Errorf("asked %d times, want 1", n)
+	}
+}
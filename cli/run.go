@@ -0,0 +1,302 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/sapslaj/gobw/agent"
+	"github.com/sapslaj/gobw/bw"
+)
+
+// secretMask replaces secret values in the output of commands run by gobw.
+const secretMask = "*****"
+
+// minMaskLength is the shortest value masked. Masking shorter values would
+// garble unrelated output more than it hides.
+const minMaskLength = 4
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// vaultEnv are the variables that open the whole vault. The command only gets
+// the secrets it was given, so they are left out unless asked for.
+var vaultEnv = []string{"BW_SESSION", agent.SocketEnv}
+
+// stringsFlag collects the values of a flag given more than once.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+// exitStatusError carries the exit status of a child process, which gobw
+// exits with in turn.
+type exitStatusError struct {
+	code int
+}
+
+func (e *exitStatusError) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.code)
+}
+
+func (c *CLI) run(args []string) error {
+	fs := c.flagSet("run")
+	var envs, envFiles stringsFlag
+	fs.Var(&envs, "env", "set `NAME=VALUE` in the command's environment, where VALUE may be a bw:// reference; repeatable")
+	fs.Var(&envFiles, "env-file", "read NAME=VALUE lines from the dotenv `file`; repeatable")
+	noMask := fs.Bool("no-mask", false, "pass the command's output through unchanged instead of masking secrets")
+	passSession := fs.Bool("pass-session", false, "pass BW_SESSION and "+agent.SocketEnv+" on, giving the command the whole vault")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	// files come first so that -env can override them
+	var vars [][2]string
+	for _, path := range envFiles {
+		fileVars, err := readEnvFile(path)
+		if err != nil {
+			return err
+		}
+		vars = append(vars, fileVars...)
+	}
+	for _, env := range envs {
+		name, value, ok := strings.Cut(env, "=")
+		if !ok || !envNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: -env %q is not NAME=VALUE", errUsage, env)
+		}
+		vars = append(vars, [2]string{name, value})
+	}
+	needsVault := false
+	for _, v := range vars {
+		needsVault = needsVault || bw.IsSecretRef(v[1])
+	}
+	if needsVault {
+		if err := c.load(); err != nil {
+			return err
+		}
+	}
+	env := os.Environ()
+	if !*passSession {
+		env = withoutEnv(env, vaultEnv)
+	}
+	var secrets []string
+	for _, v := range vars {
+		value := v[1]
+		if bw.IsSecretRef(value) {
+			ref, err := bw.ParseSecretRef(value)
+			if err != nil {
+				return fmt.Errorf("%w: %w", errUsage, err)
+			}
+			value, err = c.bwm.ResolveSecretRef(ref)
+			if err != nil {
+				return fmt.Errorf("failed to resolve %s: %w", v[0], err)
+			}
+			if !*noMask && value != "" && len(value) < minMaskLength {
+				fmt.Fprintf(c.stderr, "gobw: %s is shorter than %d characters and is not masked in the output\n", v[0], minMaskLength)
+			}
+			secrets = append(secrets, value)
+		}
+		env = append(env, v[0]+"="+value)
+	}
+
+	cmd := exec.Command(fs.Arg(0), fs.Args()[1:]...) // #nosec G204
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = c.stdout
+	cmd.Stderr = c.stderr
+	var masks []*maskWriter
+	if !*noMask {
+		stdout, stderr := newMaskWriter(c.stdout, secrets), newMaskWriter(c.stderr, secrets)
+		masks = append(masks, stdout, stderr)
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run command: %w", err)
+	}
+	go func() {
+		for sig := range signals {
+			// the terminal sends ctrl+c to the command itself; gobw only
+			// has to outlive it to flush the output
+			if sig != syscall.SIGINT {
+				_ = cmd.Process.Signal(sig)
+			}
+		}
+	}()
+	err := cmd.Wait()
+	for _, m := range masks {
+		if err := m.Flush(); err != nil {
+			return err
+		}
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+		if code < 0 {
+			// killed by a signal, exit like shells report it
+			code = 128
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+				code += int(status.Signal())
+			}
+		}
+		return &exitStatusError{code}
+	}
+	return err
+}
+
+// withoutEnv returns env without the variables named in names.
+func withoutEnv(env []string, names []string) []string {
+	out := make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		drop := false
+		for _, n := range names {
+			drop = drop || name == n
+		}
+		if !drop {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
+
+// readEnvFile reads a dotenv file: NAME=VALUE lines, optionally prefixed
+// with export, with # comments and single or double quoted values.
+func readEnvFile(path string) ([][2]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	defer f.Close()
+	var vars [][2]string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("%s:%d: expected NAME=VALUE", path, n)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value, err = strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid quoted value: %w", path, n, err)
+			}
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		vars = append(vars, [2]string{name, value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	return vars, nil
+}
+
+// maskWriter replaces secrets in a stream with secretMask. Output that might
+// be the start of a secret is held back until the next write or Flush tells.
+type maskWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	secrets [][]byte
+	buf     []byte
+}
+
+func newMaskWriter(w io.Writer, secrets []string) *maskWriter {
+	m := &maskWriter{w: w}
+	for _, s := range secrets {
+		if len(s) >= minMaskLength {
+			m.secrets = append(m.secrets, []byte(s))
+		}
+	}
+	// the longest secret wins where several start at the same place
+	sort.Slice(m.secrets, func(i, j int) bool {
+		return len(m.secrets[i]) > len(m.secrets[j])
+	})
+	return m
+}
+
+func (m *maskWriter) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.buf = append(m.buf, p...)
+	var out bytes.Buffer
+	for {
+		start, length := -1, 0
+		for _, s := range m.secrets {
+			if i := bytes.Index(m.buf, s); i >= 0 && (start < 0 || i < start) {
+				start, length = i, len(s)
+			}
+		}
+		if start < 0 {
+			break
+		}
+		out.Write(m.buf[:start])
+		out.WriteString(secretMask)
+		m.buf = m.buf[start+length:]
+	}
+	keep := m.partialSecret()
+	out.Write(m.buf[:len(m.buf)-keep])
+	m.buf = append([]byte(nil), m.buf[len(m.buf)-keep:]...)
+	if _, err := m.w.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// partialSecret returns the length of the longest end of the buffer that is
+// the beginning of a secret.
+func (m *maskWriter) partialSecret() int {
+	longest := 0
+	for _, s := range m.secrets {
+		for n := len(s) - 1; n > longest; n-- {
+			if n <= len(m.buf) && bytes.HasSuffix(m.buf, s[:n]) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
+
+// Flush writes out what is held back once the stream has ended.
+func (m *maskWriter) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.w.Write(m.buf)
+	m.buf = nil
+	return err
+}
@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/bws"
+)
+
+// secretsUsage is the usage of each secrets action.
+var secretsUsage = map[string]string{
+	"projects": "secrets projects [output flags]",
+	"list":     "secrets list [-project P] [output flags]",
+	"get":      "secrets get [output flags] <id|key>",
+	"create":   "secrets create -project P -key K [-note N]",
+	"edit":     "secrets edit [-key K] [-note N] [-project P] [-value] <id|key>",
+	"delete":   "secrets delete <id|key>",
+}
+
+// secretsManager returns the Secrets Manager backend.
+func (c *CLI) secretsManager() bws.Backend {
+	return bws.NewCLI(c.cfg.BWSPath, c.bwsAccessToken, c.cfg.BWSServerURL)
+}
+
+// bwsAccessToken returns the configured access token, looking it up in the
+// vault if it is a secret reference.
+func (c *CLI) bwsAccessToken() (string, error) {
+	if bw.IsSecretRef(c.cfg.BWSAccessToken) {
+		if err := c.load(); err != nil {
+			return "", err
+		}
+	}
+	return c.bwm.ResolveValue(c.cfg.BWSAccessToken)
+}
+
+// secretValue reads a secret value from stdin, or asks for it if stdin is a
+// terminal. Values read from stdin may span lines; the final newline is
+// dropped.
+func (c *CLI) secretValue(prompt string) (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return c.promptPassword(prompt)
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read value: %w", err)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r"), nil
+}
+
+func (c *CLI) secrets(args []string) error {
+	if len(args) == 0 || secretsUsage[args[0]] == "" {
+		fs := c.flagSet("secrets")
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	fs := c.flagSet("secrets")
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: gobw %s\n", secretsUsage[args[0]])
+		fs.PrintDefaults()
+	}
+	sm := c.secretsManager()
+	switch args[0] {
+	case "projects":
+		return c.secretsProjects(sm, fs, args[1:])
+	case "list":
+		return c.secretsList(sm, fs, args[1:])
+	case "get":
+		return c.secretsGet(sm, fs, args[1:])
+	case "create":
+		return c.secretsCreate(sm, fs, args[1:])
+	case "edit":
+		return c.secretsEdit(sm, fs, args[1:])
+	default:
+		return c.secretsDelete(sm, fs, args[1:])
+	}
+}
+
+func (c *CLI) secretsProjects(sm bws.Backend, fs *flag.FlagSet, args []string) error {
+	of := addOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	out, err := of.output()
+	if err != nil {
+		return err
+	}
+	projects, err := sm.Projects()
+	if err != nil {
+		return err
+	}
+	switch out.format {
+	case formatJSON:
+		return out.writeJSON(c.stdout, projects)
+	case formatTemplate:
+		for _, p := range projects {
+			if err := out.writeTemplate(c.stdout, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		for _, p := range projects {
+			fmt.Fprintf(c.stdout, "%s\t%s\n", p.ID, p.Name)
+		}
+		return nil
+	}
+}
+
+// findProject returns the ID of the project with the given name or ID.
+func findProject(sm bws.Backend, query string) (string, error) {
+	projects, err := sm.Projects()
+	if err != nil {
+		return "", err
+	}
+	for _, p := range projects {
+		if p.ID == query || strings.EqualFold(p.Name, query) {
+			return p.ID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no project %q", errUsage, query)
+}
+
+func (c *CLI) secretsList(sm bws.Backend, fs *flag.FlagSet, args []string) error {
+	project := fs.String("project", "", "only list secrets of the project with this name or ID")
+	of := addOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	out, err := of.output()
+	if err != nil {
+		return err
+	}
+	var projectID string
+	if *project != "" {
+		projectID, err = findProject(sm, *project)
+		if err != nil {
+			return err
+		}
+	}
+	secrets, err := sm.Secrets(projectID)
+	if err != nil {
+		return err
+	}
+	switch out.format {
+	case formatJSON:
+		return out.writeJSON(c.stdout, secrets)
+	case formatTemplate:
+		for _, s := range secrets {
+			if err := out.writeTemplate(c.stdout, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		projects, err := sm.Projects()
+		if err != nil {
+			return err
+		}
+		for _, s := range secrets {
+			fmt.Fprintf(c.stdout, "%s\t%s\t%s\n", s.ID, s.Key, bws.ProjectName(projects, s.ProjectID))
+		}
+		return nil
+	}
+}
+
+func (c *CLI) secretsGet(sm bws.Backend, fs *flag.FlagSet, args []string) error {
+	of := addOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	out, err := of.output()
+	if err != nil {
+		return err
+	}
+	secret, err := bws.FindSecret(sm, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	switch out.format {
+	case formatJSON:
+		return out.writeJSON(c.stdout, secret)
+	case formatTemplate:
+		return out.writeTemplate(c.stdout, secret)
+	default:
+		fmt.Fprintln(c.stdout, secret.Value)
+		return nil
+	}
+}
+
+func (c *CLI) secretsCreate(sm bws.Backend, fs *flag.FlagSet, args []string) error {
+	project := fs.String("project", "", "add the secret to the project with this name or ID")
+	key := fs.String("key", "", "`key` of the secret")
+	note := fs.String("note", "", "`note` about the secret")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *project == "" || *key == "" {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	projectID, err := findProject(sm, *project)
+	if err != nil {
+		return err
+	}
+	value, err := c.secretValue("Value of " + *key + ": ")
+	if err != nil {
+		return err
+	}
+	secret, err := sm.CreateSecret(bws.SecretInput{Key: *key, Value: value, Note: *note, ProjectID: projectID})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout, secret.ID)
+	return nil
+}
+
+func (c *CLI) secretsEdit(sm bws.Backend, fs *flag.FlagSet, args []string) error {
+	key := fs.String("key", "", "rename the secret to `key`")
+	note := fs.String("note", "", "replace the `note`")
+	project := fs.String("project", "", "move the secret to the project with this name or ID")
+	value := fs.Bool("value", false, "replace the value with one read from stdin or asked for")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	secret, err := bws.FindSecret(sm, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	in := bws.SecretInput{Key: *key, Note: *note}
+	if *project != "" {
+		in.ProjectID, err = findProject(sm, *project)
+		if err != nil {
+			return err
+		}
+	}
+	if *value {
+		in.Value, err = c.secretValue("New value of " + secret.Key + ": ")
+		if err != nil {
+			return err
+		}
+	}
+	if in == (bws.SecretInput{}) {
+		return fmt.Errorf("%w: nothing to change", errUsage)
+	}
+	_, err = sm.EditSecret(secret.ID, in)
+	return err
+}
+
+func (c *CLI) secretsDelete(sm bws.Backend, fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	secret, err := bws.FindSecret(sm, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return sm.DeleteSecret(secret.ID)
+}
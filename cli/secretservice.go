@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/secretservice"
+)
+
+func (c *CLI) secretService(args []string) error {
+	fs := c.flagSet("secret-service")
+	poll := fs.Duration("poll", 10*time.Second, "check whether the vault is still unlocked every `interval`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *poll <= 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	// a locked vault is fine: clients ask to unlock it when they need it
+	switch c.bwm.VaultStatus.Status {
+	case bw.Unauthenticated:
+		return bw.ErrNotLoggedIn
+	case bw.Unlocked:
+		if err := c.load(); err != nil {
+			return err
+		}
+	}
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the session bus: %w", err)
+	}
+	defer conn.Close()
+	s := secretservice.New(c.bwm, c.secretServicePassword)
+	if err := s.Export(conn); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "serving %d items as %s\n", s.Len(), secretservice.BusName)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	ticker := time.NewTicker(*poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-conn.Context().Done():
+			return errors.New("lost the connection to the session bus")
+		case sig := <-signals:
+			if sig != syscall.SIGHUP {
+				return nil
+			}
+			// reload the items, e.g. after a sync
+			err = s.Refresh(true)
+		case <-ticker.C:
+			err = s.Refresh(false)
+		}
+		if err != nil {
+			fmt.Fprintln(c.stderr, "gobw:", err)
+		}
+	}
+}
+
+// secretServicePassword asks for the master password when a client wants
+// the vault unlocked.
+func (c *CLI) secretServicePassword(attempt int) (string, error) {
+	return c.promptMasterPassword("An application wants the vault unlocked through the Secret Service.", attempt > 0)
+}
@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+// sendUsage is the usage of each send action.
+var sendUsage = map[string]string{
+	"list":    "send list [output flags]",
+	"create":  "send create [-name N] [-text T | -file F] [-hidden] [-notes N] [-delete-after DURATION] [-expire-after DURATION] [-max-access N] [-password] [-hide-email] [output flags]",
+	"receive": "send receive [-password] [-output FILE] <url>",
+	"delete":  "send delete <id>",
+}
+
+func (c *CLI) send(args []string) error {
+	if len(args) == 0 || sendUsage[args[0]] == "" {
+		fs := c.flagSet("send")
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	fs := c.flagSet("send")
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: gobw %s\n", sendUsage[args[0]])
+		fs.PrintDefaults()
+	}
+	switch args[0] {
+	case "list":
+		return c.sendList(fs, args[1:])
+	case "create":
+		return c.sendCreate(fs, args[1:])
+	case "receive":
+		return c.sendReceive(fs, args[1:])
+	default:
+		return c.sendDelete(fs, args[1:])
+	}
+}
+
+func (c *CLI) sendList(fs *flag.FlagSet, args []string) error {
+	of := addOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	out, err := of.output()
+	if err != nil {
+		return err
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+	sends, err := c.bwm.ListSends()
+	if err != nil {
+		return err
+	}
+	switch out.format {
+	case formatJSON:
+		return out.writeJSON(c.stdout, sends)
+	case formatTemplate:
+		for _, send := range sends {
+			if err := out.writeTemplate(c.stdout, send); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		for _, send := range sends {
+			fmt.Fprintf(c.stdout, "%s\t%s\t%s\t%s\t%s\n", send.ID, send.Name, strings.ToLower(send.Type.String()), sendAccess(send), send.AccessURL)
+		}
+		return nil
+	}
+}
+
+// sendAccess formats how often a Send was opened, out of how often it may be.
+func sendAccess(send bw.Send) string {
+	if send.MaxAccessCount == nil {
+		return fmt.Sprint(send.AccessCount)
+	}
+	return fmt.Sprintf("%d/%d", send.AccessCount, *send.MaxAccessCount)
+}
+
+func (c *CLI) sendCreate(fs *flag.FlagSet, args []string) error {
+	name := fs.String("name", "", "`name` of the Send (default the file name)")
+	text := fs.String("text", "", "`text` to share; read from stdin if neither -text nor -file is given")
+	file := fs.String("file", "", "share this `file`")
+	hidden := fs.Bool("hidden", false, "hide the text until the recipient reveals it")
+	notes := fs.String("notes", "", "private `notes` about the Send")
+	deleteAfter := fs.Duration("delete-after", 7*24*time.Hour, "delete the Send after `duration`, at most 31 days")
+	expireAfter := fs.Duration("expire-after", 0, "stop giving access after `duration`, before it is deleted")
+	maxAccess := fs.Int("max-access", 0, "allow opening the Send at most `n` times")
+	password := fs.Bool("password", false, "ask for a password recipients need to open the Send")
+	hideEmail := fs.Bool("hide-email", false, "hide your email address from recipients")
+	of := addOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || (*text != "" && *file != "") || *deleteAfter <= 0 || *expireAfter < 0 || *maxAccess < 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if *deleteAfter > bw.MaxSendDeletion {
+		return fmt.Errorf("%w: -delete-after is over %d days", errUsage, bw.MaxSendDeletion/(24*time.Hour))
+	}
+	if *expireAfter > *deleteAfter {
+		return fmt.Errorf("%w: -expire-after is after the Send is deleted", errUsage)
+	}
+	out, err := of.output()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	opts := bw.SendOptions{
+		Name:           *name,
+		Notes:          *notes,
+		Text:           *text,
+		Hidden:         *hidden,
+		File:           *file,
+		DeletionDate:   now.Add(*deleteAfter),
+		MaxAccessCount: *maxAccess,
+		HideEmail:      *hideEmail,
+	}
+	if *expireAfter > 0 {
+		opts.ExpirationDate = now.Add(*expireAfter)
+	}
+	if *text == "" && *file == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read text: %w", err)
+		}
+		opts.Text = string(data)
+	}
+	if opts.Name == "" && opts.File == "" {
+		return fmt.Errorf("%w: -name is required for text Sends", errUsage)
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+	if *password {
+		opts.Password, err = c.promptNewPassword("Send password: ")
+		if err != nil {
+			return err
+		}
+	}
+	send, err := c.bwm.CreateSend(opts)
+	if err != nil {
+		return err
+	}
+	switch out.format {
+	case formatJSON:
+		return out.writeJSON(c.stdout, send)
+	case formatTemplate:
+		return out.writeTemplate(c.stdout, send)
+	default:
+		fmt.Fprintln(c.stdout, send.AccessURL)
+		return nil
+	}
+}
+
+func (c *CLI) sendReceive(fs *flag.FlagSet, args []string) error {
+	password := fs.Bool("password", false, "ask for the password of the Send")
+	output := fs.String("output", "", "save a file Send to `file` (default its name in the current directory)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	url := fs.Arg(0)
+	var pw string
+	if *password {
+		var err error
+		pw, err = c.promptPassword("Send password: ")
+		if err != nil {
+			return err
+		}
+	}
+	send, err := bw.ReceiveSend(url, pw)
+	if err != nil {
+		return err
+	}
+	if send.Type == bw.SendText {
+		if send.Text == nil {
+			return nil
+		}
+		fmt.Fprint(c.stdout, send.Text.Text)
+		if !strings.HasSuffix(send.Text.Text, "\n") {
+			fmt.Fprintln(c.stdout)
+		}
+		return nil
+	}
+	path := *output
+	if path == "" {
+		if send.File == nil || send.File.FileName == "" {
+			return fmt.Errorf("%w: the Send has no file name, use -output", errUsage)
+		}
+		// the name comes from the sender, so it must not point elsewhere
+		path = filepath.Base(send.File.FileName)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	if err := bw.DownloadSend(url, pw, path); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stderr, "saved", path)
+	return nil
+}
+
+func (c *CLI) sendDelete(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+	return c.bwm.DeleteSend(fs.Arg(0))
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/sshagent"
+	"github.com/sapslaj/gobw/ui"
+)
+
+// sshConfirmTimeout is how long a signature request waits for an answer
+// before it is denied.
+const sshConfirmTimeout = time.Minute
+
+func (c *CLI) sshAgent(args []string) error {
+	fs := c.flagSet("ssh-agent")
+	socket := fs.String("socket", sshagent.DefaultSocketPath(), "listen on the unix socket at `path`")
+	confirm := fs.Bool("confirm", false, "ask on the terminal before every signature")
+	poll := fs.Duration("poll", 10*time.Second, "check whether the vault is still unlocked every `interval`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *poll <= 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+
+	var confirmFunc sshagent.ConfirmFunc
+	if *confirm {
+		confirmFunc = c.confirmSignature
+	}
+	a := sshagent.New(confirmFunc)
+	if err := c.loadSSHKeys(a); err != nil {
+		return err
+	}
+	l, err := sshagent.Listen(*socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(*socket)
+	defer l.Close()
+	fmt.Fprintf(c.stdout, "SSH_AUTH_SOCK=%s; export SSH_AUTH_SOCK;\n", shellQuote(*socket))
+
+	served := make(chan error, 1)
+	go func() {
+		served <- a.Serve(l)
+	}()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	ticker := time.NewTicker(*poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-served:
+			return err
+		case sig := <-signals:
+			if sig != syscall.SIGHUP {
+				return nil
+			}
+			// reload the keys, e.g. after adding one to the vault
+			err = c.refreshSSHKeys(a, true)
+		case <-ticker.C:
+			err = c.refreshSSHKeys(a, false)
+		}
+		if err != nil {
+			fmt.Fprintln(c.stderr, "gobw:", err)
+		}
+	}
+}
+
+// refreshSSHKeys drops the keys once the vault is no longer unlocked. If
+// reload is set, it unlocks the vault again like any other command, and
+// reloads the keys.
+func (c *CLI) refreshSSHKeys(a *sshagent.Agent, reload bool) error {
+	if err := c.bwm.UpdateStatus(); err != nil {
+		return err
+	}
+	if c.bwm.VaultStatus.Status != bw.Unlocked && a.Len() > 0 {
+		a.Clear()
+		fmt.Fprintln(c.stderr, "vault is locked, dropped all keys")
+	}
+	if !reload {
+		return nil
+	}
+	if err := c.load(); err != nil {
+		return fmt.Errorf("failed to reload the keys: %w", err)
+	}
+	return c.loadSSHKeys(a)
+}
+
+// loadSSHKeys hands the keys in the vault to the agent. Keys that cannot be
+// read are reported and skipped, as are keys of items with master password
+// re-prompt unless the password is entered now.
+func (c *CLI) loadSSHKeys(a *sshagent.Agent) error {
+	found, err := c.bwm.SSHKeys()
+	if err != nil {
+		return err
+	}
+	keys := make([]sshagent.Key, 0, len(found))
+	for _, k := range found {
+		if k.Err != nil {
+			fmt.Fprintf(c.stderr, "skipped key in %s: %s\n", k.Comment(), k.Err)
+			continue
+		}
+		if _, err := c.bwm.CheckReprompt(k.Item, ""); err != nil {
+			fmt.Fprintf(c.stderr, "skipped key in %s: %s\n", k.Comment(), err)
+			continue
+		}
+		keys = append(keys, sshagent.Key{Key: k.Key, Comment: k.Comment()})
+	}
+	if err := a.SetKeys(keys); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "serving %d keys\n", len(keys))
+	return nil
+}
+
+// confirmMu makes concurrent signature requests wait for the terminal.
+var confirmMu sync.Mutex
+
+// confirmSignature asks on the controlling terminal whether key may sign.
+// Requests are denied without a terminal or an answer in time.
+func (c *CLI) confirmSignature(key *agent.Key) bool {
+	confirmMu.Lock()
+	defer confirmMu.Unlock()
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "denied signature with %s: no terminal to confirm on\n", key.Comment)
+		return false
+	}
+	defer tty.Close()
+	question := fmt.Sprintf("Allow a signature with the SSH key %s?\n\n%s %s", key.Comment, key.Format, ssh.FingerprintSHA256(key))
+	ctx, cancel := context.WithTimeout(context.Background(), sshConfirmTimeout)
+	defer cancel()
+	p := tea.NewProgram(ui.NewConfirm("SSH agent", question),
+		tea.WithInput(tty), tea.WithOutput(tty), tea.WithContext(ctx), tea.WithoutSignalHandler())
+	m, err := p.Run()
+	if err != nil {
+		if errors.Is(err, tea.ErrProgramKilled) {
+			fmt.Fprintf(c.stderr, "denied signature with %s: no answer\n", key.Comment)
+		}
+		return false
+	}
+	return m.(ui.Confirm).Allowed()
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
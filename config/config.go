@@ -0,0 +1,142 @@
+// Package config loads the optional gobw configuration file.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Config is the content of $XDG_CONFIG_HOME/gobw/config.json. Every setting
+// is optional.
+type Config struct {
+	// EquivalentDomains are extra sets of domains treated as the same site
+	// when matching login URIs, like the equivalent domains setting of the
+	// Bitwarden web vault.
+	EquivalentDomains [][]string `json:"equivalentDomains"`
+	// PasswordMaxAgeDays is how old a password may get before the vault
+	// health report flags it. Defaults to a year.
+	PasswordMaxAgeDays int `json:"passwordMaxAgeDays"`
+	// TwoFactorDomains are extra domains the vault health report considers
+	// to support TOTP two-factor authentication.
+	TwoFactorDomains []string `json:"twoFactorDomains"`
+	// BreachAPIURL is the Pwned Passwords compatible range API used to check
+	// for breached passwords, e.g. a local mirror. Defaults to
+	// https://api.pwnedpasswords.com.
+	BreachAPIURL string `json:"breachApiUrl"`
+	// BreachHashFile is a downloaded Pwned Passwords SHA-1 file ordered by
+	// hash. When set it is used instead of the range API.
+	BreachHashFile string `json:"breachHashFile"`
+	// DockerCredentialFolder is the folder docker-credential-gobw stores
+	// registry logins in. Defaults to "Docker".
+	DockerCredentialFolder string `json:"dockerCredentialFolder"`
+	// Pinentry is the pinentry program, e.g. pinentry-gtk-2, that asks for
+	// the master password outside the TUI. Without one the subcommands ask
+	// on the terminal, and fail on a locked vault.
+	Pinentry string `json:"pinentry"`
+	// PinStore enables unlocking with a short PIN after a full unlock and
+	// is where the session key encrypted with the PIN is kept: "memory"
+	// for as long as gobw runs, or "keyring" for the Linux kernel keyring.
+	PinStore string `json:"pinStore"`
+	// PinAttempts is how many wrong PINs are allowed before the master
+	// password is needed again. Defaults to 3.
+	PinAttempts int `json:"pinAttempts"`
+	// PinWindowMinutes is how long after a full unlock the PIN may be used.
+	// Defaults to 8 hours.
+	PinWindowMinutes int `json:"pinWindowMinutes"`
+	// RepromptGraceSeconds is how long hidden fields of items with master
+	// password re-prompt stay available after the password was entered, in
+	// the TUI and to the subcommands.
+	// Defaults to 30 seconds; a negative value asks every time.
+	RepromptGraceSeconds int `json:"repromptGraceSeconds"`
+	// LaunchCommands open login URIs by scheme, e.g. "https" or "ssh", with
+	// "*" for http(s) without a command of its own. "%s" in the arguments is
+	// replaced by the URI, which is appended otherwise. Only http(s) and the
+	// schemes listed here are opened: http(s) URIs with $BROWSER or xdg-open
+	// (open on macOS) when there is no command.
+	LaunchCommands map[string][]string `json:"launchCommands"`
+	// BWSAccessToken is the Secrets Manager machine account access token,
+	// or better a bw:// reference to where it is kept in the vault.
+	// BWS_ACCESS_TOKEN takes precedence.
+	BWSAccessToken string `json:"bwsAccessToken"`
+	// BWSPath is the bws binary. Defaults to bws in $PATH.
+	BWSPath string `json:"bwsPath"`
+	// BWSServerURL is the server of a self-hosted Secrets Manager.
+	BWSServerURL string `json:"bwsServerUrl"`
+}
+
+// RepromptGrace returns RepromptGraceSeconds as a duration, with the default
+// applied.
+func (c *Config) RepromptGrace() time.Duration {
+	switch {
+	case c.RepromptGraceSeconds < 0:
+		return 0
+	case c.RepromptGraceSeconds == 0:
+		return 30 * time.Second
+	}
+	return time.Duration(c.RepromptGraceSeconds) * time.Second
+}
+
+// PinMaxAttempts returns PinAttempts with the default applied.
+func (c *Config) PinMaxAttempts() int {
+	if c.PinAttempts <= 0 {
+		return 3
+	}
+	return c.PinAttempts
+}
+
+// PinWindow returns PinWindowMinutes as a duration, with the default
+// applied.
+func (c *Config) PinWindow() time.Duration {
+	if c.PinWindowMinutes <= 0 {
+		return 8 * time.Hour
+	}
+	return time.Duration(c.PinWindowMinutes) * time.Minute
+}
+
+// PasswordMaxAge returns PasswordMaxAgeDays as a duration, with the default
+// applied.
+func (c *Config) PasswordMaxAge() time.Duration {
+	days := c.PasswordMaxAgeDays
+	if days <= 0 {
+		days = 365
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Path returns the location of the configuration file. GOBW_CONFIG overrides
+// the default location.
+func Path() (string, error) {
+	if p := os.Getenv("GOBW_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+	return filepath.Join(dir, "gobw", "config.json"), nil
+}
+
+// Load reads the configuration file. A missing file is not an error.
+func Load() (*Config, error) {
+	var cfg Config
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State is what gobw remembers between sessions, as opposed to Config which
+// is written by the user. It lives in $XDG_STATE_HOME/gobw/state.json.
+type State struct {
+	// Sort is the sort mode of the item list.
+	Sort           string `json:"sort"`
+	SortDescending bool   `json:"sortDescending"`
+	// LastUsed maps item IDs to when they were last opened.
+	LastUsed map[string]time.Time `json:"lastUsed"`
+
+	path string
+}
+
+// StatePath returns the location of the state file.
+func StatePath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find state directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "gobw", "state.json"), nil
+}
+
+// LoadState reads the state file. A missing file yields an empty state.
+func LoadState() (*State, error) {
+	path, err := StatePath()
+	if err != nil {
+		return nil, err
+	}
+	s := State{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	err = json.Unmarshal(data, &s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Save writes the state file. A state that was not loaded from disk is not
+// saved.
+func (s *State) Save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	err = os.MkdirAll(filepath.Dir(s.path), 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	err = os.WriteFile(s.path, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	return nil
+}
+
+// MarkUsed records that an item was just used.
+func (s *State) MarkUsed(id string) {
+	if s.LastUsed == nil {
+		s.LastUsed = make(map[string]time.Time)
+	}
+	s.LastUsed[id] = time.Now()
+}
@@ -7,7 +7,13 @@ require (
 	github.com/charmbracelet/bubbles v0.15.0
 	github.com/charmbracelet/bubbletea v0.23.2
 	github.com/charmbracelet/lipgloss v0.6.0
-	golang.org/x/term v0.6.0
+	github.com/godbus/dbus/v5 v5.2.2
+	github.com/nbutton23/zxcvbn-go v0.0.0-20210217022336-fa2cb2858354
+	github.com/sahilm/fuzzy v0.1.0
+	golang.org/x/crypto v0.14.0
+	golang.org/x/net v0.11.0
+	golang.org/x/sys v0.27.0
+	golang.org/x/term v0.14.0
 )
 
 require (
@@ -22,8 +28,6 @@ require (
 	github.com/muesli/reflow v0.3.0 // indirect
 	github.com/muesli/termenv v0.14.0 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	github.com/sahilm/fuzzy v0.1.0 // indirect
 	golang.org/x/sync v0.1.0 // indirect
-	golang.org/x/sys v0.6.0 // indirect
-	golang.org/x/text v0.8.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
 )
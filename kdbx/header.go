@@ -0,0 +1,434 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+
+	"github.com/sapslaj/gobw/kdbx/internal/argon2d"
+)
+
+const (
+	signature1 = 0x9AA2D903
+	signature2 = 0xB54BFB67
+	// version4 is KDBX 4.0, the major version in the high 16 bits.
+	version4 = 0x00040000
+
+	compressionGzip = 1
+	// innerStreamChaCha20 is the cipher protecting field values in the XML.
+	innerStreamChaCha20 = 3
+)
+
+// Limits on what a crafted database can make Read allocate or compute, mostly
+// before anything in it is authenticated.
+const (
+	// maxHeaderSize caps the whole outer header, maxHeaderFieldSize each field
+	// of it. Real headers are a few hundred bytes.
+	maxHeaderSize      = 4 << 20
+	maxHeaderFieldSize = 1 << 20
+	// maxArgon2Memory is the most memory, in bytes, the Argon2 KDF may ask
+	// for. KeePassXC defaults to 64 MiB.
+	maxArgon2Memory = 2 << 30
+	// maxArgon2Iterations and maxAESRounds cap the work of the KDFs, far
+	// above what KeePassXC's one second benchmark picks.
+	maxArgon2Iterations = 1000
+	maxAESRounds        = 100000000
+	// maxPayloadSize caps the decompressed XML, which is authenticated but
+	// may still be a gzip bomb.
+	maxPayloadSize = 512 << 20
+)
+
+// Outer header field IDs.
+const (
+	headerEnd           = 0
+	headerCipherID      = 2
+	headerCompression   = 3
+	headerMasterSeed    = 4
+	headerEncryptionIV  = 7
+	headerKDFParameters = 11
+)
+
+// Inner header field IDs, at the start of the decrypted payload.
+const (
+	innerHeaderEnd       = 0
+	innerHeaderStreamID  = 1
+	innerHeaderStreamKey = 2
+	innerHeaderBinary    = 3
+)
+
+var (
+	cipherAES256   = UUID{0x31, 0xc1, 0xf2, 0xe6, 0xbf, 0x71, 0x43, 0x50, 0xbe, 0x58, 0x05, 0x21, 0x6a, 0xfc, 0x5a, 0xff}
+	cipherChaCha20 = UUID{0xd6, 0x03, 0x8a, 0x2b, 0x8b, 0x6f, 0x4c, 0xb5, 0xa5, 0x24, 0x33, 0x9a, 0x31, 0xdb, 0xb5, 0x9a}
+	kdfArgon2d     = UUID{0xef, 0x63, 0x6d, 0xdf, 0x8c, 0x29, 0x44, 0x4b, 0x91, 0xf7, 0xa9, 0xa4, 0x03, 0xe3, 0x0a, 0x0c}
+	kdfArgon2id    = UUID{0x9e, 0x29, 0x8b, 0x19, 0x56, 0xdb, 0x47, 0x73, 0xb2, 0x3d, 0xfc, 0x3e, 0xc6, 0xf0, 0xa1, 0xe6}
+	kdfAES         = UUID{0xc9, 0xd9, 0xf3, 0x9a, 0x62, 0x8a, 0x44, 0x60, 0xbf, 0x74, 0x0d, 0x08, 0xc1, 0x8f, 0x4f, 0xea}
+)
+
+// header is the unencrypted outer header of a database.
+type header struct {
+	cipher      Cipher
+	compression uint32
+	masterSeed  []byte
+	iv          []byte
+	kdf         variantDictionary
+}
+
+func writeField(w *bytes.Buffer, id byte, data []byte) {
+	w.WriteByte(id)
+	_ = binary.Write(w, binary.LittleEndian, uint32(len(data)))
+	w.Write(data)
+}
+
+func (h *header) marshal() []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, []uint32{signature1, signature2, version4})
+	cipherID := cipherAES256
+	if h.cipher == ChaCha20 {
+		cipherID = cipherChaCha20
+	}
+	compression := make([]byte, 4)
+	binary.LittleEndian.PutUint32(compression, h.compression)
+	writeField(&buf, headerCipherID, cipherID[:])
+	writeField(&buf, headerCompression, compression)
+	writeField(&buf, headerMasterSeed, h.masterSeed)
+	writeField(&buf, headerEncryptionIV, h.iv)
+	writeField(&buf, headerKDFParameters, h.kdf.marshal())
+	writeField(&buf, headerEnd, []byte("\r\n\r\n"))
+	return buf.Bytes()
+}
+
+// readHeader parses the outer header and returns it with its raw bytes, which
+// the header hash and HMAC cover.
+func readHeader(r io.Reader) (*header, []byte, error) {
+	var raw bytes.Buffer
+	tr := io.TeeReader(r, &raw)
+	var prelude [3]uint32
+	err := binary.Read(tr, binary.LittleEndian, &prelude)
+	if err != nil {
+		return nil, nil, ErrInvalidSignature
+	}
+	if prelude[0] != signature1 || prelude[1] != signature2 {
+		return nil, nil, ErrInvalidSignature
+	}
+	if prelude[2]>>16 != 4 {
+		return nil, nil, fmt.Errorf("%w (found %d.%d)", ErrUnsupportedVersion, prelude[2]>>16, prelude[2]&0xffff)
+	}
+
+	h := &header{}
+	for {
+		var id byte
+		var size uint32
+		err = binary.Read(tr, binary.LittleEndian, &id)
+		if err == nil {
+			err = binary.Read(tr, binary.LittleEndian, &size)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: truncated header", ErrCorrupt)
+		}
+		if size > maxHeaderFieldSize || raw.Len()+int(size) > maxHeaderSize {
+			return nil, nil, fmt.Errorf("%w: header field %d is too large", ErrCorrupt, id)
+		}
+		data := make([]byte, size)
+		_, err = io.ReadFull(tr, data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: truncated header", ErrCorrupt)
+		}
+		switch id {
+		case headerEnd:
+			return h, raw.Bytes(), h.validate()
+		case headerCipherID:
+			if size != 16 {
+				return nil, nil, ErrUnsupportedCipher
+			}
+			switch UUID(data) {
+			case cipherAES256:
+				h.cipher = AES256
+			case cipherChaCha20:
+				h.cipher = ChaCha20
+			default:
+				return nil, nil, ErrUnsupportedCipher
+			}
+		case headerCompression:
+			if size != 4 {
+				return nil, nil, fmt.Errorf("%w: invalid compression flags", ErrCorrupt)
+			}
+			h.compression = binary.LittleEndian.Uint32(data)
+		case headerMasterSeed:
+			h.masterSeed = data
+		case headerEncryptionIV:
+			h.iv = data
+		case headerKDFParameters:
+			h.kdf, err = readVariantDictionary(data)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+}
+
+func (h *header) validate() error {
+	if len(h.masterSeed) != 32 {
+		return fmt.Errorf("%w: invalid master seed", ErrCorrupt)
+	}
+	want := 16
+	if h.cipher == ChaCha20 {
+		want = 12
+	}
+	if len(h.iv) != want {
+		return fmt.Errorf("%w: invalid encryption IV", ErrCorrupt)
+	}
+	if h.kdf == nil {
+		return fmt.Errorf("%w: missing key derivation parameters", ErrCorrupt)
+	}
+	return nil
+}
+
+// keys holds the keys derived from the credentials and the header.
+type keys struct {
+	cipher []byte
+	hmac   []byte
+}
+
+func deriveKeys(h *header, password string) (*keys, error) {
+	pw := sha256.Sum256([]byte(password))
+	composite := sha256.Sum256(pw[:])
+	transformed, err := h.kdf.transform(composite[:])
+	if err != nil {
+		return nil, err
+	}
+	cipherKey := sha256.Sum256(append(append([]byte{}, h.masterSeed...), transformed...))
+	hmacKey := sha512.Sum512(append(append(append([]byte{}, h.masterSeed...), transformed...), 1))
+	return &keys{cipher: cipherKey[:], hmac: hmacKey[:]}, nil
+}
+
+// blockKey is the HMAC key of the block with the given index. The header is
+// authenticated as block ^uint64(0).
+func (k *keys) blockKey(index uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], index)
+	sum := sha512.Sum512(append(buf[:], k.hmac...))
+	return sum[:]
+}
+
+// Variant dictionary value types.
+const (
+	vdEnd       = 0x00
+	vdUInt32    = 0x04
+	vdUInt64    = 0x05
+	vdBool      = 0x08
+	vdInt32     = 0x0C
+	vdInt64     = 0x0D
+	vdString    = 0x18
+	vdByteArray = 0x42
+)
+
+type variantValue struct {
+	kind byte
+	data []byte
+}
+
+// variantDictionary is the typed key-value map of the KDF parameters. Keys
+// are kept in insertion order so a read dictionary round-trips.
+type variantDictionary []struct {
+	key   string
+	value variantValue
+}
+
+func (d variantDictionary) get(key string) (variantValue, bool) {
+	for _, e := range d {
+		if e.key == key {
+			return e.value, true
+		}
+	}
+	return variantValue{}, false
+}
+
+func (d *variantDictionary) set(key string, kind byte, data []byte) {
+	*d = append(*d, struct {
+		key   string
+		value variantValue
+	}{key, variantValue{kind, data}})
+}
+
+func (d *variantDictionary) setUint32(key string, v uint32) {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, v)
+	d.set(key, vdUInt32, data)
+}
+
+func (d *variantDictionary) setUint64(key string, v uint64) {
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint64(data, v)
+	d.set(key, vdUInt64, data)
+}
+
+func (d variantDictionary) bytes(key string) ([]byte, error) {
+	v, ok := d.get(key)
+	if !ok || v.kind != vdByteArray {
+		return nil, fmt.Errorf("%w: missing KDF parameter %s", ErrCorrupt, key)
+	}
+	return v.data, nil
+}
+
+func (d variantDictionary) uint(key string) (uint64, error) {
+	v, ok := d.get(key)
+	switch {
+	case ok && v.kind == vdUInt32 && len(v.data) == 4:
+		return uint64(binary.LittleEndian.Uint32(v.data)), nil
+	case ok && v.kind == vdUInt64 && len(v.data) == 8:
+		return binary.LittleEndian.Uint64(v.data), nil
+	default:
+		return 0, fmt.Errorf("%w: missing KDF parameter %s", ErrCorrupt, key)
+	}
+}
+
+func (d variantDictionary) marshal() []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(0x0100))
+	for _, e := range d {
+		buf.WriteByte(e.value.kind)
+		_ = binary.Write(&buf, binary.LittleEndian, int32(len(e.key)))
+		buf.WriteString(e.key)
+		_ = binary.Write(&buf, binary.LittleEndian, int32(len(e.value.data)))
+		buf.Write(e.value.data)
+	}
+	buf.WriteByte(vdEnd)
+	return buf.Bytes()
+}
+
+func readVariantDictionary(data []byte) (variantDictionary, error) {
+	r := bytes.NewReader(data)
+	var version uint16
+	err := binary.Read(r, binary.LittleEndian, &version)
+	if err != nil || version>>8 != 1 {
+		return nil, fmt.Errorf("%w: unsupported KDF parameters", ErrCorrupt)
+	}
+	var d variantDictionary
+	for {
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: truncated KDF parameters", ErrCorrupt)
+		}
+		if kind == vdEnd {
+			return d, nil
+		}
+		var key, value []byte
+		for _, field := range []*[]byte{&key, &value} {
+			var n int32
+			err = binary.Read(r, binary.LittleEndian, &n)
+			if err != nil || n < 0 || int(n) > r.Len() {
+				return nil, fmt.Errorf("%w: truncated KDF parameters", ErrCorrupt)
+			}
+			*field = make([]byte, n)
+			_, _ = io.ReadFull(r, *field)
+		}
+		d.set(string(key), kind, value)
+	}
+}
+
+// newKDFParameters builds the parameters for a new database with a fresh salt.
+func newKDFParameters(opts Options, salt []byte) (variantDictionary, error) {
+	var d variantDictionary
+	switch opts.KDF {
+	case Argon2d:
+		d.set("$UUID", vdByteArray, kdfArgon2d[:])
+	case Argon2id:
+		d.set("$UUID", vdByteArray, kdfArgon2id[:])
+	default:
+		return nil, ErrUnsupportedKDF
+	}
+	d.set("S", vdByteArray, salt)
+	d.setUint32("P", opts.Parallelism)
+	d.setUint64("M", opts.Memory)
+	d.setUint64("I", opts.Iterations)
+	d.setUint32("V", argon2.Version)
+	return d, nil
+}
+
+// transform derives the 32 byte transformed key from the composite key.
+func (d variantDictionary) transform(composite []byte) ([]byte, error) {
+	id, err := d.bytes("$UUID")
+	if err != nil || len(id) != 16 {
+		return nil, ErrUnsupportedKDF
+	}
+	switch UUID(id) {
+	case kdfArgon2d, kdfArgon2id:
+		return d.argon2(UUID(id), composite)
+	case kdfAES:
+		return d.aesKDF(composite)
+	default:
+		return nil, ErrUnsupportedKDF
+	}
+}
+
+func (d variantDictionary) argon2(id UUID, composite []byte) ([]byte, error) {
+	salt, err := d.bytes("S")
+	if err != nil {
+		return nil, err
+	}
+	iterations, err := d.uint("I")
+	if err != nil {
+		return nil, err
+	}
+	memory, err := d.uint("M")
+	if err != nil {
+		return nil, err
+	}
+	parallelism, err := d.uint("P")
+	if err != nil {
+		return nil, err
+	}
+	version, err := d.uint("V")
+	if err != nil {
+		return nil, err
+	}
+	if version != argon2.Version {
+		return nil, fmt.Errorf("%w: Argon2 version %#x", ErrUnsupportedKDF, version)
+	}
+	if iterations == 0 || iterations > 1<<32-1 || memory/1024 > 1<<32-1 || parallelism == 0 || parallelism > 255 {
+		return nil, fmt.Errorf("%w: invalid Argon2 parameters", ErrCorrupt)
+	}
+	if memory > maxArgon2Memory {
+		return nil, fmt.Errorf("%w: Argon2 memory of %d MiB is over the limit of %d MiB", ErrUnsupportedKDF, memory>>20, maxArgon2Memory>>20)
+	}
+	if iterations > maxArgon2Iterations {
+		return nil, fmt.Errorf("%w: %d Argon2 iterations are over the limit of %d", ErrUnsupportedKDF, iterations, maxArgon2Iterations)
+	}
+	if id == kdfArgon2d {
+		return argon2d.Key(composite, salt, uint32(iterations), uint32(memory/1024), uint8(parallelism), 32), nil
+	}
+	return argon2.IDKey(composite, salt, uint32(iterations), uint32(memory/1024), uint8(parallelism), 32), nil
+}
+
+// aesKDF is the KDBX 3 key derivation: the key encrypted with AES-ECB for the
+// given number of rounds.
+func (d variantDictionary) aesKDF(composite []byte) ([]byte, error) {
+	seed, err := d.bytes("S")
+	if err != nil {
+		return nil, err
+	}
+	rounds, err := d.uint("R")
+	if err != nil {
+		return nil, err
+	}
+	if rounds > maxAESRounds {
+		return nil, fmt.Errorf("%w: %d AES-KDF rounds are over the limit of %d", ErrUnsupportedKDF, rounds, maxAESRounds)
+	}
+	block, err := aes.NewCipher(seed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid AES-KDF seed", ErrCorrupt)
+	}
+	key := append([]byte{}, composite...)
+	for i := uint64(0); i < rounds; i++ {
+		block.Encrypt(key[:16], key[:16])
+		block.Encrypt(key[16:], key[16:])
+	}
+	sum := sha256.Sum256(key)
+	return sum[:], nil
+}
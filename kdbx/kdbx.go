@@ -0,0 +1,150 @@
+// Package kdbx reads and writes KeePass KDBX 4 databases, the format used by
+// KeePass 2 and KeePassXC.
+package kdbx
+
+import (
+	"crypto/rand"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidSignature   = errors.New("not a KeePass database")
+	ErrUnsupportedVersion = errors.New("unsupported KeePass database version, only KDBX 4 is supported")
+	ErrInvalidCredentials = errors.New("invalid database password")
+	ErrCorrupt            = errors.New("database is corrupt")
+	ErrUnsupportedCipher  = errors.New("unsupported database cipher")
+	ErrUnsupportedKDF     = errors.New("unsupported key derivation function")
+)
+
+// Cipher is the outer encryption of the database.
+type Cipher int
+
+const (
+	AES256 Cipher = iota
+	ChaCha20
+)
+
+// KDF is the function that derives the database key from the password.
+type KDF int
+
+const (
+	Argon2d KDF = iota
+	Argon2id
+	AESKDF
+)
+
+// Options tune how a database is written.
+type Options struct {
+	Cipher Cipher
+	// KDF is Argon2d or Argon2id; AES-KDF is only supported for reading.
+	KDF KDF
+	// Iterations, Memory (in bytes) and Parallelism are the Argon2 cost
+	// parameters.
+	Iterations  uint64
+	Memory      uint64
+	Parallelism uint32
+}
+
+// DefaultOptions match the defaults of a new KeePassXC database.
+func DefaultOptions() Options {
+	return Options{
+		Cipher:      AES256,
+		KDF:         Argon2id,
+		Iterations:  10,
+		Memory:      64 << 20,
+		Parallelism: 2,
+	}
+}
+
+type UUID [16]byte
+
+func NewUUID() UUID {
+	var u UUID
+	_, err := rand.Read(u[:])
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// Well-known entry field keys.
+const (
+	TitleKey    = "Title"
+	UserNameKey = "UserName"
+	PasswordKey = "Password"
+	URLKey      = "URL"
+	NotesKey    = "Notes"
+	// OTPKey holds an otpauth:// URI, the convention of KeePassXC.
+	OTPKey = "otp"
+)
+
+type Database struct {
+	Name string
+	Root *Group
+	// RecycleBin is the UUID of the group holding deleted entries, zero if
+	// there is none.
+	RecycleBin UUID
+}
+
+type Group struct {
+	UUID    UUID
+	Name    string
+	Notes   string
+	Groups  []*Group
+	Entries []*Entry
+}
+
+// Field is a string field of an entry. Protected fields are encrypted inside
+// the database and hidden by the KeePass clients.
+type Field struct {
+	Key       string
+	Value     string
+	Protected bool
+}
+
+// Binary is a file attached to an entry.
+type Binary struct {
+	Name string
+	Data []byte
+}
+
+type Entry struct {
+	UUID             UUID
+	Tags             []string
+	Fields           []Field
+	Binaries         []Binary
+	CreationTime     time.Time
+	ModificationTime time.Time
+}
+
+// Get returns the value of the field with the given key, or an empty string.
+func (e *Entry) Get(key string) string {
+	for _, f := range e.Fields {
+		if f.Key == key {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Set adds or replaces a field.
+func (e *Entry) Set(key string, value string, protected bool) {
+	for i := range e.Fields {
+		if e.Fields[i].Key == key {
+			e.Fields[i] = Field{key, value, protected}
+			return
+		}
+	}
+	e.Fields = append(e.Fields, Field{key, value, protected})
+}
+
+// standardKeys are the fields every KeePass entry has.
+var standardKeys = map[string]bool{
+	TitleKey: true, UserNameKey: true, PasswordKey: true, URLKey: true, NotesKey: true,
+}
+
+// IsStandard reports whether key is one of the fields every entry has.
+func IsStandard(key string) bool {
+	return standardKeys[key]
+}
@@ -0,0 +1,191 @@
+package kdbx
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+// Read decrypts a KDBX 4 database with the password.
+func Read(r io.Reader, password string) (*Database, error) {
+	br := bufio.NewReader(r)
+	h, rawHeader, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	var headerHash, headerMAC [32]byte
+	_, err = io.ReadFull(br, headerHash[:])
+	if err == nil {
+		_, err = io.ReadFull(br, headerMAC[:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrCorrupt)
+	}
+	if sum := sha256.Sum256(rawHeader); !hmac.Equal(sum[:], headerHash[:]) {
+		return nil, fmt.Errorf("%w: header checksum mismatch", ErrCorrupt)
+	}
+	k, err := deriveKeys(h, password)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, k.blockKey(^uint64(0)))
+	mac.Write(rawHeader)
+	if !hmac.Equal(mac.Sum(nil), headerMAC[:]) {
+		return nil, ErrInvalidCredentials
+	}
+
+	encrypted, err := readBlocks(br, k)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := decryptPayload(h, k, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if h.compression == compressionGzip {
+		payload, err = decompress(payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return unmarshalPayload(payload)
+}
+
+// decompress gunzips the payload, up to maxPayloadSize.
+func decompress(payload []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupt, err)
+	}
+	out, err := io.ReadAll(io.LimitReader(zr, maxPayloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupt, err)
+	}
+	if len(out) > maxPayloadSize {
+		return nil, fmt.Errorf("%w: payload is over the limit of %d MiB", ErrCorrupt, maxPayloadSize>>20)
+	}
+	return out, nil
+}
+
+func readBlocks(r io.Reader, k *keys) ([]byte, error) {
+	var out bytes.Buffer
+	for index := uint64(0); ; index++ {
+		var blockMAC [32]byte
+		var size int32
+		_, err := io.ReadFull(r, blockMAC[:])
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, &size)
+		}
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("%w: truncated block %d", ErrCorrupt, index)
+		}
+		if size > maxBlockSize {
+			return nil, fmt.Errorf("%w: block %d is too large", ErrCorrupt, index)
+		}
+		data := make([]byte, size)
+		_, err = io.ReadFull(r, data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: truncated block %d", ErrCorrupt, index)
+		}
+		if !hmac.Equal(blockHMAC(k, index, data), blockMAC[:]) {
+			return nil, fmt.Errorf("%w: block %d failed authentication", ErrCorrupt, index)
+		}
+		if size == 0 {
+			return out.Bytes(), nil
+		}
+		out.Write(data)
+	}
+}
+
+func decryptPayload(h *header, k *keys, data []byte) ([]byte, error) {
+	if h.cipher == ChaCha20 {
+		c, err := chacha20.NewUnauthenticatedCipher(k.cipher, h.iv)
+		if err != nil {
+			return nil, err
+		}
+		c.XORKeyStream(data, data)
+		return data, nil
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: invalid payload length", ErrCorrupt)
+	}
+	block, err := aes.NewCipher(k.cipher)
+	if err != nil {
+		return nil, err
+	}
+	cipher.NewCBCDecrypter(block, h.iv).CryptBlocks(data, data)
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(data) {
+		return nil, fmt.Errorf("%w: invalid padding", ErrCorrupt)
+	}
+	return data[:len(data)-padding], nil
+}
+
+func unmarshalPayload(payload []byte) (*Database, error) {
+	r := bytes.NewReader(payload)
+	var streamID uint32
+	var streamKey []byte
+	var pool [][]byte
+	for {
+		var id byte
+		var size uint32
+		err := binary.Read(r, binary.LittleEndian, &id)
+		if err == nil {
+			err = binary.Read(r, binary.LittleEndian, &size)
+		}
+		if err != nil || int(size) > r.Len() {
+			return nil, fmt.Errorf("%w: truncated inner header", ErrCorrupt)
+		}
+		data := make([]byte, size)
+		_, _ = io.ReadFull(r, data)
+		if id == innerHeaderEnd {
+			break
+		}
+		switch id {
+		case innerHeaderStreamID:
+			if size != 4 {
+				return nil, fmt.Errorf("%w: invalid inner stream", ErrCorrupt)
+			}
+			streamID = binary.LittleEndian.Uint32(data)
+		case innerHeaderStreamKey:
+			streamKey = data
+		case innerHeaderBinary:
+			if size == 0 {
+				return nil, fmt.Errorf("%w: invalid attachment", ErrCorrupt)
+			}
+			// skip the memory protection flag
+			pool = append(pool, data[1:])
+		}
+	}
+	if streamID != innerStreamChaCha20 {
+		return nil, fmt.Errorf("%w: inner stream %d", ErrUnsupportedCipher, streamID)
+	}
+	stream, err := innerStream(streamKey)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	doc, err = transformProtected(doc, stream, false)
+	if err != nil {
+		return nil, err
+	}
+	var f xmlFile
+	err = xml.Unmarshal(doc, &f)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupt, err)
+	}
+	return fromXML(&f, pool)
+}
@@ -0,0 +1,185 @@
+package kdbx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+// blockSize is the payload size of the HMAC blocks, the KeePass default.
+const blockSize = 1 << 20
+
+// maxBlockSize is the largest block Read accepts. Each block is authenticated
+// only after it is read, so this bounds what a crafted file can allocate.
+const maxBlockSize = 64 << 20
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+// innerStream returns the cipher protecting field values inside the XML.
+func innerStream(key []byte) (cipher.Stream, error) {
+	sum := sha512.Sum512(key)
+	return chacha20.NewUnauthenticatedCipher(sum[:32], sum[32:44])
+}
+
+// Write encrypts the database with the password and writes it as KDBX 4.
+func Write(w io.Writer, db *Database, password string, opts Options) error {
+	masterSeed, err := randomBytes(32)
+	if err != nil {
+		return err
+	}
+	ivSize := aes.BlockSize
+	if opts.Cipher == ChaCha20 {
+		ivSize = chacha20.NonceSize
+	}
+	iv, err := randomBytes(ivSize)
+	if err != nil {
+		return err
+	}
+	salt, err := randomBytes(32)
+	if err != nil {
+		return err
+	}
+	kdf, err := newKDFParameters(opts, salt)
+	if err != nil {
+		return err
+	}
+	h := &header{
+		cipher:      opts.Cipher,
+		compression: compressionGzip,
+		masterSeed:  masterSeed,
+		iv:          iv,
+		kdf:         kdf,
+	}
+	k, err := deriveKeys(h, password)
+	if err != nil {
+		return err
+	}
+
+	payload, err := db.marshalPayload()
+	if err != nil {
+		return err
+	}
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, err = zw.Write(payload)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		return err
+	}
+	encrypted, err := encryptPayload(h, k, compressed.Bytes())
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	rawHeader := h.marshal()
+	out.Write(rawHeader)
+	headerHash := sha256.Sum256(rawHeader)
+	out.Write(headerHash[:])
+	mac := hmac.New(sha256.New, k.blockKey(^uint64(0)))
+	mac.Write(rawHeader)
+	out.Write(mac.Sum(nil))
+	writeBlocks(&out, k, encrypted)
+	_, err = out.WriteTo(w)
+	return err
+}
+
+// marshalPayload builds the inner header followed by the XML document.
+func (db *Database) marshalPayload() ([]byte, error) {
+	streamKey, err := randomBytes(64)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := innerStream(streamKey)
+	if err != nil {
+		return nil, err
+	}
+	doc, pool := db.toXML()
+	plain, err := xml.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	protected, err := transformProtected(plain, stream, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	streamID := make([]byte, 4)
+	binary.LittleEndian.PutUint32(streamID, innerStreamChaCha20)
+	writeField(&buf, innerHeaderStreamID, streamID)
+	writeField(&buf, innerHeaderStreamKey, streamKey)
+	for _, data := range pool {
+		// the leading flag byte marks attachments as not memory protected
+		writeField(&buf, innerHeaderBinary, append([]byte{0}, data...))
+	}
+	writeField(&buf, innerHeaderEnd, nil)
+	buf.WriteString(xml.Header)
+	buf.Write(protected)
+	return buf.Bytes(), nil
+}
+
+func encryptPayload(h *header, k *keys, data []byte) ([]byte, error) {
+	if h.cipher == ChaCha20 {
+		c, err := chacha20.NewUnauthenticatedCipher(k.cipher, h.iv)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(data))
+		c.XORKeyStream(out, data)
+		return out, nil
+	}
+	block, err := aes.NewCipher(k.cipher)
+	if err != nil {
+		return nil, err
+	}
+	padding := aes.BlockSize - len(data)%aes.BlockSize
+	padded := append(append([]byte{}, data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	out := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, h.iv).CryptBlocks(out, padded)
+	return out, nil
+}
+
+func blockHMAC(k *keys, index uint64, data []byte) []byte {
+	mac := hmac.New(sha256.New, k.blockKey(index))
+	_ = binary.Write(mac, binary.LittleEndian, index)
+	_ = binary.Write(mac, binary.LittleEndian, int32(len(data)))
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// writeBlocks splits the encrypted payload into HMAC authenticated blocks,
+// terminated by an empty one.
+func writeBlocks(w *bytes.Buffer, k *keys, data []byte) {
+	index := uint64(0)
+	for {
+		n := len(data)
+		if n > blockSize {
+			n = blockSize
+		}
+		w.Write(blockHMAC(k, index, data[:n]))
+		_ = binary.Write(w, binary.LittleEndian, int32(n))
+		w.Write(data[:n])
+		if n == 0 {
+			return
+		}
+		data = data[n:]
+		index++
+	}
+}
@@ -0,0 +1,336 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xmlBool is written as True/False like the KeePass clients do.
+type xmlBool bool
+
+func (b xmlBool) MarshalText() ([]byte, error) {
+	if b {
+		return []byte("True"), nil
+	}
+	return []byte("False"), nil
+}
+
+func (b *xmlBool) UnmarshalText(text []byte) error {
+	*b = xmlBool(strings.EqualFold(string(text), "true"))
+	return nil
+}
+
+type xmlFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    xmlMeta  `xml:"Meta"`
+	Root    xmlRoot  `xml:"Root"`
+}
+
+type xmlMeta struct {
+	Generator         string              `xml:"Generator"`
+	DatabaseName      string              `xml:"DatabaseName"`
+	MemoryProtection  xmlMemoryProtection `xml:"MemoryProtection"`
+	RecycleBinEnabled xmlBool             `xml:"RecycleBinEnabled"`
+	RecycleBinUUID    string              `xml:"RecycleBinUUID"`
+}
+
+type xmlMemoryProtection struct {
+	ProtectTitle    xmlBool `xml:"ProtectTitle"`
+	ProtectUserName xmlBool `xml:"ProtectUserName"`
+	ProtectPassword xmlBool `xml:"ProtectPassword"`
+	ProtectURL      xmlBool `xml:"ProtectURL"`
+	ProtectNotes    xmlBool `xml:"ProtectNotes"`
+}
+
+type xmlRoot struct {
+	Group          xmlGroup `xml:"Group"`
+	DeletedObjects struct{} `xml:"DeletedObjects"`
+}
+
+type xmlTimes struct {
+	CreationTime         string  `xml:"CreationTime"`
+	LastModificationTime string  `xml:"LastModificationTime"`
+	LastAccessTime       string  `xml:"LastAccessTime"`
+	ExpiryTime           string  `xml:"ExpiryTime"`
+	Expires              xmlBool `xml:"Expires"`
+	UsageCount           int     `xml:"UsageCount"`
+	LocationChanged      string  `xml:"LocationChanged"`
+}
+
+type xmlGroup struct {
+	UUID       string     `xml:"UUID"`
+	Name       string     `xml:"Name"`
+	Notes      string     `xml:"Notes"`
+	IconID     int        `xml:"IconID"`
+	Times      xmlTimes   `xml:"Times"`
+	IsExpanded xmlBool    `xml:"IsExpanded"`
+	Entries    []xmlEntry `xml:"Entry"`
+	Groups     []xmlGroup `xml:"Group"`
+}
+
+type xmlValue struct {
+	Protected xmlBool `xml:"Protected,attr,omitempty"`
+	Value     string  `xml:",chardata"`
+}
+
+type xmlString struct {
+	Key   string   `xml:"Key"`
+	Value xmlValue `xml:"Value"`
+}
+
+type xmlBinaryRef struct {
+	Key   string `xml:"Key"`
+	Value struct {
+		Ref int `xml:"Ref,attr"`
+	} `xml:"Value"`
+}
+
+type xmlEntry struct {
+	UUID     string         `xml:"UUID"`
+	IconID   int            `xml:"IconID"`
+	Times    xmlTimes       `xml:"Times"`
+	Tags     string         `xml:"Tags"`
+	Strings  []xmlString    `xml:"String"`
+	Binaries []xmlBinaryRef `xml:"Binary"`
+}
+
+// epoch is the zero of KDBX 4 timestamps.
+var epoch = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// encodeTime writes a KDBX 4 timestamp: base64 of the little-endian seconds
+// since year 1.
+func encodeTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	var buf [8]byte
+	// the duration since year 1 overflows time.Duration, so whole seconds
+	// are counted from the Unix epoch instead.
+	binary.LittleEndian.PutUint64(buf[:], uint64(t.Unix()-epoch.Unix()))
+	return base64.StdEncoding.EncodeToString(buf[:])
+}
+
+// decodeTime reads KDBX 4 timestamps and the ISO 8601 ones of KDBX 3.
+func decodeTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if raw, err := base64.StdEncoding.DecodeString(s); err == nil && len(raw) == 8 {
+		secs := int64(binary.LittleEndian.Uint64(raw))
+		return time.Unix(secs+epoch.Unix(), 0).UTC()
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func newXMLTimes(created time.Time, modified time.Time) xmlTimes {
+	if modified.IsZero() {
+		modified = created
+	}
+	return xmlTimes{
+		CreationTime:         encodeTime(created),
+		LastModificationTime: encodeTime(modified),
+		LastAccessTime:       encodeTime(modified),
+		ExpiryTime:           encodeTime(modified),
+		LocationChanged:      encodeTime(modified),
+	}
+}
+
+func encodeUUID(u UUID) string {
+	return base64.StdEncoding.EncodeToString(u[:])
+}
+
+func decodeUUID(s string) UUID {
+	var u UUID
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(raw) != 16 {
+		return NewUUID()
+	}
+	copy(u[:], raw)
+	return u
+}
+
+// transformProtected rewrites the XML so that every protected value is run
+// through the inner stream cipher, in document order as the format demands.
+// When encrypting, the plain values become base64 ciphertext; when decrypting
+// the reverse.
+func transformProtected(doc []byte, stream cipher.Stream, encrypt bool) ([]byte, error) {
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	var out bytes.Buffer
+	enc := xml.NewEncoder(&out)
+	protected := false
+	var text []byte
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCorrupt, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "Value" {
+				for _, a := range t.Attr {
+					if a.Name.Local == "Protected" && strings.EqualFold(a.Value, "true") {
+						protected = true
+						text = nil
+					}
+				}
+			}
+		case xml.CharData:
+			if protected {
+				text = append(text, t...)
+				continue
+			}
+		case xml.EndElement:
+			if protected && t.Name.Local == "Value" {
+				protected = false
+				value, err := applyStream(text, stream, encrypt)
+				if err != nil {
+					return nil, err
+				}
+				if err := enc.EncodeToken(xml.CharData(value)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := enc.EncodeToken(xml.CopyToken(tok)); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func applyStream(text []byte, stream cipher.Stream, encrypt bool) ([]byte, error) {
+	if encrypt {
+		ciphertext := make([]byte, len(text))
+		stream.XORKeyStream(ciphertext, text)
+		return []byte(base64.StdEncoding.EncodeToString(ciphertext)), nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(text)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid protected value", ErrCorrupt)
+	}
+	stream.XORKeyStream(raw, raw)
+	return raw, nil
+}
+
+// toXML converts the database, collecting attachments into the binary pool
+// of the inner header.
+func (db *Database) toXML() (*xmlFile, [][]byte) {
+	var pool [][]byte
+	var group func(g *Group, root bool) xmlGroup
+	group = func(g *Group, root bool) xmlGroup {
+		xg := xmlGroup{
+			UUID:       encodeUUID(g.UUID),
+			Name:       g.Name,
+			Notes:      g.Notes,
+			IconID:     48,
+			Times:      newXMLTimes(time.Now(), time.Now()),
+			IsExpanded: true,
+		}
+		if root {
+			xg.IconID = 49
+		}
+		for _, e := range g.Entries {
+			xe := xmlEntry{
+				UUID:  encodeUUID(e.UUID),
+				Times: newXMLTimes(e.CreationTime, e.ModificationTime),
+				Tags:  strings.Join(e.Tags, ";"),
+			}
+			for _, f := range e.Fields {
+				xe.Strings = append(xe.Strings, xmlString{
+					Key:   f.Key,
+					Value: xmlValue{Protected: xmlBool(f.Protected), Value: f.Value},
+				})
+			}
+			for _, b := range e.Binaries {
+				ref := xmlBinaryRef{Key: b.Name}
+				ref.Value.Ref = len(pool)
+				pool = append(pool, b.Data)
+				xe.Binaries = append(xe.Binaries, ref)
+			}
+			xg.Entries = append(xg.Entries, xe)
+		}
+		for _, sub := range g.Groups {
+			xg.Groups = append(xg.Groups, group(sub, false))
+		}
+		return xg
+	}
+	root := db.Root
+	if root == nil {
+		root = &Group{UUID: NewUUID(), Name: "Root"}
+	}
+	return &xmlFile{
+		Meta: xmlMeta{
+			Generator:         "gobw",
+			DatabaseName:      db.Name,
+			MemoryProtection:  xmlMemoryProtection{ProtectPassword: true},
+			RecycleBinEnabled: true,
+			RecycleBinUUID:    encodeUUID(db.RecycleBin),
+		},
+		Root: xmlRoot{Group: group(root, true)},
+	}, pool
+}
+
+// fromXML converts the decrypted XML back, resolving attachments from the
+// binary pool.
+func fromXML(f *xmlFile, pool [][]byte) (*Database, error) {
+	var group func(xg xmlGroup) (*Group, error)
+	group = func(xg xmlGroup) (*Group, error) {
+		g := &Group{UUID: decodeUUID(xg.UUID), Name: xg.Name, Notes: xg.Notes}
+		for _, xe := range xg.Entries {
+			e := &Entry{
+				UUID:             decodeUUID(xe.UUID),
+				CreationTime:     decodeTime(xe.Times.CreationTime),
+				ModificationTime: decodeTime(xe.Times.LastModificationTime),
+			}
+			for _, tag := range strings.FieldsFunc(xe.Tags, func(r rune) bool { return r == ';' || r == ',' }) {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					e.Tags = append(e.Tags, tag)
+				}
+			}
+			for _, s := range xe.Strings {
+				e.Fields = append(e.Fields, Field{Key: s.Key, Value: s.Value.Value, Protected: bool(s.Value.Protected)})
+			}
+			for _, b := range xe.Binaries {
+				if b.Value.Ref < 0 || b.Value.Ref >= len(pool) {
+					return nil, fmt.Errorf("%w: unknown attachment %s", ErrCorrupt, strconv.Quote(b.Key))
+				}
+				e.Binaries = append(e.Binaries, Binary{Name: b.Key, Data: pool[b.Value.Ref]})
+			}
+			g.Entries = append(g.Entries, e)
+		}
+		for _, xsub := range xg.Groups {
+			sub, err := group(xsub)
+			if err != nil {
+				return nil, err
+			}
+			g.Groups = append(g.Groups, sub)
+		}
+		return g, nil
+	}
+	root, err := group(f.Root.Group)
+	if err != nil {
+		return nil, err
+	}
+	db := &Database{Name: f.Meta.DatabaseName, Root: root}
+	if f.Meta.RecycleBinEnabled {
+		db.RecycleBin = decodeUUID(f.Meta.RecycleBinUUID)
+	}
+	return db, nil
+}
@@ -1,35 +1,147 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/atotto/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/sapslaj/gobw/agent"
 	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/bws"
+	"github.com/sapslaj/gobw/cli"
+	"github.com/sapslaj/gobw/config"
 	"github.com/sapslaj/gobw/ui"
 )
 
 func main() {
-	cmd := exec.Command("bw", "-v")
-	if err := cmd.Run(); err != nil {
-		fmt.Println("Could not find 'bw' command in '$PATH'. Please check if Bitwarden CLI is installed.\nGoodbye")
+	sessionStore := flag.String("session-store", "none", "persist the session key in `store` (none, keyring, file)")
+	sessionTTL := flag.Duration("session-ttl", time.Hour, "how long a stored session may be reused")
+	printSession := flag.Bool("print-session", false, "print 'export BW_SESSION=...' on exit, for use with eval")
+	forgetSession := flag.Bool("forget-session", false, "wipe the stored session and exit")
+	matchURL := flag.String("url", "", "only list logins with a URI matching `url`")
+	agentSocket := flag.String("agent", agent.DefaultSocketPath(), "use the gobw agent listening on `path` if it runs; empty to never use one")
+	flag.Parse()
+
+	args := flag.Args()
+	// git and docker run their helpers by name, e.g. credential.helper=gobw
+	// as "git-credential-gobw get"
+	switch filepath.Base(os.Args[0]) {
+	case "git-credential-gobw":
+		args = append([]string{"git-credential"}, args...)
+	case "docker-credential-gobw":
+		args = append([]string{"docker-credential"}, args...)
+	}
+	// stdout is read by git, docker and eval, so errors go to stderr. git
+	// asks its next credential helper when one has no answer, so a helper
+	// that cannot run at all stays silent.
+	quiet := len(args) > 1 && args[0] == "git-credential" && args[len(args)-1] == "get"
+	fail := func(a ...any) {
+		if quiet {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, a...)
 		os.Exit(1)
 	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fail(err)
+	}
+	if *matchURL != "" {
+		if _, err := bw.NewURIMatcher(*matchURL, nil); err != nil {
+			fail("Invalid URL:", err)
+		}
+	}
+
+	store, err := bw.NewSessionStore(*sessionStore, *sessionTTL)
+	if err != nil {
+		fail(err)
+	}
+	pinStore, err := bw.NewPinStore(cfg.PinStore, cfg.PinWindow())
+	if err != nil {
+		fail(err)
+	}
+	if *forgetSession {
+		if store == nil && pinStore == nil {
+			fail("No session store selected. Use -session-store to pick one.")
+		}
+		for _, s := range []bw.SessionStore{store, pinStore} {
+			if s == nil {
+				continue
+			}
+			if err := s.Clear(); err != nil {
+				fail(err)
+			}
+		}
+		return
+	}
+
+	// Secrets Manager only needs bw to look the access token up in the vault,
+	// machines may have just bws and BWS_ACCESS_TOKEN
+	needsBW := len(args) == 0 || args[0] != "secrets" ||
+		(os.Getenv(bws.AccessTokenEnv) == "" && bw.IsSecretRef(cfg.BWSAccessToken))
+	if needsBW {
+		cmd := exec.Command("bw", "-v")
+		if err := cmd.Run(); err != nil {
+			fail("Could not find 'bw' command in '$PATH'. Please check if Bitwarden CLI is installed.\nGoodbye")
+		}
+	}
+	bwOpts := []bw.Option{
+		bw.WithSessionStore(store),
+		bw.WithEquivalentDomains(cfg.EquivalentDomains),
+		bw.WithPinStore(pinStore, bw.PinOptions{Attempts: cfg.PinMaxAttempts(), Window: cfg.PinWindow()}),
+	}
+	// the agent itself must not talk to another one
+	if *agentSocket != "" && (len(args) == 0 || args[0] != "agent") {
+		if client, err := agent.Dial(*agentSocket); err == nil {
+			bwOpts = append(bwOpts, bw.WithAgent(client))
+		}
+	}
+	bwm := bw.NewBWManager(bwOpts...)
+	if needsBW {
+		if err := bwm.UpdateStatus(); err != nil {
+			fail(err)
+		}
+	}
+	if len(args) > 0 {
+		os.Exit(cli.New(bwm, cfg, os.Stdout, os.Stderr).Run(args))
+	}
 	if clipboard.Unsupported {
 		// TODO: better error message
 		panic("failed to setup clipboard.")
 	}
-	bwm := bw.NewBWManager()
-	if err := bwm.UpdateStatus(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if *printSession {
+		// stdout is captured by the shell, so the TUI has to talk to the
+		// terminal directly.
+		tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		if err != nil {
+			fail("Could not open terminal:", err)
+		}
+		opts = append(opts, tea.WithInput(tty), tea.WithOutput(tty))
 	}
-	m := ui.NewMainModel(bwm)
-	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
-		fmt.Println("Error running program:", err)
+	state, err := config.LoadState()
+	if err != nil {
+		fail(err)
+	}
+	// the access token may be a reference into the vault, which is only
+	// unlocked later on
+	sm := bws.NewCLI(cfg.BWSPath, func() (string, error) {
+		return bwm.ResolveValue(cfg.BWSAccessToken)
+	}, cfg.BWSServerURL)
+	m := ui.NewMainModel(bwm, ui.WithURL(*matchURL), ui.WithState(state), ui.WithConfig(cfg), ui.WithSecretsManager(sm))
+	if _, err := tea.NewProgram(m, opts...).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error running program:", err)
 		os.Exit(1)
 	}
+	if *printSession && bwm.Session() != "" {
+		fmt.Printf("export BW_SESSION='%s'\n", strings.ReplaceAll(bwm.Session(), "'", `'\''`))
+	}
 }
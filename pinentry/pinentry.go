@@ -0,0 +1,168 @@
+// Package pinentry asks for passwords with a pinentry program, the password
+// dialogs of GnuPG (pinentry-curses, -gtk-2, -qt, -tty and others), by
+// speaking the Assuan protocol to it.
+package pinentry
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// gpgErrCanceled is the GPG_ERR_CANCELED error code in the low bits of the
+// errors pinentry reports when the dialog is closed.
+const gpgErrCanceled = 99
+
+var ErrCanceled = errors.New("pinentry canceled")
+
+// Prompt is the text of a password dialog. Only Prompt is required.
+type Prompt struct {
+	Title       string
+	Description string
+	Prompt      string
+	// Error is shown above the input, e.g. after a wrong password.
+	Error string
+}
+
+// GetPin shows a password dialog with program and returns what was entered.
+// A dialog closed without an answer returns ErrCanceled.
+func GetPin(program string, p Prompt) (string, error) {
+	cmd := exec.Command(program) // #nosec G204
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", fmt.Errorf("failed to run pinentry: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", fmt.Errorf("failed to run pinentry: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to run pinentry: %w", err)
+	}
+	c := &conn{w: stdin, r: bufio.NewReader(stdout)}
+	pin, err := c.getPin(p)
+	// BYE ends pinentry; it is gone already if the conversation failed
+	_, _ = c.command("BYE")
+	stdin.Close()
+	_ = cmd.Wait()
+	if err != nil {
+		return "", err
+	}
+	return pin, nil
+}
+
+// conn is an Assuan connection to pinentry.
+type conn struct {
+	w io.Writer
+	r *bufio.Reader
+}
+
+func (c *conn) getPin(p Prompt) (string, error) {
+	// the server greets with OK
+	if _, err := c.response(); err != nil {
+		return "", err
+	}
+	commands := []string{}
+	if tty := ttyName(); tty != "" {
+		// curses and tty pinentries draw on the terminal gobw runs in
+		commands = append(commands, "OPTION ttyname="+tty)
+		if term := os.Getenv("TERM"); term != "" {
+			commands = append(commands, "OPTION ttytype="+term)
+		}
+	}
+	for _, arg := range []struct {
+		cmd   string
+		value string
+	}{
+		{"SETTITLE", p.Title},
+		{"SETDESC", p.Description},
+		{"SETPROMPT", p.Prompt},
+		{"SETERROR", p.Error},
+	} {
+		if arg.value != "" {
+			commands = append(commands, arg.cmd+" "+escape(arg.value))
+		}
+	}
+	for _, cmd := range commands {
+		if _, err := c.command(cmd); err != nil {
+			return "", err
+		}
+	}
+	data, err := c.command("GETPIN")
+	if err != nil {
+		return "", err
+	}
+	return data, nil
+}
+
+// command sends a command and returns the data of the response.
+func (c *conn) command(cmd string) (string, error) {
+	if _, err := io.WriteString(c.w, cmd+"\n"); err != nil {
+		return "", fmt.Errorf("failed to talk to pinentry: %w", err)
+	}
+	return c.response()
+}
+
+// response reads lines up to OK or ERR, collecting the data lines.
+func (c *conn) response() (string, error) {
+	var data strings.Builder
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("failed to talk to pinentry: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		keyword, rest, _ := strings.Cut(line, " ")
+		switch keyword {
+		case "OK":
+			return data.String(), nil
+		case "ERR":
+			return "", responseError(rest)
+		case "D":
+			value, err := url.PathUnescape(rest)
+			if err != nil {
+				return "", fmt.Errorf("invalid data from pinentry: %w", err)
+			}
+			data.WriteString(value)
+		case "INQUIRE":
+			// gobw has nothing to answer with
+			if _, err := io.WriteString(c.w, "CAN\n"); err != nil {
+				return "", fmt.Errorf("failed to talk to pinentry: %w", err)
+			}
+		}
+		// status (S) and comment (#) lines carry nothing gobw needs
+	}
+}
+
+// responseError turns "ERR <code> <description>" into an error.
+func responseError(rest string) error {
+	code, desc, _ := strings.Cut(rest, " ")
+	if n, err := strconv.ParseUint(code, 10, 32); err == nil && n&0xffff == gpgErrCanceled {
+		return ErrCanceled
+	}
+	return fmt.Errorf("pinentry failed: %s", desc)
+}
+
+// escape percent-encodes the characters Assuan does not allow in arguments.
+func escape(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// ttyName returns the terminal for pinentry to use, like GPG_TTY for gpg.
+func ttyName() string {
+	if tty := os.Getenv("GPG_TTY"); tty != "" {
+		return tty
+	}
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return ""
+	}
+	f.Close()
+	return "/dev/tty"
+}
@@ -0,0 +1,281 @@
+package secretservice
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// properties implements org.freedesktop.DBus.Properties for one interface of
+// an object. The values are read fresh on every call.
+type properties struct {
+	s     *Service
+	iface string
+	get   func() map[string]dbus.Variant
+	// set handles writes; properties without it are read-only.
+	set func(name string, value dbus.Variant) *dbus.Error
+}
+
+func (p *properties) Get(iface string, name string) (dbus.Variant, *dbus.Error) {
+	all, dbusErr := p.GetAll(iface)
+	if dbusErr != nil {
+		return dbus.Variant{}, dbusErr
+	}
+	value, ok := all[name]
+	if !ok {
+		return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []any{"unknown property " + name})
+	}
+	return value, nil
+}
+
+func (p *properties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	if iface != p.iface {
+		return nil, errUnknownInterface(iface)
+	}
+	p.s.mu.Lock()
+	defer p.s.mu.Unlock()
+	return p.get(), nil
+}
+
+func (p *properties) Set(iface string, name string, value dbus.Variant) *dbus.Error {
+	if iface != p.iface {
+		return errUnknownInterface(iface)
+	}
+	if p.set == nil {
+		return errPropertyReadOnly(name)
+	}
+	p.s.mu.Lock()
+	defer p.s.mu.Unlock()
+	return p.set(name, value)
+}
+
+func errUnknownInterface(iface string) *dbus.Error {
+	err := dbus.MakeUnknownInterfaceError(iface)
+	return &err
+}
+
+func errPropertyReadOnly(name string) *dbus.Error {
+	return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []any{"property " + name + " is read-only"})
+}
+
+// introspectable implements org.freedesktop.DBus.Introspectable with XML
+// built on every call, as the children of the collection come and go.
+type introspectable func() string
+
+func (f introspectable) Introspect() (string, *dbus.Error) {
+	return f(), nil
+}
+
+// introspect returns the introspection of a leaf object implementing iface,
+// given as XML.
+func introspect(iface string) introspectable {
+	return func() string {
+		return introspectNode(iface, nil)
+	}
+}
+
+func introspectNode(iface string, children []string) string {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE node PUBLIC "-//freedesktop//DTD D-BUS Object Introspection 1.0//EN" "http://www.freedesktop.org/standards/dbus/1.0/introspect.dtd">` + "\n<node>\n")
+	b.WriteString(standardInterfacesXML)
+	b.WriteString(iface)
+	for _, child := range children {
+		b.WriteString(`  <node name="` + child + `"/>` + "\n")
+	}
+	b.WriteString("</node>\n")
+	return b.String()
+}
+
+func (s *Service) introspectService() string {
+	return introspectNode(ifaceServiceXML, nil)
+}
+
+func (s *Service) introspectCollection() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	children := make([]string, 0, len(s.items))
+	for path := range s.items {
+		children = append(children, strings.TrimPrefix(string(path), string(collectionPath)+"/"))
+	}
+	sort.Strings(children)
+	return introspectNode(ifaceCollectionXML, children)
+}
+
+// export puts obj on the bus at path as iface, along with its properties,
+// if any, and its introspection.
+func (s *Service) export(path dbus.ObjectPath, iface string, obj any, props *properties, xml introspectable) error {
+	if err := s.conn.Export(obj, path, iface); err != nil {
+		return err
+	}
+	if props != nil {
+		if err := s.conn.Export(props, path, ifaceProperties); err != nil {
+			return err
+		}
+	}
+	return s.conn.Export(xml, path, ifaceIntrospectable)
+}
+
+// unexport removes everything export put at path.
+func (s *Service) unexport(path dbus.ObjectPath, iface string) {
+	for _, name := range []string{iface, ifaceProperties, ifaceIntrospectable} {
+		// unexporting only fails for invalid paths
+		_ = s.conn.Export(nil, path, name)
+	}
+}
+
+const standardInterfacesXML = `  <interface name="org.freedesktop.DBus.Properties">
+    <method name="Get">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="name" type="s" direction="in"/>
+      <arg name="value" type="v" direction="out"/>
+    </method>
+    <method name="GetAll">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="props" type="a{sv}" direction="out"/>
+    </method>
+    <method name="Set">
+      <arg name="interface" type="s" direction="in"/>
+      <arg name="name" type="s" direction="in"/>
+      <arg name="value" type="v" direction="in"/>
+    </method>
+    <signal name="PropertiesChanged">
+      <arg name="interface" type="s"/>
+      <arg name="changed_properties" type="a{sv}"/>
+      <arg name="invalidated_properties" type="as"/>
+    </signal>
+  </interface>
+  <interface name="org.freedesktop.DBus.Introspectable">
+    <method name="Introspect">
+      <arg name="xml_data" type="s" direction="out"/>
+    </method>
+  </interface>
+  <interface name="org.freedesktop.DBus.Peer">
+    <method name="Ping"/>
+    <method name="GetMachineId">
+      <arg name="machine_uuid" type="s" direction="out"/>
+    </method>
+  </interface>
+`
+
+const ifaceServiceXML = `  <interface name="org.freedesktop.Secret.Service">
+    <method name="OpenSession">
+      <arg name="algorithm" type="s" direction="in"/>
+      <arg name="input" type="v" direction="in"/>
+      <arg name="output" type="v" direction="out"/>
+      <arg name="result" type="o" direction="out"/>
+    </method>
+    <method name="CreateCollection">
+      <arg name="properties" type="a{sv}" direction="in"/>
+      <arg name="alias" type="s" direction="in"/>
+      <arg name="collection" type="o" direction="out"/>
+      <arg name="prompt" type="o" direction="out"/>
+    </method>
+    <method name="SearchItems">
+      <arg name="attributes" type="a{ss}" direction="in"/>
+      <arg name="unlocked" type="ao" direction="out"/>
+      <arg name="locked" type="ao" direction="out"/>
+    </method>
+    <method name="Unlock">
+      <arg name="objects" type="ao" direction="in"/>
+      <arg name="unlocked" type="ao" direction="out"/>
+      <arg name="prompt" type="o" direction="out"/>
+    </method>
+    <method name="Lock">
+      <arg name="objects" type="ao" direction="in"/>
+      <arg name="locked" type="ao" direction="out"/>
+      <arg name="Prompt" type="o" direction="out"/>
+    </method>
+    <method name="GetSecrets">
+      <arg name="items" type="ao" direction="in"/>
+      <arg name="session" type="o" direction="in"/>
+      <arg name="secrets" type="a{o(oayays)}" direction="out"/>
+    </method>
+    <method name="ReadAlias">
+      <arg name="name" type="s" direction="in"/>
+      <arg name="collection" type="o" direction="out"/>
+    </method>
+    <method name="SetAlias">
+      <arg name="name" type="s" direction="in"/>
+      <arg name="collection" type="o" direction="in"/>
+    </method>
+    <signal name="CollectionCreated">
+      <arg name="collection" type="o"/>
+    </signal>
+    <signal name="CollectionDeleted">
+      <arg name="collection" type="o"/>
+    </signal>
+    <signal name="CollectionChanged">
+      <arg name="collection" type="o"/>
+    </signal>
+    <property name="Collections" type="ao" access="read"/>
+  </interface>
+`
+
+const ifaceCollectionXML = `  <interface name="org.freedesktop.Secret.Collection">
+    <method name="Delete">
+      <arg name="prompt" type="o" direction="out"/>
+    </method>
+    <method name="SearchItems">
+      <arg name="attributes" type="a{ss}" direction="in"/>
+      <arg name="results" type="ao" direction="out"/>
+    </method>
+    <method name="CreateItem">
+      <arg name="properties" type="a{sv}" direction="in"/>
+      <arg name="secret" type="(oayays)" direction="in"/>
+      <arg name="replace" type="b" direction="in"/>
+      <arg name="item" type="o" direction="out"/>
+      <arg name="prompt" type="o" direction="out"/>
+    </method>
+    <signal name="ItemCreated">
+      <arg name="item" type="o"/>
+    </signal>
+    <signal name="ItemDeleted">
+      <arg name="item" type="o"/>
+    </signal>
+    <signal name="ItemChanged">
+      <arg name="item" type="o"/>
+    </signal>
+    <property name="Items" type="ao" access="read"/>
+    <property name="Label" type="s" access="read"/>
+    <property name="Locked" type="b" access="read"/>
+    <property name="Created" type="t" access="read"/>
+    <property name="Modified" type="t" access="read"/>
+  </interface>
+`
+
+const ifaceItemXML = `  <interface name="org.freedesktop.Secret.Item">
+    <method name="Delete">
+      <arg name="Prompt" type="o" direction="out"/>
+    </method>
+    <method name="GetSecret">
+      <arg name="session" type="o" direction="in"/>
+      <arg name="secret" type="(oayays)" direction="out"/>
+    </method>
+    <method name="SetSecret">
+      <arg name="secret" type="(oayays)" direction="in"/>
+    </method>
+    <property name="Locked" type="b" access="read"/>
+    <property name="Attributes" type="a{ss}" access="read"/>
+    <property name="Label" type="s" access="readwrite"/>
+    <property name="Created" type="t" access="read"/>
+    <property name="Modified" type="t" access="read"/>
+  </interface>
+`
+
+const ifaceSessionXML = `  <interface name="org.freedesktop.Secret.Session">
+    <method name="Close"/>
+  </interface>
+`
+
+const ifacePromptXML = `  <interface name="org.freedesktop.Secret.Prompt">
+    <method name="Prompt">
+      <arg name="window-id" type="s" direction="in"/>
+    </method>
+    <method name="Dismiss"/>
+    <signal name="Completed">
+      <arg name="dismissed" type="b"/>
+      <arg name="result" type="v"/>
+    </signal>
+  </interface>
+`
@@ -0,0 +1,403 @@
+package secretservice
+
+import (
+	"github.com/godbus/dbus/v5"
+)
+
+// Item properties CreateItem reads.
+const (
+	propItemLabel      = ifaceItem + ".Label"
+	propItemAttributes = ifaceItem + ".Attributes"
+)
+
+// serviceObject implements org.freedesktop.Secret.Service. Like the other
+// objects below, its exported methods are the D-Bus methods.
+type serviceObject struct {
+	s *Service
+}
+
+func (o serviceObject) OpenSession(algorithm string, input dbus.Variant, sender dbus.Sender) (dbus.Variant, dbus.ObjectPath, *dbus.Error) {
+	s := o.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.nextPath(sessionPrefix)
+	var sess *session
+	var output dbus.Variant
+	switch algorithm {
+	case AlgorithmPlain:
+		sess = &session{path: path, owner: string(sender)}
+		output = dbus.MakeVariant("")
+	case AlgorithmDH:
+		public, ok := input.Value().([]byte)
+		if !ok {
+			return dbus.Variant{}, "", errInvalidArgs("expected the client's public key")
+		}
+		var err error
+		var servicePublic []byte
+		sess, servicePublic, err = newDHSession(path, string(sender), public)
+		if err != nil {
+			return dbus.Variant{}, "", errInvalidArgs(err.Error())
+		}
+		output = dbus.MakeVariant(servicePublic)
+	default:
+		return dbus.Variant{}, "", errNotSupported("unsupported algorithm " + algorithm)
+	}
+	if err := s.openSession(sess); err != nil {
+		return dbus.Variant{}, "", dbus.MakeFailedError(err)
+	}
+	return output, path, nil
+}
+
+// CreateCollection hands out the vault, the only collection there is.
+func (o serviceObject) CreateCollection(properties map[string]dbus.Variant, alias string) (dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	return collectionPath, noPrompt, nil
+}
+
+func (o serviceObject) SearchItems(attributes map[string]string) ([]dbus.ObjectPath, []dbus.ObjectPath, *dbus.Error) {
+	s := o.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locked {
+		return []dbus.ObjectPath{}, s.search(attributes), nil
+	}
+	return s.search(attributes), []dbus.ObjectPath{}, nil
+}
+
+// Unlock unlocks the vault, and with it all objects, after a prompt for the
+// master password.
+func (o serviceObject) Unlock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	s := o.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.locked {
+		return objects, noPrompt, nil
+	}
+	path := s.nextPath(promptPrefix)
+	p := &promptObject{s: s, path: path, objects: objects}
+	if err := s.export(path, ifacePrompt, p, nil, introspect(ifacePromptXML)); err != nil {
+		return nil, "", dbus.MakeFailedError(err)
+	}
+	return []dbus.ObjectPath{}, path, nil
+}
+
+// Lock locks the vault, and with it all objects.
+func (o serviceObject) Lock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	if err := o.s.lock(); err != nil {
+		return nil, "", dbus.MakeFailedError(err)
+	}
+	return objects, noPrompt, nil
+}
+
+func (o serviceObject) GetSecrets(items []dbus.ObjectPath, sessionPath dbus.ObjectPath, sender dbus.Sender) (map[dbus.ObjectPath]Secret, *dbus.Error) {
+	s := o.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, dbusErr := s.session(sessionPath, sender)
+	if dbusErr != nil {
+		return nil, dbusErr
+	}
+	secrets := make(map[dbus.ObjectPath]Secret, len(items))
+	for _, path := range items {
+		item, dbusErr := s.vaultItem(path)
+		if dbusErr == errIsLocked {
+			return nil, dbusErr
+		}
+		if dbusErr != nil {
+			// unknown items are left out of the result
+			continue
+		}
+		item, err := s.bwm.CheckReprompt(item, "")
+		if err != nil {
+			return nil, errReprompt(err)
+		}
+		secret, err := sess.encrypt([]byte(item.SecretValue()))
+		if err != nil {
+			return nil, dbus.MakeFailedError(err)
+		}
+		secrets[path] = secret
+	}
+	return secrets, nil
+}
+
+func (o serviceObject) ReadAlias(name string) (dbus.ObjectPath, *dbus.Error) {
+	if name == "default" {
+		return collectionPath, nil
+	}
+	return noPrompt, nil
+}
+
+func (o serviceObject) SetAlias(name string, collection dbus.ObjectPath) *dbus.Error {
+	return errNotSupported("the vault is the only collection")
+}
+
+func (s *Service) serviceProperties() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"Collections": dbus.MakeVariant([]dbus.ObjectPath{collectionPath}),
+	}
+}
+
+// collectionObject implements org.freedesktop.Secret.Collection for the
+// vault.
+type collectionObject struct {
+	s *Service
+}
+
+func (o collectionObject) Delete() (dbus.ObjectPath, *dbus.Error) {
+	return "", errNotSupported("the vault cannot be deleted")
+}
+
+func (o collectionObject) SearchItems(attributes map[string]string) ([]dbus.ObjectPath, *dbus.Error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	return o.s.search(attributes), nil
+}
+
+func (o collectionObject) CreateItem(properties map[string]dbus.Variant, secret Secret, replace bool, sender dbus.Sender) (dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	s := o.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locked {
+		return "", "", errIsLocked
+	}
+	sess, dbusErr := s.session(secret.Session, sender)
+	if dbusErr != nil {
+		return "", "", dbusErr
+	}
+	value, err := sess.decrypt(secret)
+	if err != nil {
+		return "", "", errInvalidArgs(err.Error())
+	}
+	label, _ := properties[propItemLabel].Value().(string)
+	attrs, _ := properties[propItemAttributes].Value().(map[string]string)
+	item, err := s.bwm.StoreSecretItem(label, attrs, string(value), replace)
+	if err != nil {
+		return "", "", dbus.MakeFailedError(err)
+	}
+	if err := s.updateItems(); err != nil {
+		return "", "", dbus.MakeFailedError(err)
+	}
+	return itemPath(item.ID), noPrompt, nil
+}
+
+func (s *Service) collectionProperties() map[string]dbus.Variant {
+	paths := make([]dbus.ObjectPath, 0, len(s.items))
+	modified := s.started
+	for path, e := range s.items {
+		paths = append(paths, path)
+		if e.item.RevisionDate.After(modified) {
+			modified = e.item.RevisionDate
+		}
+	}
+	return map[string]dbus.Variant{
+		"Items":    dbus.MakeVariant(paths),
+		"Label":    dbus.MakeVariant(collectionLabel),
+		"Locked":   dbus.MakeVariant(s.locked),
+		"Created":  dbus.MakeVariant(uint64(s.started.Unix())),
+		"Modified": dbus.MakeVariant(uint64(modified.Unix())),
+	}
+}
+
+// exportCollection exports the vault at its own path and as the default
+// alias.
+func (s *Service) exportCollection() error {
+	for _, path := range []dbus.ObjectPath{collectionPath, defaultAliasPath} {
+		props := &properties{s: s, iface: ifaceCollection, get: s.collectionProperties}
+		err := s.export(path, ifaceCollection, collectionObject{s}, props, s.introspectCollection)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// itemObject implements org.freedesktop.Secret.Item for a vault item.
+type itemObject struct {
+	s    *Service
+	path dbus.ObjectPath
+}
+
+// Delete moves the item to the trash.
+func (o itemObject) Delete() (dbus.ObjectPath, *dbus.Error) {
+	s := o.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, dbusErr := s.vaultItem(o.path)
+	if dbusErr != nil {
+		return "", dbusErr
+	}
+	if err := s.bwm.DeleteSecretItem(item.ID); err != nil {
+		return "", errEdit(err)
+	}
+	if err := s.updateItems(); err != nil {
+		return "", dbus.MakeFailedError(err)
+	}
+	return noPrompt, nil
+}
+
+func (o itemObject) GetSecret(sessionPath dbus.ObjectPath, sender dbus.Sender) (Secret, *dbus.Error) {
+	s := o.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, dbusErr := s.session(sessionPath, sender)
+	if dbusErr != nil {
+		return Secret{}, dbusErr
+	}
+	item, dbusErr := s.vaultItem(o.path)
+	if dbusErr != nil {
+		return Secret{}, dbusErr
+	}
+	item, err := s.bwm.CheckReprompt(item, "")
+	if err != nil {
+		return Secret{}, errReprompt(err)
+	}
+	secret, err := sess.encrypt([]byte(item.SecretValue()))
+	if err != nil {
+		return Secret{}, dbus.MakeFailedError(err)
+	}
+	return secret, nil
+}
+
+func (o itemObject) SetSecret(secret Secret, sender dbus.Sender) *dbus.Error {
+	s := o.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, dbusErr := s.session(secret.Session, sender)
+	if dbusErr != nil {
+		return dbusErr
+	}
+	item, dbusErr := s.vaultItem(o.path)
+	if dbusErr != nil {
+		return dbusErr
+	}
+	value, err := sess.decrypt(secret)
+	if err != nil {
+		return errInvalidArgs(err.Error())
+	}
+	if _, err := s.bwm.SetSecretValue(item.ID, string(value)); err != nil {
+		return errEdit(err)
+	}
+	if err := s.updateItems(); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+func (s *Service) itemProperties(path dbus.ObjectPath) func() map[string]dbus.Variant {
+	return func() map[string]dbus.Variant {
+		e := s.items[path]
+		return map[string]dbus.Variant{
+			"Locked":     dbus.MakeVariant(s.locked),
+			"Attributes": dbus.MakeVariant(e.attrs),
+			"Label":      dbus.MakeVariant(e.item.Name),
+			"Created":    dbus.MakeVariant(uint64(e.item.CreationDate.Unix())),
+			"Modified":   dbus.MakeVariant(uint64(e.item.RevisionDate.Unix())),
+		}
+	}
+}
+
+// setItemLabel renames the item when a client sets its Label.
+func (s *Service) setItemLabel(path dbus.ObjectPath) func(string, dbus.Variant) *dbus.Error {
+	return func(name string, value dbus.Variant) *dbus.Error {
+		label, ok := value.Value().(string)
+		if name != "Label" || !ok {
+			return errPropertyReadOnly(name)
+		}
+		item, dbusErr := s.vaultItem(path)
+		if dbusErr != nil {
+			return dbusErr
+		}
+		if _, err := s.bwm.RenameSecretItem(item.ID, label); err != nil {
+			return errEdit(err)
+		}
+		if err := s.updateItems(); err != nil {
+			return dbus.MakeFailedError(err)
+		}
+		return nil
+	}
+}
+
+func (s *Service) exportItem(path dbus.ObjectPath) error {
+	props := &properties{s: s, iface: ifaceItem, get: s.itemProperties(path), set: s.setItemLabel(path)}
+	return s.export(path, ifaceItem, itemObject{s, path}, props, introspect(ifaceItemXML))
+}
+
+// sessionObject implements org.freedesktop.Secret.Session.
+type sessionObject struct {
+	s    *Service
+	path dbus.ObjectPath
+}
+
+func (o sessionObject) Close(sender dbus.Sender) *dbus.Error {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	if _, dbusErr := o.s.session(o.path, sender); dbusErr != nil {
+		return dbusErr
+	}
+	o.s.closeSession(o.path)
+	return nil
+}
+
+// promptObject implements org.freedesktop.Secret.Prompt for unlocking the
+// vault.
+type promptObject struct {
+	s       *Service
+	path    dbus.ObjectPath
+	objects []dbus.ObjectPath
+	// started and done are guarded by s.mu.
+	started bool
+	done    bool
+}
+
+// Prompt asks for the master password in the background and signals
+// Completed once the vault is unlocked or the password was not given.
+func (p *promptObject) Prompt(windowID string) *dbus.Error {
+	p.s.mu.Lock()
+	defer p.s.mu.Unlock()
+	if !p.started {
+		p.started = true
+		go p.run()
+	}
+	return nil
+}
+
+func (p *promptObject) Dismiss() *dbus.Error {
+	p.complete(false)
+	return nil
+}
+
+func (p *promptObject) run() {
+	s := p.s
+	s.unlockMu.Lock()
+	defer s.unlockMu.Unlock()
+	var err error
+	for attempt := 0; attempt < unlockAttempts; attempt++ {
+		var password string
+		password, err = s.password(attempt)
+		if err != nil {
+			break
+		}
+		err = s.unlock(password)
+		if err == nil {
+			break
+		}
+	}
+	p.complete(err == nil)
+}
+
+// complete ends the prompt, with the unlocked objects as result unless it
+// was dismissed.
+func (p *promptObject) complete(unlocked bool) {
+	s := p.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.done {
+		return
+	}
+	p.done = true
+	result := []dbus.ObjectPath{}
+	if unlocked {
+		result = p.objects
+	}
+	s.emit(p.path, ifacePrompt+".Completed", !unlocked, dbus.MakeVariant(result))
+	s.unexport(p.path, ifacePrompt)
+}
@@ -0,0 +1,396 @@
+// Package secretservice provides the items in the vault over the
+// freedesktop.org Secret Service API on D-Bus, so that libsecret
+// applications and secret-tool read and store their secrets in the vault.
+//
+// The vault is a single collection, also known as the default alias. Items
+// are looked up by the attributes bw.Item.SecretAttributes derives from them,
+// and the collection is locked whenever the vault is.
+package secretservice
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+// BusName is the well-known name of the Secret Service on the session bus.
+const BusName = "org.freedesktop.secrets"
+
+const (
+	servicePath      dbus.ObjectPath = "/org/freedesktop/secrets"
+	collectionPath   dbus.ObjectPath = "/org/freedesktop/secrets/collection/bitwarden"
+	defaultAliasPath dbus.ObjectPath = "/org/freedesktop/secrets/aliases/default"
+	sessionPrefix                    = "/org/freedesktop/secrets/session/s"
+	promptPrefix                     = "/org/freedesktop/secrets/prompt/p"
+	// noPrompt is returned where no prompt is needed.
+	noPrompt dbus.ObjectPath = "/"
+
+	collectionLabel = "Bitwarden"
+	// unlockAttempts is how often the master password is asked for before
+	// an unlock prompt is dismissed.
+	unlockAttempts = 3
+)
+
+const (
+	ifaceService        = "org.freedesktop.Secret.Service"
+	ifaceCollection     = "org.freedesktop.Secret.Collection"
+	ifaceItem           = "org.freedesktop.Secret.Item"
+	ifaceSession        = "org.freedesktop.Secret.Session"
+	ifacePrompt         = "org.freedesktop.Secret.Prompt"
+	ifaceProperties     = "org.freedesktop.DBus.Properties"
+	ifaceIntrospectable = "org.freedesktop.DBus.Introspectable"
+)
+
+// ErrNameTaken is returned by Export when another Secret Service, like
+// gnome-keyring, already runs on the bus.
+var ErrNameTaken = errors.New("another Secret Service already owns " + BusName)
+
+// PasswordFunc asks for the master password to unlock the vault. attempt
+// counts the wrong passwords entered before.
+type PasswordFunc func(attempt int) (string, error)
+
+// entry is what the service remembers of an item: the item without its
+// secrets, so that items stay searchable while the vault is locked and can
+// be unlocked on demand.
+type entry struct {
+	item  bw.Item
+	attrs map[string]string
+}
+
+func newEntry(item bw.Item) entry {
+	var fields []bw.ItemField
+	for _, f := range item.Fields {
+		if f.Type == bw.FieldText {
+			fields = append(fields, f)
+		}
+	}
+	return entry{
+		item: bw.Item{
+			ID:           item.ID,
+			Type:         item.Type,
+			Name:         item.Name,
+			Login:        bw.ItemLogin{Username: item.Login.Username, URIs: item.Login.URIs},
+			Fields:       fields,
+			RevisionDate: item.RevisionDate,
+			CreationDate: item.CreationDate,
+		},
+		attrs: item.SecretAttributes(),
+	}
+}
+
+// Service is the Secret Service for one vault.
+type Service struct {
+	conn     *dbus.Conn
+	password PasswordFunc
+	started  time.Time
+	// unlockMu makes concurrent unlock prompts take turns.
+	unlockMu sync.Mutex
+
+	// mu serializes the use of bwm, which is not safe for concurrent use,
+	// and guards the fields below.
+	mu       sync.Mutex
+	bwm      *bw.Manager
+	locked   bool
+	items    map[dbus.ObjectPath]entry
+	sessions map[dbus.ObjectPath]*session
+	serial   int
+}
+
+// New creates a Service for the vault managed by bwm. The items must have
+// been loaded.
+func New(bwm *bw.Manager, password PasswordFunc) *Service {
+	return &Service{
+		bwm:      bwm,
+		password: password,
+		started:  time.Now(),
+		items:    make(map[dbus.ObjectPath]entry),
+		sessions: make(map[dbus.ObjectPath]*session),
+	}
+}
+
+// Export puts the service on conn and claims BusName.
+func (s *Service) Export(conn *dbus.Conn) error {
+	s.mu.Lock()
+	s.conn = conn
+	props := &properties{s: s, iface: ifaceService, get: s.serviceProperties}
+	err := s.export(servicePath, ifaceService, serviceObject{s}, props, s.introspectService)
+	if err == nil {
+		err = s.exportCollection()
+	}
+	if err == nil {
+		s.locked = s.bwm.VaultStatus.Status != bw.Unlocked
+		err = s.updateItems()
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to export Secret Service: %w", err)
+	}
+	// sessions end with the client that opened them
+	err = conn.AddMatchSignal(
+		dbus.WithMatchInterface("org.freedesktop.DBus"),
+		dbus.WithMatchMember("NameOwnerChanged"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to export Secret Service: %w", err)
+	}
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+	go s.watchClients(signals)
+	reply, err := conn.RequestName(BusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("failed to request %s: %w", BusName, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return ErrNameTaken
+	}
+	return nil
+}
+
+func (s *Service) watchClients(signals <-chan *dbus.Signal) {
+	for sig := range signals {
+		if sig.Name != "org.freedesktop.DBus.NameOwnerChanged" || len(sig.Body) != 3 {
+			continue
+		}
+		name, _ := sig.Body[0].(string)
+		newOwner, _ := sig.Body[2].(string)
+		if newOwner != "" || !strings.HasPrefix(name, ":") {
+			continue
+		}
+		s.mu.Lock()
+		for path, sess := range s.sessions {
+			if sess.owner == name {
+				s.closeSession(path)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Refresh checks whether the vault is still unlocked, locking the
+// collection if it is not. With reload, the items are also listed again.
+func (s *Service) Refresh(reload bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.bwm.UpdateStatus(); err != nil {
+		return err
+	}
+	if s.bwm.VaultStatus.Status != bw.Unlocked {
+		s.setLocked(true)
+		return nil
+	}
+	if !reload {
+		return nil
+	}
+	if err := s.bwm.UpdateList(); err != nil {
+		return err
+	}
+	return s.updateItems()
+}
+
+// Len returns the number of items in the collection.
+func (s *Service) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// itemPath returns the object path of the item with the given ID. Object
+// paths only allow letters, digits and underscores.
+func itemPath(id string) dbus.ObjectPath {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, id)
+	return collectionPath + "/" + dbus.ObjectPath(name)
+}
+
+// updateItems syncs the exported items with the ones in the vault. While the
+// vault is locked the known items are kept.
+func (s *Service) updateItems() error {
+	if s.locked {
+		return nil
+	}
+	vaultItems, err := s.bwm.GetList()
+	if err != nil {
+		return err
+	}
+	items := make(map[dbus.ObjectPath]entry, len(vaultItems))
+	for _, item := range vaultItems {
+		path := itemPath(item.ID)
+		e := newEntry(item)
+		items[path] = e
+		old, ok := s.items[path]
+		switch {
+		case !ok:
+			if err := s.exportItem(path); err != nil {
+				return err
+			}
+			s.emit(collectionPath, ifaceCollection+".ItemCreated", path)
+		case !old.item.RevisionDate.Equal(e.item.RevisionDate):
+			s.emit(collectionPath, ifaceCollection+".ItemChanged", path)
+		}
+	}
+	for path := range s.items {
+		if _, ok := items[path]; !ok {
+			s.unexport(path, ifaceItem)
+			s.emit(collectionPath, ifaceCollection+".ItemDeleted", path)
+		}
+	}
+	s.items = items
+	return nil
+}
+
+// setLocked records a change of the lock state and tells the clients.
+func (s *Service) setLocked(locked bool) {
+	if s.locked == locked {
+		return
+	}
+	s.locked = locked
+	for _, path := range []dbus.ObjectPath{collectionPath, defaultAliasPath} {
+		s.emit(path, ifaceProperties+".PropertiesChanged", ifaceCollection,
+			map[string]dbus.Variant{"Locked": dbus.MakeVariant(locked)}, []string{})
+	}
+	s.emit(servicePath, ifaceService+".CollectionChanged", collectionPath)
+}
+
+// unlock unlocks the vault with the master password.
+func (s *Service) unlock(password string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.locked {
+		return nil
+	}
+	if err := s.bwm.Unlock(password); err != nil {
+		return err
+	}
+	if err := s.bwm.UpdateList(); err != nil {
+		return err
+	}
+	s.setLocked(false)
+	return s.updateItems()
+}
+
+// lock locks the vault, which voids the session of every gobw process.
+func (s *Service) lock() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locked {
+		return nil
+	}
+	if err := s.bwm.Lock(); err != nil {
+		return err
+	}
+	s.setLocked(true)
+	return nil
+}
+
+// search returns the items having all of attrs.
+func (s *Service) search(attrs map[string]string) []dbus.ObjectPath {
+	paths := []dbus.ObjectPath{}
+	for path, e := range s.items {
+		if e.item.MatchesSecretAttributes(attrs) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return paths[i] < paths[j]
+	})
+	return paths
+}
+
+// vaultItem returns the vault item behind an exported item.
+func (s *Service) vaultItem(path dbus.ObjectPath) (bw.Item, *dbus.Error) {
+	e, ok := s.items[path]
+	if !ok {
+		return bw.Item{}, errNoSuchObject(path)
+	}
+	if s.locked {
+		return bw.Item{}, errIsLocked
+	}
+	items, err := s.bwm.GetList()
+	if err != nil {
+		return bw.Item{}, dbus.MakeFailedError(err)
+	}
+	for _, item := range items {
+		if item.ID == e.item.ID {
+			return item, nil
+		}
+	}
+	return bw.Item{}, errNoSuchObject(path)
+}
+
+// session returns a session opened by sender.
+func (s *Service) session(path dbus.ObjectPath, sender dbus.Sender) (*session, *dbus.Error) {
+	sess, ok := s.sessions[path]
+	if !ok || sess.owner != string(sender) {
+		return nil, errNoSession
+	}
+	return sess, nil
+}
+
+func (s *Service) openSession(sess *session) error {
+	if err := s.export(sess.path, ifaceSession, sessionObject{s, sess.path}, nil, introspect(ifaceSessionXML)); err != nil {
+		return err
+	}
+	s.sessions[sess.path] = sess
+	return nil
+}
+
+func (s *Service) closeSession(path dbus.ObjectPath) {
+	delete(s.sessions, path)
+	s.unexport(path, ifaceSession)
+}
+
+// nextPath returns a fresh object path for a session or prompt.
+func (s *Service) nextPath(prefix string) dbus.ObjectPath {
+	s.serial++
+	return dbus.ObjectPath(prefix + strconv.Itoa(s.serial))
+}
+
+func (s *Service) emit(path dbus.ObjectPath, name string, values ...any) {
+	// signals only fail once the connection is gone, which ends the
+	// service anyway
+	_ = s.conn.Emit(path, name, values...)
+}
+
+var (
+	errIsLocked  = dbus.NewError("org.freedesktop.Secret.Error.IsLocked", []any{"the vault is locked"})
+	errNoSession = dbus.NewError("org.freedesktop.Secret.Error.NoSession", []any{"no such session"})
+)
+
+func errNoSuchObject(path dbus.ObjectPath) *dbus.Error {
+	return dbus.NewError("org.freedesktop.Secret.Error.NoSuchObject", []any{fmt.Sprintf("no such object %s", path)})
+}
+
+func errNotSupported(msg string) *dbus.Error {
+	return dbus.NewError("org.freedesktop.DBus.Error.NotSupported", []any{msg})
+}
+
+// errEdit reports a failed change to an item, refusing changes to items the
+// service did not store.
+func errEdit(err error) *dbus.Error {
+	if errors.Is(err, bw.ErrNotSecretServiceItem) {
+		return dbus.NewError("org.freedesktop.DBus.Error.AccessDenied", []any{err.Error()})
+	}
+	return dbus.MakeFailedError(err)
+}
+
+// errReprompt refuses the secret of an item with master password re-prompt
+// when the master password was not given.
+func errReprompt(err error) *dbus.Error {
+	return dbus.NewError("org.freedesktop.DBus.Error.AccessDenied", []any{err.Error()})
+}
+
+func errInvalidArgs(msg string) *dbus.Error {
+	return dbus.NewError("org.freedesktop.DBus.Error.InvalidArgs", []any{msg})
+}
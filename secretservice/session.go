@@ -0,0 +1,115 @@
+package secretservice
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/godbus/dbus/v5"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Algorithms for transferring secrets that OpenSession accepts.
+const (
+	AlgorithmPlain = "plain"
+	AlgorithmDH    = "dh-ietf1024-sha256-aes128-cbc-pkcs7"
+)
+
+// dhPrime is the 1024 bit MODP group of RFC 2409 the DH algorithm is defined
+// with. Its generator is 2.
+var dhPrime, _ = new(big.Int).SetString(
+	"FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1"+
+		"29024E088A67CC74020BBEA63B139B22514A08798E3404DD"+
+		"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245"+
+		"E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE65381"+
+		"FFFFFFFFFFFFFFFF", 16)
+
+var errInvalidSecret = errors.New("invalid secret")
+
+// Secret is the Secret struct of the Secret Service API, (oayays) on the bus.
+type Secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// session holds what a client negotiated with OpenSession. A nil key means
+// secrets travel in plain text.
+type session struct {
+	path  dbus.ObjectPath
+	owner string
+	key   []byte
+}
+
+// newDHSession agrees on an AES key with a client that sent its public key.
+// It returns the session along with the service's public key.
+func newDHSession(path dbus.ObjectPath, owner string, clientPublic []byte) (*session, []byte, error) {
+	y := new(big.Int).SetBytes(clientPublic)
+	if y.Cmp(big.NewInt(1)) <= 0 || y.Cmp(new(big.Int).Sub(dhPrime, big.NewInt(1))) >= 0 {
+		return nil, nil, errors.New("invalid public key")
+	}
+	x, err := rand.Int(rand.Reader, dhPrime)
+	if err != nil {
+		return nil, nil, err
+	}
+	public := new(big.Int).Exp(big.NewInt(2), x, dhPrime)
+	shared := new(big.Int).Exp(y, x, dhPrime).FillBytes(make([]byte, 128))
+	key := make([]byte, aes.BlockSize)
+	_, err = io.ReadFull(hkdf.New(sha256.New, shared, nil, nil), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &session{path: path, owner: owner, key: key}, public.Bytes(), nil
+}
+
+// encrypt wraps value into a Secret for the client.
+func (s *session) encrypt(value []byte) (Secret, error) {
+	secret := Secret{Session: s.path, ContentType: "text/plain; charset=utf8"}
+	if s.key == nil {
+		secret.Value = value
+		return secret, nil
+	}
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return Secret{}, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return Secret{}, err
+	}
+	pad := aes.BlockSize - len(value)%aes.BlockSize
+	data := append(append([]byte(nil), value...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	secret.Parameters = iv
+	secret.Value = data
+	return secret, nil
+}
+
+// decrypt unwraps a Secret the client sent.
+func (s *session) decrypt(secret Secret) ([]byte, error) {
+	if s.key == nil {
+		return secret.Value, nil
+	}
+	if len(secret.Parameters) != aes.BlockSize || len(secret.Value) == 0 || len(secret.Value)%aes.BlockSize != 0 {
+		return nil, errInvalidSecret
+	}
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, len(secret.Value))
+	cipher.NewCBCDecrypter(block, secret.Parameters).CryptBlocks(data, secret.Value)
+	pad := int(data[len(data)-1])
+	if pad == 0 || pad > aes.BlockSize || !bytes.Equal(data[len(data)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, fmt.Errorf("%w: bad padding", errInvalidSecret)
+	}
+	return data[:len(data)-pad], nil
+}
@@ -0,0 +1,57 @@
+package sshagent
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// RuntimeDir returns the directory gobw keeps its sockets in:
+// $XDG_RUNTIME_DIR/gobw, or a per-user directory under the temporary
+// directory without a runtime directory.
+func RuntimeDir() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return filepath.Join(os.TempDir(), "gobw-"+strconv.Itoa(os.Getuid()))
+	}
+	return filepath.Join(dir, "gobw")
+}
+
+// DefaultSocketPath returns ssh-agent.sock in RuntimeDir.
+func DefaultSocketPath() string {
+	return filepath.Join(RuntimeDir(), "ssh-agent.sock")
+}
+
+// Listen creates a unix socket at path that only the current user can
+// connect to. Its directory is created private if missing and must be
+// private otherwise, and a stale socket left behind by an earlier agent is
+// replaced.
+func Listen(path string) (net.Listener, error) {
+	err := privateDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to check socket: %w", err)
+	case info.Mode()&fs.ModeSocket == 0:
+		return nil, fmt.Errorf("failed to listen on %s: file exists and is not a socket", path)
+	default:
+		// a socket nobody answers on is left over from an agent that died
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to listen on %s: another agent is running", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+	return listenPrivate(path)
+}
@@ -0,0 +1,33 @@
+//go:build !unix
+
+package sshagent
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// privateDir creates dir if missing. File modes and owners are not checked
+// where unix permissions do not exist.
+func privateDir(dir string) error {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	return nil
+}
+
+// CheckDir relies on the permissions of the user's directories where unix
+// permissions do not exist.
+func CheckDir(_ string) error {
+	return nil
+}
+
+func listenPrivate(path string) (net.Listener, error) {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	return l, nil
+}
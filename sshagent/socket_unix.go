@@ -0,0 +1,55 @@
+//go:build unix
+
+package sshagent
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// privateDir creates dir if missing and otherwise checks that it is a
+// directory of the current user that nobody else can use. Without the check,
+// another user could create a predictable directory like /tmp/gobw-1000
+// first and put their own socket in it.
+func privateDir(dir string) error {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	return CheckDir(dir)
+}
+
+// CheckDir returns an error unless dir is a directory, not a symlink, owned
+// by the current user with no permissions for anybody else. Clients check
+// the directory before trusting a socket in it.
+func CheckDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check socket directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("socket directory %s is not a directory", dir)
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("socket directory %s is not owned by the current user", dir)
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("socket directory %s is accessible to other users (mode %#o), it must be 0700", dir, perm)
+	}
+	return nil
+}
+
+// listenPrivate creates the socket under a umask that leaves it to the
+// current user from the start.
+func listenPrivate(path string) (net.Listener, error) {
+	old := syscall.Umask(0o177)
+	l, err := net.Listen("unix", path)
+	syscall.Umask(old)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	return l, nil
+}
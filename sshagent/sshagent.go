@@ -0,0 +1,198 @@
+// Package sshagent serves private keys over the ssh-agent protocol. The keys
+// are read-only: clients may list them and sign with them, but not add,
+// remove or lock them.
+package sshagent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var (
+	ErrReadOnly   = errors.New("agent keys are managed by the vault")
+	ErrKeyUnknown = errors.New("key not found")
+	ErrDenied     = errors.New("signature request denied")
+)
+
+// Key is a private key to serve.
+type Key struct {
+	// Key is a private key as returned by ssh.ParseRawPrivateKey.
+	Key     any
+	Comment string
+}
+
+// ConfirmFunc asks whether the key may sign a request.
+type ConfirmFunc func(key *agent.Key) bool
+
+type signer struct {
+	signer  ssh.Signer
+	comment string
+}
+
+// Agent implements agent.ExtendedAgent over a fixed set of keys.
+type Agent struct {
+	mu      sync.Mutex
+	signers []signer
+	confirm ConfirmFunc
+}
+
+// New returns an agent without keys. If confirm is not nil, it is called
+// before every signature.
+func New(confirm ConfirmFunc) *Agent {
+	return &Agent{confirm: confirm}
+}
+
+// SetKeys replaces the keys served.
+func (a *Agent) SetKeys(keys []Key) error {
+	signers := make([]signer, 0, len(keys))
+	for _, k := range keys {
+		s, err := ssh.NewSignerFromKey(k.Key)
+		if err != nil {
+			return fmt.Errorf("failed to use key %q: %w", k.Comment, err)
+		}
+		signers = append(signers, signer{s, k.Comment})
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.signers = signers
+	return nil
+}
+
+// Clear drops all keys.
+func (a *Agent) Clear() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.signers = nil
+}
+
+// Len returns the number of keys served.
+func (a *Agent) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.signers)
+}
+
+// Serve answers agent requests on connections accepted from l until it is
+// closed.
+func (a *Agent) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			_ = agent.ServeAgent(a, conn)
+		}()
+	}
+}
+
+func (a *Agent) List() ([]*agent.Key, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	keys := make([]*agent.Key, 0, len(a.signers))
+	for _, s := range a.signers {
+		pub := s.signer.PublicKey()
+		keys = append(keys, &agent.Key{
+			Format:  pub.Type(),
+			Blob:    pub.Marshal(),
+			Comment: s.comment,
+		})
+	}
+	return keys, nil
+}
+
+func (a *Agent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+func (a *Agent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	s, ok := a.find(key)
+	if !ok {
+		return nil, ErrKeyUnknown
+	}
+	// confirming may take a while, so it happens without holding the lock
+	if a.confirm != nil {
+		pub := s.signer.PublicKey()
+		if !a.confirm(&agent.Key{Format: pub.Type(), Blob: pub.Marshal(), Comment: s.comment}) {
+			return nil, ErrDenied
+		}
+		// the keys may have been dropped in the meantime
+		if _, ok := a.find(key); !ok {
+			return nil, ErrKeyUnknown
+		}
+	}
+	if flags == 0 {
+		return s.signer.Sign(rand.Reader, data)
+	}
+	algorithmSigner, ok := s.signer.(ssh.AlgorithmSigner)
+	if !ok {
+		return nil, fmt.Errorf("key %q does not support signature flags %d", s.comment, flags)
+	}
+	var algorithm string
+	switch flags {
+	case agent.SignatureFlagRsaSha256:
+		algorithm = ssh.KeyAlgoRSASHA256
+	case agent.SignatureFlagRsaSha512:
+		algorithm = ssh.KeyAlgoRSASHA512
+	default:
+		return nil, fmt.Errorf("unsupported signature flags %d", flags)
+	}
+	return algorithmSigner.SignWithAlgorithm(rand.Reader, data, algorithm)
+}
+
+func (a *Agent) find(key ssh.PublicKey) (signer, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	wanted := key.Marshal()
+	for _, s := range a.signers {
+		if bytes.Equal(s.signer.PublicKey().Marshal(), wanted) {
+			return s, true
+		}
+	}
+	return signer{}, false
+}
+
+func (a *Agent) Signers() ([]ssh.Signer, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	signers := make([]ssh.Signer, 0, len(a.signers))
+	for _, s := range a.signers {
+		signers = append(signers, s.signer)
+	}
+	return signers, nil
+}
+
+func (a *Agent) Add(key agent.AddedKey) error {
+	return ErrReadOnly
+}
+
+func (a *Agent) Remove(key ssh.PublicKey) error {
+	return ErrReadOnly
+}
+
+func (a *Agent) RemoveAll() error {
+	return ErrReadOnly
+}
+
+func (a *Agent) Lock(passphrase []byte) error {
+	return ErrReadOnly
+}
+
+func (a *Agent) Unlock(passphrase []byte) error {
+	return ErrReadOnly
+}
+
+func (a *Agent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
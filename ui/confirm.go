@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Confirm is a standalone yes/no prompt, used by commands running outside
+// the main model such as the SSH agent asking before each signature.
+type Confirm struct {
+	title    string
+	question string
+	allow    bool
+	answered bool
+}
+
+func NewConfirm(title string, question string) Confirm {
+	return Confirm{title: title, question: question}
+}
+
+// Allowed reports whether the user allowed the request. Quitting without an
+// answer denies it.
+func (m Confirm) Allowed() bool {
+	return m.answered && m.allow
+}
+
+func (m Confirm) Init() tea.Cmd {
+	return nil
+}
+
+func (m Confirm) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.String() {
+	case "left", "right", "tab", "shift+tab", "h", "l":
+		m.allow = !m.allow
+	case "y":
+		m.allow = true
+		m.answered = true
+		return m, tea.Quit
+	case "n", "esc", "ctrl+c", "q":
+		m.allow = false
+		m.answered = true
+		return m, tea.Quit
+	case "enter":
+		m.answered = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m Confirm) View() string {
+	if m.answered {
+		return ""
+	}
+	allow, deny := blurredStyle.Render("[ Allow ]"), focusedStyle.Render("[ Deny ]")
+	if m.allow {
+		allow, deny = focusedStyle.Render("[ Allow ]"), blurredStyle.Render("[ Deny ]")
+	}
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(fmt.Sprintf(" %s %s ", logo, m.title)))
+	b.WriteString("\n\n")
+	b.WriteString(m.question)
+	b.WriteString("\n\n")
+	fmt.Fprintf(&b, "%s  %s\n\n", allow, deny)
+	b.WriteString(mutedStyle.Render("←/→ choose • y allow • n/esc deny • enter confirm"))
+	return docStyle.Render(b.String())
+}
@@ -0,0 +1,296 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+type ShowExport struct{}
+
+func SelectShowExport() tea.Cmd {
+	return func() tea.Msg {
+		return ShowExport{}
+	}
+}
+
+type exportDone struct {
+	path   string
+	result bw.ExportResult
+	err    error
+}
+
+// runExport re-checks the master password before writing anything. The
+// status is copied here, on the main loop.
+func runExport(bwm *bw.Manager, masterPassword string, path string, opts bw.ExportOptions) tea.Cmd {
+	status := bwm.VaultStatus
+	return func() tea.Msg {
+		err := bw.CheckPassword(status, masterPassword)
+		if err != nil {
+			return exportDone{err: err}
+		}
+		opts.MasterPassword = masterPassword
+		result, err := bwm.ExportFile(path, false, opts)
+		return exportDone{path, result, err}
+	}
+}
+
+// exportField identifies a row of the export form.
+type exportField int
+
+const (
+	exportFormatField exportField = iota
+	exportTypeField
+	exportFolderField
+	exportOrgField
+	exportPathField
+	exportMasterPasswordField
+	exportFilePasswordField
+	exportConfirmPasswordField
+	exportSubmitField
+)
+
+func exportFormats() []bw.ExportFormat {
+	return []bw.ExportFormat{bw.ExportJSON, bw.ExportCSV, bw.ExportEncryptedJSON, bw.ExportKDBX}
+}
+
+var errExportPasswordMismatch = errors.New("file passwords do not match")
+
+// Export is the form for exporting the vault to a file.
+type Export struct {
+	bwm    *bw.Manager
+	focus  exportField
+	format int
+	// itemType indexes listTabs, where 0 means all types.
+	itemType int
+	inputs   map[exportField]*textinput.Model
+	text     string
+	busy     bool
+}
+
+func NewExport(bwm *bw.Manager) Export {
+	m := Export{
+		bwm:    bwm,
+		inputs: make(map[exportField]*textinput.Model),
+		text:   "Export the vault. Exports hold your secrets unencrypted unless encrypted JSON or KeePass is chosen.",
+	}
+	placeholders := map[exportField]string{
+		exportFolderField:          "Folder (all)",
+		exportOrgField:             "Organization (all)",
+		exportPathField:            "File",
+		exportMasterPasswordField:  "Master password",
+		exportFilePasswordField:    "File password",
+		exportConfirmPasswordField: "Repeat file password",
+	}
+	for field, placeholder := range placeholders {
+		t := textinput.New()
+		t.CursorStyle = cursorStyle
+		t.Placeholder = placeholder
+		if field >= exportMasterPasswordField {
+			t.EchoMode = textinput.EchoPassword
+			t.EchoCharacter = '•'
+		}
+		m.inputs[field] = &t
+	}
+	m.inputs[exportPathField].SetValue(bw.DefaultExportPath(bw.ExportJSON, time.Now()))
+	return m
+}
+
+func (m Export) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m Export) selectedFormat() bw.ExportFormat {
+	return exportFormats()[m.format]
+}
+
+// fields returns the rows of the form in order. The file password is only
+// asked for encrypted exports.
+func (m Export) fields() []exportField {
+	fields := []exportField{
+		exportFormatField, exportTypeField, exportFolderField, exportOrgField,
+		exportPathField, exportMasterPasswordField,
+	}
+	if m.selectedFormat().IsEncrypted() {
+		fields = append(fields, exportFilePasswordField, exportConfirmPasswordField)
+	}
+	return append(fields, exportSubmitField)
+}
+
+func (m *Export) moveFocus(delta int) tea.Cmd {
+	fields := m.fields()
+	i := 0
+	for j, f := range fields {
+		if f == m.focus {
+			i = j
+		}
+	}
+	m.focus = fields[(i+delta+len(fields))%len(fields)]
+	var cmd tea.Cmd
+	for field, input := range m.inputs {
+		if field == m.focus {
+			cmd = input.Focus()
+			input.PromptStyle = focusedStyle
+			input.TextStyle = focusedStyle
+			continue
+		}
+		input.Blur()
+		input.PromptStyle = noStyle
+		input.TextStyle = noStyle
+	}
+	return cmd
+}
+
+// cycle changes the format or type selector under focus.
+func (m *Export) cycle(delta int) {
+	switch m.focus {
+	case exportFormatField:
+		n := len(exportFormats())
+		m.format = (m.format + delta + n) % n
+		// keep the extension of the suggested file name in line
+		path := m.inputs[exportPathField]
+		if strings.HasPrefix(path.Value(), "bitwarden_export_") {
+			base := path.Value()
+			if i := strings.LastIndex(base, "."); i >= 0 {
+				base = base[:i]
+			}
+			path.SetValue(base + "." + m.selectedFormat().Extension())
+		}
+	case exportTypeField:
+		n := len(listTabs())
+		m.itemType = (m.itemType + delta + n) % n
+	}
+}
+
+func (m Export) submit() (Export, tea.Cmd) {
+	opts := bw.ExportOptions{
+		Format: m.selectedFormat(),
+		Filter: bw.ExportFilter{
+			Folder:       strings.TrimSpace(m.inputs[exportFolderField].Value()),
+			Organization: strings.TrimSpace(m.inputs[exportOrgField].Value()),
+			Type:         listTabs()[m.itemType],
+		},
+	}
+	path := strings.TrimSpace(m.inputs[exportPathField].Value())
+	if path == "" {
+		m.text = "Please choose a file to export to."
+		return m, nil
+	}
+	if opts.Format.IsEncrypted() {
+		opts.Password = m.inputs[exportFilePasswordField].Value()
+		if opts.Password == "" {
+			m.text = bw.ErrExportPassword.Error()
+			return m, nil
+		}
+		if opts.Password != m.inputs[exportConfirmPasswordField].Value() {
+			m.text = errExportPasswordMismatch.Error()
+			return m, nil
+		}
+	}
+	m.busy = true
+	m.text = "Exporting. Please wait"
+	return m, runExport(m.bwm, m.inputs[exportMasterPasswordField].Value(), path, opts)
+}
+
+func (m Export) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case ShowExport:
+		m = NewExport(m.bwm)
+		return m, m.moveFocus(0)
+	case exportDone:
+		m.busy = false
+		for _, field := range []exportField{exportMasterPasswordField, exportFilePasswordField, exportConfirmPasswordField} {
+			m.inputs[field].SetValue("")
+		}
+		if msg.err != nil {
+			m.text = fmt.Sprintf("Export failed: %s", msg.err)
+			return m, nil
+		}
+		m.text = fmt.Sprintf("Exported %d items to %s.", msg.result.Exported, msg.path)
+		if msg.result.Skipped > 0 {
+			m.text += fmt.Sprintf(" Skipped %d card, identity and SSH key items, which CSV cannot hold.", msg.result.Skipped)
+		}
+		return m, nil
+	case tea.KeyMsg:
+		if m.busy {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			return m, SelectLoadingDone()
+		case "tab", "down":
+			return m, m.moveFocus(1)
+		case "shift+tab", "up":
+			return m, m.moveFocus(-1)
+		case "left", "right":
+			if m.focus == exportFormatField || m.focus == exportTypeField {
+				delta := 1
+				if msg.String() == "left" {
+					delta = -1
+				}
+				m.cycle(delta)
+				return m, nil
+			}
+		case "enter":
+			if m.focus == exportSubmitField {
+				return m.submit()
+			}
+			return m, m.moveFocus(1)
+		}
+	}
+
+	cmds := make([]tea.Cmd, 0, len(m.inputs))
+	for _, input := range m.inputs {
+		var cmd tea.Cmd
+		*input, cmd = input.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m Export) selector(field exportField, label string, value string) string {
+	style := blurredStyle
+	if m.focus == field {
+		style = focusedStyle
+	}
+	return style.Render(fmt.Sprintf("%s: ‹ %s ›", label, value))
+}
+
+func (m Export) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(fmt.Sprintf(" %s Export ", logo)))
+	b.WriteString("\n\n")
+	b.WriteString(m.text)
+	b.WriteString("\n\n")
+	for _, field := range m.fields() {
+		switch field {
+		case exportFormatField:
+			b.WriteString(m.selector(field, "Format", string(m.selectedFormat())))
+		case exportTypeField:
+			b.WriteString(m.selector(field, "Type", tabLabel(listTabs()[m.itemType])))
+		case exportSubmitField:
+			button := &blurredButton
+			if m.focus == exportSubmitField {
+				button = &focusedButton
+			}
+			fmt.Fprintf(&b, "\n%s\n\n", *button)
+			continue
+		default:
+			b.WriteString(m.inputs[field].View())
+		}
+		b.WriteRune('\n')
+	}
+	b.WriteString(mutedStyle.Render("tab/↑/↓ move • ←/→ change • esc back"))
+	return docStyle.Render(b.String())
+}
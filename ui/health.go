@@ -0,0 +1,379 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/config"
+)
+
+type ShowHealth struct{}
+
+func SelectShowHealth() tea.Cmd {
+	return func() tea.Msg {
+		return ShowHealth{}
+	}
+}
+
+type healthReportDone struct {
+	report *bw.HealthReport
+	err    error
+}
+
+func analyseHealth(bwm *bw.Manager, opts bw.HealthOptions) tea.Cmd {
+	return func() tea.Msg {
+		report, err := bwm.HealthReport(opts)
+		return healthReportDone{report, err}
+	}
+}
+
+// breachCheckDone carries a report including breached passwords. Unlike
+// healthReportDone a failure keeps the current report on screen.
+type breachCheckDone struct {
+	report *bw.HealthReport
+	err    error
+}
+
+// checkBreaches runs the analysis again with breached passwords included.
+func checkBreaches(bwm *bw.Manager, opts bw.HealthOptions, apiURL string, hashFile string) tea.Cmd {
+	return func() tea.Msg {
+		source, err := bw.OpenBreachSource(apiURL, hashFile)
+		if err != nil {
+			return breachCheckDone{err: err}
+		}
+		defer source.Close()
+		opts.Breaches = source
+		report, err := bwm.HealthReport(opts)
+		return breachCheckDone{report, err}
+	}
+}
+
+type healthCategory int
+
+const (
+	healthAll healthCategory = iota
+	healthBreached
+	healthWeak
+	healthReused
+	healthOld
+	healthInsecure
+	healthTwoFactor
+)
+
+func healthCategories() []healthCategory {
+	return []healthCategory{healthAll, healthBreached, healthWeak, healthReused, healthOld, healthInsecure, healthTwoFactor}
+}
+
+func (c healthCategory) String() string {
+	switch c {
+	case healthBreached:
+		return "Breached"
+	case healthWeak:
+		return "Weak"
+	case healthReused:
+		return "Reused"
+	case healthOld:
+		return "Old"
+	case healthInsecure:
+		return "Unsecured URIs"
+	case healthTwoFactor:
+		return "No 2FA"
+	default:
+		return "All"
+	}
+}
+
+type healthFinding struct {
+	category healthCategory
+	item     bw.HealthItem
+	detail   string
+}
+
+func (f healthFinding) Title() string {
+	return fmt.Sprintf("%s · %s", f.item.Name, strings.ToLower(f.category.String()))
+}
+func (f healthFinding) Description() string { return f.detail }
+func (f healthFinding) FilterValue() string { return f.item.Name }
+
+func healthFindings(report *bw.HealthReport) []healthFinding {
+	var findings []healthFinding
+	for _, f := range report.Breached {
+		findings = append(findings, healthFinding{
+			category: healthBreached,
+			item:     f.Item,
+			detail:   fmt.Sprintf("seen %d times in data breaches", f.Count),
+		})
+	}
+	for _, f := range report.Weak {
+		findings = append(findings, healthFinding{
+			category: healthWeak,
+			item:     f.Item,
+			detail:   fmt.Sprintf("strength %d/4, cracked in %s", f.Score, f.CrackTime),
+		})
+	}
+	for _, f := range report.Reused {
+		for i, item := range f.Items {
+			others := make([]string, 0, len(f.Items)-1)
+			for j, other := range f.Items {
+				if i != j {
+					others = append(others, other.Name)
+				}
+			}
+			findings = append(findings, healthFinding{
+				category: healthReused,
+				item:     item,
+				detail:   "same password as " + strings.Join(others, ", "),
+			})
+		}
+	}
+	for _, f := range report.Old {
+		findings = append(findings, healthFinding{
+			category: healthOld,
+			item:     f.Item,
+			detail:   fmt.Sprintf("password is %d days old (%s)", f.AgeDays, f.PasswordDate.Format("2006-01-02")),
+		})
+	}
+	for _, f := range report.InsecureURIs {
+		findings = append(findings, healthFinding{
+			category: healthInsecure,
+			item:     f.Item,
+			detail:   f.URI,
+		})
+	}
+	for _, f := range report.MissingTwoFactor {
+		findings = append(findings, healthFinding{
+			category: healthTwoFactor,
+			item:     f.Item,
+			detail:   f.Domain + " supports TOTP but the item has none",
+		})
+	}
+	return findings
+}
+
+type healthKeyBindings struct {
+	View     key.Binding
+	NextTab  key.Binding
+	PrevTab  key.Binding
+	Export   key.Binding
+	Breaches key.Binding
+	Back     key.Binding
+}
+
+func newHealthKeyBindings() healthKeyBindings {
+	return healthKeyBindings{
+		View: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("Enter", "view item"),
+		),
+		NextTab: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "next category"),
+		),
+		PrevTab: key.NewBinding(
+			key.WithKeys("shift+tab"),
+			key.WithHelp("shift+tab", "previous category"),
+		),
+		Export: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "export JSON"),
+		),
+		Breaches: key.NewBinding(
+			key.WithKeys("b"),
+			key.WithHelp("b", "check breaches"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("q", "esc"),
+			key.WithHelp("q", "back"),
+		),
+	}
+}
+
+type Health struct {
+	bwm  *bw.Manager
+	opts bw.HealthOptions
+	// breachAPIURL and breachHashFile locate the breach source checked on
+	// request.
+	breachAPIURL   string
+	breachHashFile string
+	report         *bw.HealthReport
+	findings       []healthFinding
+	list           list.Model
+	keys           healthKeyBindings
+	tab            int
+	err            error
+}
+
+func NewHealth(h int, v int, bwm *bw.Manager, opts bw.HealthOptions, cfg *config.Config) Health {
+	d := list.NewDefaultDelegate()
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.Foreground(selectedColor).BorderLeftForeground(selectedColor)
+	d.Styles.SelectedDesc = d.Styles.SelectedTitle.Copy()
+	width, height := docStyle.GetFrameSize()
+	l := list.New(nil, d, h-width, v-height-tabBarHeight)
+	l.Title = fmt.Sprintf(" %s Vault Health ", logo)
+	l.Styles.Title = titleStyle
+	l.SetStatusBarItemName("finding", "findings")
+	// the list re-enables its quit binding on every update, so it is
+	// unbound instead of disabled to leave q for going back.
+	l.KeyMap.Quit = key.NewBinding()
+	keys := newHealthKeyBindings()
+	l.AdditionalFullHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.View, keys.NextTab, keys.PrevTab, keys.Breaches, keys.Export, keys.Back}
+	}
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.View, keys.Export, keys.Back}
+	}
+	return Health{
+		bwm:            bwm,
+		opts:           opts,
+		breachAPIURL:   cfg.BreachAPIURL,
+		breachHashFile: cfg.BreachHashFile,
+		list:           l,
+		keys:           keys,
+	}
+}
+
+func (m Health) Init() tea.Cmd {
+	return nil
+}
+
+func (m *Health) refresh() tea.Cmd {
+	category := healthCategories()[m.tab]
+	items := make([]list.Item, 0, len(m.findings))
+	for _, f := range m.findings {
+		if category == healthAll || f.category == category {
+			items = append(items, f)
+		}
+	}
+	return m.list.SetItems(items)
+}
+
+// export writes the report to a 0600 JSON file in the working directory.
+func (m Health) export() (string, error) {
+	data, err := json.MarshalIndent(m.report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := fmt.Sprintf("gobw-health-%s.json", m.report.Generated.Format("20060102-150405"))
+	err = os.WriteFile(path, data, 0o600)
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (m Health) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case ShowHealth:
+		m.report = nil
+		m.err = nil
+		return m, analyseHealth(m.bwm, m.opts)
+	case healthReportDone:
+		m.report = msg.report
+		m.err = msg.err
+		if m.err != nil {
+			return m, nil
+		}
+		m.findings = healthFindings(m.report)
+		return m, m.refresh()
+	case breachCheckDone:
+		if msg.err != nil {
+			return m, m.list.NewStatusMessage(fmt.Sprintf("breach check failed: %s", msg.err))
+		}
+		m.report = msg.report
+		m.findings = healthFindings(m.report)
+		status := fmt.Sprintf("%d breached passwords", len(m.report.Breached))
+		return m, tea.Batch(m.refresh(), m.list.NewStatusMessage(status))
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+		switch {
+		case key.Matches(msg, m.keys.Back) && m.list.FilterState() == list.Unfiltered:
+			return m, SelectLoadingDone()
+		case m.report == nil:
+			return m, nil
+		case key.Matches(msg, m.keys.View):
+			finding, ok := m.list.SelectedItem().(healthFinding)
+			if !ok {
+				return m, nil
+			}
+			items, err := m.bwm.GetList()
+			if err != nil {
+				return m, m.list.NewStatusMessage(err.Error())
+			}
+			for _, item := range items {
+				if item.ID == finding.item.ID {
+					return m, SelectListSelectedEntry(NewBWListItem(item))
+				}
+			}
+			return m, m.list.NewStatusMessage("item no longer exists")
+		case key.Matches(msg, m.keys.Breaches):
+			source := "breach API"
+			if m.breachHashFile != "" {
+				source = "hash file"
+			}
+			return m, tea.Batch(
+				m.list.NewStatusMessage("checking passwords against the "+source),
+				checkBreaches(m.bwm, m.opts, m.breachAPIURL, m.breachHashFile),
+			)
+		case key.Matches(msg, m.keys.NextTab):
+			m.tab = (m.tab + 1) % len(healthCategories())
+			return m, m.refresh()
+		case key.Matches(msg, m.keys.PrevTab):
+			m.tab = (m.tab + len(healthCategories()) - 1) % len(healthCategories())
+			return m, m.refresh()
+		case key.Matches(msg, m.keys.Export):
+			path, err := m.export()
+			if err != nil {
+				return m, m.list.NewStatusMessage(fmt.Sprintf("export failed: %s", err))
+			}
+			return m, m.list.NewStatusMessage("exported to " + path)
+		}
+	case tea.WindowSizeMsg:
+		m.list.SetSize(msg.Width, msg.Height-tabBarHeight)
+		return m, tea.ClearScreen
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m Health) tabBar() string {
+	counts := make(map[healthCategory]int)
+	for _, f := range m.findings {
+		counts[healthAll]++
+		counts[f.category]++
+	}
+	tabs := make([]string, 0, len(healthCategories()))
+	for i, c := range healthCategories() {
+		label := fmt.Sprintf(" %s %d ", c, counts[c])
+		if i == m.tab {
+			tabs = append(tabs, activeTabStyle.Render(label))
+		} else {
+			tabs = append(tabs, tabStyle.Render(label))
+		}
+	}
+	return strings.Join(tabs, mutedStyle.Render("│"))
+}
+
+func (m Health) View() string {
+	if m.err != nil {
+		return docStyle.Render(fmt.Sprintf("%s\n\n Failed to analyse vault: %s\n\n Press q to go back.",
+			titleStyle.Render(fmt.Sprintf(" %s Vault Health ", logo)), m.err))
+	}
+	if m.report == nil {
+		return docStyle.Render(titleStyle.Render(fmt.Sprintf(" %s Vault Health ", logo)) + "\n\n Analysing vault. Please wait\n\n")
+	}
+	return docStyle.Render(lipgloss.JoinVertical(lipgloss.Left, m.tabBar(), "", m.list.View()))
+}
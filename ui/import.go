@@ -0,0 +1,392 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+type ShowImport struct{}
+
+func SelectShowImport() tea.Cmd {
+	return func() tea.Msg {
+		return ShowImport{}
+	}
+}
+
+type importRead struct {
+	items []bw.ImportedItem
+	err   error
+}
+
+type importDone struct {
+	result bw.ImportResult
+	err    error
+}
+
+func readImport(bwm *bw.Manager, format bw.ImportFormat, path string, password string) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		if format == "" {
+			format, err = bw.DetectImportFormat(path)
+			if err != nil {
+				return importRead{err: err}
+			}
+		}
+		items, err := bw.ReadImport(format, path, password)
+		if err != nil {
+			return importRead{err: err}
+		}
+		bwm.MarkDuplicates(items)
+		return importRead{items: items}
+	}
+}
+
+func runImport(bwm *bw.Manager, items []bw.ImportedItem, opts bw.ImportOptions) tea.Cmd {
+	return func() tea.Msg {
+		result, err := bwm.Import(items, opts)
+		return importDone{result, err}
+	}
+}
+
+// importField identifies a row of the import form.
+type importField int
+
+const (
+	importPathField importField = iota
+	importFormatField
+	importFolderField
+	importPasswordField
+	importSubmitField
+)
+
+// importFormats are the choices of the format selector, where the empty
+// format means guessing it from the file.
+func importFormats() []bw.ImportFormat {
+	return append([]bw.ImportFormat{""}, bw.ImportFormats()...)
+}
+
+// importPreviewRows is how many items the preview shows at once when the
+// terminal size is unknown.
+const importPreviewRows = 15
+
+// Import is the import wizard: a form choosing the file, then a preview of
+// the items in it where duplicates of vault items start out unchecked.
+type Import struct {
+	bwm    *bw.Manager
+	focus  importField
+	format int
+	inputs map[importField]*textinput.Model
+	text   string
+	busy   bool
+	// the preview step is shown while items is not nil
+	items    []bw.ImportedItem
+	selected []bool
+	cursor   int
+	height   int
+}
+
+func NewImport(bwm *bw.Manager) Import {
+	m := Import{
+		bwm:    bwm,
+		inputs: make(map[importField]*textinput.Model),
+		text:   "Import items from Bitwarden, LastPass, 1Password, Chrome, Firefox, pass or KeePass.",
+	}
+	placeholders := map[importField]string{
+		importPathField:     "File or password store directory",
+		importFolderField:   "Folder to import into (none)",
+		importPasswordField: "File password (if protected)",
+	}
+	for field, placeholder := range placeholders {
+		t := textinput.New()
+		t.CursorStyle = cursorStyle
+		t.Placeholder = placeholder
+		if field == importPasswordField {
+			t.EchoMode = textinput.EchoPassword
+			t.EchoCharacter = '•'
+		}
+		m.inputs[field] = &t
+	}
+	return m
+}
+
+func (m Import) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m Import) fields() []importField {
+	return []importField{importPathField, importFormatField, importFolderField, importPasswordField, importSubmitField}
+}
+
+func (m *Import) moveFocus(delta int) tea.Cmd {
+	fields := m.fields()
+	i := 0
+	for j, f := range fields {
+		if f == m.focus {
+			i = j
+		}
+	}
+	return m.setFocus(fields[(i+delta+len(fields))%len(fields)])
+}
+
+func (m *Import) setFocus(field importField) tea.Cmd {
+	m.focus = field
+	var cmd tea.Cmd
+	for field, input := range m.inputs {
+		if field == m.focus {
+			cmd = input.Focus()
+			input.PromptStyle = focusedStyle
+			input.TextStyle = focusedStyle
+			continue
+		}
+		input.Blur()
+		input.PromptStyle = noStyle
+		input.TextStyle = noStyle
+	}
+	return cmd
+}
+
+func (m Import) submit() (Import, tea.Cmd) {
+	path := strings.TrimSpace(m.inputs[importPathField].Value())
+	if path == "" {
+		m.text = "Please choose a file to import."
+		return m, nil
+	}
+	m.busy = true
+	m.text = "Reading. Please wait"
+	return m, readImport(m.bwm, importFormats()[m.format], path, m.inputs[importPasswordField].Value())
+}
+
+// chosen returns the checked items of the preview.
+func (m Import) chosen() []bw.ImportedItem {
+	items := make([]bw.ImportedItem, 0, len(m.items))
+	for i, item := range m.items {
+		if m.selected[i] {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (m Import) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case ShowImport:
+		height := m.height
+		m = NewImport(m.bwm)
+		m.height = height
+		return m, m.setFocus(importPathField)
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		return m, nil
+	case importRead:
+		m.busy = false
+		if errors.Is(msg.err, bw.ErrImportPassword) {
+			m.text = "The file is password protected. Please enter its password."
+			return m, m.setFocus(importPasswordField)
+		}
+		if msg.err != nil {
+			m.text = fmt.Sprintf("Import failed: %s", msg.err)
+			return m, nil
+		}
+		if len(msg.items) == 0 {
+			m.text = "The file holds no items."
+			return m, nil
+		}
+		m.items = msg.items
+		m.selected = make([]bool, len(msg.items))
+		duplicates := 0
+		for i, item := range msg.items {
+			m.selected[i] = item.DuplicateOf == ""
+			if item.DuplicateOf != "" {
+				duplicates++
+			}
+		}
+		m.cursor = 0
+		m.text = fmt.Sprintf("Found %d items, %d of them already in the vault.", len(msg.items), duplicates)
+		return m, nil
+	case importDone:
+		m.busy = false
+		m.items = nil
+		m.inputs[importPasswordField].SetValue("")
+		if msg.err != nil {
+			m.text = fmt.Sprintf("Import failed after %d items: %s", msg.result.Imported, msg.err)
+			return m, nil
+		}
+		m.text = fmt.Sprintf("Imported %d items, created %d folders and %d attachments.", msg.result.Imported, msg.result.Folders, msg.result.Attachments)
+		return m, nil
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		if m.busy {
+			return m, nil
+		}
+		if m.items != nil {
+			return m.updatePreview(msg)
+		}
+		switch msg.String() {
+		case "esc":
+			return m, SelectLoadingDone()
+		case "tab", "down":
+			return m, m.moveFocus(1)
+		case "shift+tab", "up":
+			return m, m.moveFocus(-1)
+		case "left", "right":
+			if m.focus == importFormatField {
+				delta := 1
+				if msg.String() == "left" {
+					delta = -1
+				}
+				n := len(importFormats())
+				m.format = (m.format + delta + n) % n
+				return m, nil
+			}
+		case "enter":
+			if m.focus == importSubmitField {
+				return m.submit()
+			}
+			return m, m.moveFocus(1)
+		}
+	}
+
+	if m.items != nil {
+		return m, nil
+	}
+	cmds := make([]tea.Cmd, 0, len(m.inputs))
+	for _, input := range m.inputs {
+		var cmd tea.Cmd
+		*input, cmd = input.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m Import) updatePreview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.items = nil
+		m.text = "Import cancelled."
+		return m, m.setFocus(importPathField)
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.items)-1 {
+			m.cursor++
+		}
+	case " ", "x":
+		m.selected[m.cursor] = !m.selected[m.cursor]
+	case "a":
+		// select everything, or nothing if everything already is
+		all := true
+		for _, s := range m.selected {
+			all = all && s
+		}
+		for i := range m.selected {
+			m.selected[i] = !all
+		}
+	case "enter":
+		items := m.chosen()
+		if len(items) == 0 {
+			m.text = "No items are selected."
+			return m, nil
+		}
+		m.busy = true
+		m.text = fmt.Sprintf("Importing %d items. Please wait", len(items))
+		opts := bw.ImportOptions{Folder: strings.TrimSpace(m.inputs[importFolderField].Value())}
+		return m, runImport(m.bwm, items, opts)
+	}
+	return m, nil
+}
+
+func (m Import) selector(field importField, label string, value string) string {
+	style := blurredStyle
+	if m.focus == field {
+		style = focusedStyle
+	}
+	return style.Render(fmt.Sprintf("%s: ‹ %s ›", label, value))
+}
+
+func (m Import) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(fmt.Sprintf(" %s Import ", logo)))
+	b.WriteString("\n\n")
+	b.WriteString(m.text)
+	b.WriteString("\n\n")
+	if m.items != nil {
+		b.WriteString(m.previewView())
+		return docStyle.Render(b.String())
+	}
+	for _, field := range m.fields() {
+		switch field {
+		case importFormatField:
+			format := string(importFormats()[m.format])
+			if format == "" {
+				format = "guess from file"
+			}
+			b.WriteString(m.selector(field, "Format", format))
+		case importSubmitField:
+			button := &blurredButton
+			if m.focus == importSubmitField {
+				button = &focusedButton
+			}
+			fmt.Fprintf(&b, "\n%s\n\n", *button)
+			continue
+		default:
+			b.WriteString(m.inputs[field].View())
+		}
+		b.WriteRune('\n')
+	}
+	b.WriteString(mutedStyle.Render("tab/↑/↓ move • ←/→ change • esc back"))
+	return docStyle.Render(b.String())
+}
+
+func (m Import) previewView() string {
+	var b strings.Builder
+	rows := importPreviewRows
+	if m.height > 12 {
+		rows = m.height - 12
+	}
+	start := 0
+	if m.cursor >= rows {
+		start = m.cursor - rows + 1
+	}
+	end := start + rows
+	if end > len(m.items) {
+		end = len(m.items)
+	}
+	folder := strings.TrimSpace(m.inputs[importFolderField].Value())
+	for i := start; i < end; i++ {
+		item := m.items[i]
+		check := "[ ]"
+		if m.selected[i] {
+			check = "[x]"
+		}
+		line := fmt.Sprintf("%s %s  %s", check, item.Item.Name, mutedStyle.Render(tabLabel(item.Item.Type)))
+		if path := bw.JoinFolder(folder, item.Folder); path != "" {
+			line += mutedStyle.Render("  " + path)
+		}
+		if item.DuplicateOf != "" {
+			name := item.DuplicateOf
+			if existing, err := m.bwm.FindItem(item.DuplicateOf); err == nil {
+				name = existing.Name
+			}
+			line += fmt.Sprintf("  (duplicate of %s)", name)
+		}
+		if i == m.cursor {
+			b.WriteString(selectedRowStyle.Render(line))
+		} else {
+			b.WriteString(rowStyle.Render(line))
+		}
+		b.WriteRune('\n')
+	}
+	fmt.Fprintf(&b, "\n%d of %d selected\n\n", len(m.chosen()), len(m.items))
+	b.WriteString(mutedStyle.Render("↑/↓ move • space toggle • a all/none • enter import • esc back"))
+	return b.String()
+}
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -8,13 +9,23 @@ import (
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/timer"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/config"
 )
 
+type ItemShowClosed struct{}
+
+func SelectItemShowClosed() tea.Cmd {
+	return func() tea.Msg {
+		return ItemShowClosed{}
+	}
+}
+
 type tickMsg time.Time
 
 func tick() tea.Msg {
@@ -22,12 +33,34 @@ func tick() tea.Msg {
 	return tickMsg{}
 }
 
+type repromptVerified struct {
+	item bw.Item
+	err  error
+}
+
+// verifyReprompt checks the master password entered for an item with master
+// password re-prompt offline, leaving the session alone, and has the agent
+// reveal the item if it came from one. The status is copied before the check
+// runs in the background.
+func verifyReprompt(bwm *bw.Manager, item bw.Item, pw string) tea.Cmd {
+	status := bwm.VaultStatus
+	return func() tea.Msg {
+		if err := bw.CheckPassword(status, pw); err != nil {
+			return repromptVerified{err: err}
+		}
+		item, err := bwm.RevealItem(item, pw)
+		return repromptVerified{item: item, err: err}
+	}
+}
+
 type itemShowKeyBindings struct {
 	CursorUp     key.Binding
 	CursorDown   key.Binding
 	Copy         key.Binding
 	CopyPassword key.Binding
 	CopyUsername key.Binding
+	Launch       key.Binding
+	LaunchTOTP   key.Binding
 	Quit         key.Binding
 }
 
@@ -53,6 +86,14 @@ func newItemShowKeyBindings() itemShowKeyBindings {
 			key.WithKeys("u"),
 			key.WithHelp("u", "copy username"),
 		),
+		Launch: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "open URI, copy password"),
+		),
+		LaunchTOTP: key.NewBinding(
+			key.WithKeys("O"),
+			key.WithHelp("O", "open URI, copy TOTP"),
+		),
 		Quit: key.NewBinding(
 			key.WithKeys("q", "esc"),
 			key.WithHelp("q", "quit"),
@@ -61,7 +102,7 @@ func newItemShowKeyBindings() itemShowKeyBindings {
 }
 
 func (k itemShowKeyBindings) ShortHelp() []key.Binding {
-	return []key.Binding{k.CursorUp, k.CursorDown, k.Copy, k.CopyUsername, k.CopyPassword, k.Quit}
+	return []key.Binding{k.CursorUp, k.CursorDown, k.Copy, k.CopyUsername, k.CopyPassword, k.Launch, k.LaunchTOTP, k.Quit}
 }
 
 func (k itemShowKeyBindings) FullHelp() [][]key.Binding {
@@ -69,16 +110,21 @@ func (k itemShowKeyBindings) FullHelp() [][]key.Binding {
 }
 
 type itemShowRow struct {
-	label       string
-	value       string
+	label string
+	value string
+	// detail is shown next to the value but not copied with it.
+	detail      string
 	hidden      bool
 	blockRender bool
-	marginTop   int
+	// uri marks the rows of login URIs, which can be launched.
+	uri       bool
+	marginTop int
 }
 
-func (row itemShowRow) render(selected bool) string {
+// render draws the row. Hidden values are only shown when reveal is set.
+func (row itemShowRow) render(selected bool, reveal bool) string {
 	value := row.value
-	if row.hidden && !selected && len(value) > 0 {
+	if row.hidden && !reveal && len(value) > 0 {
 		value = "•••"
 	}
 	spacer := "\t"
@@ -115,6 +161,9 @@ func (row itemShowRow) render(selected bool) string {
 		return marginTop + label + value + "\n"
 	}
 	intermediate := fmt.Sprintf("%s:%s%s", row.label, spacer, value)
+	if row.detail != "" {
+		intermediate += " " + mutedStyle.Render("("+row.detail+")")
+	}
 	if selected {
 		return marginTop + selectedRowStyle.Render(intermediate) + "\n"
 	}
@@ -130,14 +179,122 @@ type ItemShow struct {
 	flashMsg   string
 	flashTimer timer.Model
 	rows       []itemShowRow
+	// grace is how long a re-entered master password also opens other items
+	// with master password re-prompt.
+	grace time.Duration
+	// verifiedItem is the item the master password was last entered for,
+	// at verifiedAt. It stays open until it is closed.
+	verifiedItem string
+	verifiedAt   time.Time
+	// reprompting shows the master password modal; pending is the key that
+	// opened it, carried out once the password checks out.
+	reprompting bool
+	verifying   bool
+	pending     tea.Msg
+	password    textinput.Model
+	repromptMsg string
+	// launchCommands open URIs by scheme, see config.Config.
+	launchCommands map[string][]string
 }
 
-func NewItemShow(bwm *bw.Manager) tea.Model {
+func NewItemShow(bwm *bw.Manager, cfg *config.Config) tea.Model {
+	password := textinput.New()
+	password.Placeholder = "Master password"
+	password.EchoMode = textinput.EchoPassword
+	password.EchoCharacter = '•'
+	password.CursorStyle = cursorStyle
+	password.TextStyle = focusedStyle
 	return ItemShow{
-		bwm:  bwm,
-		keys: newItemShowKeyBindings(),
-		help: help.New(),
+		bwm:            bwm,
+		keys:           newItemShowKeyBindings(),
+		help:           help.New(),
+		grace:          cfg.RepromptGrace(),
+		password:       password,
+		launchCommands: cfg.LaunchCommands,
+	}
+}
+
+// locked reports whether the hidden fields of the item need the master
+// password first.
+func (c ItemShow) locked() bool {
+	if c.item.Reprompt == 0 || c.verifiedItem == c.item.ID {
+		return false
+	}
+	// the grace period does not reveal items an agent handed out redacted
+	if c.bwm.IsRedacted(c.item) {
+		return true
 	}
+	return c.verifiedAt.IsZero() || time.Since(c.verifiedAt) > c.grace
+}
+
+// reprompt opens the master password modal, carrying out msg afterwards.
+func (c ItemShow) reprompt(msg tea.Msg) (tea.Model, tea.Cmd) {
+	c.reprompting = true
+	c.pending = msg
+	c.repromptMsg = "This item is protected. Enter your master password to continue."
+	c.password.SetValue("")
+	return c, c.password.Focus()
+}
+
+func (c ItemShow) updateReprompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if c.verifying {
+		return c, nil
+	}
+	switch msg.String() {
+	case "ctrl+c":
+		return c, tea.Quit
+	case "esc":
+		c.reprompting = false
+		c.pending = nil
+		c.password.Blur()
+		return c, nil
+	case "enter":
+		c.verifying = true
+		c.repromptMsg = "Checking the master password..."
+		return c, verifyReprompt(c.bwm, c.item, c.password.Value())
+	}
+	var cmd tea.Cmd
+	c.password, cmd = c.password.Update(msg)
+	return c, cmd
+}
+
+// launch opens the selected URI, or the first one if no URI is selected, and
+// copies the password or, with totp or for logins without a password, the
+// current TOTP code.
+func (c ItemShow) launch(totp bool) (tea.Model, tea.Cmd) {
+	var raw string
+	if row := c.rows[c.selected]; row.uri {
+		raw = row.value
+	} else if len(c.item.Login.URIs) > 0 {
+		raw = c.item.Login.URIs[0].URI
+	}
+	if raw == "" {
+		return c.flash("the item has no URI")
+	}
+	schemes := make([]string, 0, len(c.launchCommands))
+	for scheme := range c.launchCommands {
+		schemes = append(schemes, scheme)
+	}
+	uri, err := bw.LaunchURI(raw, schemes)
+	if err != nil {
+		return c.flash(err.Error())
+	}
+	copied, data := "password", c.item.Login.Password
+	if totp || (data == "" && c.item.Login.TOTP != "") {
+		code, _, err := bw.TOTP(c.item.Login.TOTP, time.Now())
+		if err != nil {
+			return c.flash(err.Error())
+		}
+		copied, data = "TOTP", code
+	}
+	if data == "" {
+		return c, launchURI(c.launchCommands, uri, "")
+	}
+	err = clipboard.WriteAll(data)
+	if err != nil {
+		panic(fmt.Errorf("error copying %s to clipboard: %w", copied, err))
+	}
+	return c, launchURI(c.launchCommands, uri, copied)
 }
 
 func (c ItemShow) Init() tea.Cmd {
@@ -152,6 +309,7 @@ func (c ItemShow) flash(msg string) (tea.Model, tea.Cmd) {
 
 func (c ItemShow) setItem(listItem BWListItem) tea.Model {
 	c.item = listItem.Item
+	c.verifiedItem = ""
 	c.rows = make([]itemShowRow, 0)
 	c.rows = append(c.rows, itemShowRow{
 		label:     "Item Name",
@@ -193,6 +351,35 @@ func (c ItemShow) setItem(listItem BWListItem) tea.Model {
 		value:  c.item.Login.Password,
 		hidden: true,
 	})
+	for i, uri := range c.item.Login.URIs {
+		row := itemShowRow{
+			label:  "URI",
+			value:  uri.URI,
+			detail: uri.Match.String(),
+			uri:    true,
+		}
+		if i == 0 {
+			row.marginTop = 1
+		}
+		c.rows = append(c.rows, row)
+	}
+	if c.item.Type == bw.SSHKey {
+		c.rows = append(c.rows, itemShowRow{
+			label:     "Public Key",
+			value:     c.item.SSHKey.PublicKey,
+			marginTop: 1,
+		})
+		c.rows = append(c.rows, itemShowRow{
+			label: "Fingerprint",
+			value: c.item.SSHKey.KeyFingerprint,
+		})
+		c.rows = append(c.rows, itemShowRow{
+			label:       "Private Key",
+			value:       c.item.SSHKey.PrivateKey,
+			hidden:      true,
+			blockRender: true,
+		})
+	}
 	c.rows = append(c.rows, itemShowRow{
 		label:       "Notes",
 		value:       c.item.Notes,
@@ -220,21 +407,69 @@ func (c ItemShow) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			panic("Could not get BWListItem")
 		}
 		return c.setItem(listItem), tick
+	case uriLaunched:
+		switch {
+		case msg.err != nil:
+			return c.flash(fmt.Sprintf("failed to open %s: %s", msg.uri, msg.err))
+		case msg.copied != "":
+			return c.flash(fmt.Sprintf("opened %s, copied %s to clipboard", msg.uri, msg.copied))
+		}
+		return c.flash("opened " + msg.uri)
+	case repromptVerified:
+		c.verifying = false
+		if errors.Is(msg.err, bw.ErrInvalidPassword) {
+			c.repromptMsg = "Wrong master password. Try again or press 'esc' to cancel."
+			c.password.SetValue("")
+			return c, nil
+		}
+		if msg.err != nil {
+			c.repromptMsg = fmt.Sprintf("%s. Press 'esc' to cancel.", msg.err)
+			c.password.SetValue("")
+			return c, nil
+		}
+		// the revealed item may have more rows than the redacted one
+		selected := c.selected
+		c = c.setItem(NewBWListItem(msg.item)).(ItemShow)
+		if selected < len(c.rows) {
+			c.selected = selected
+		}
+		c.reprompting = false
+		c.password.Blur()
+		c.verifiedItem = c.item.ID
+		c.verifiedAt = time.Now()
+		pending := c.pending
+		c.pending = nil
+		if pending != nil {
+			return c.Update(pending)
+		}
 	case tea.KeyMsg:
+		if c.reprompting {
+			return c.updateReprompt(msg)
+		}
 		switch {
 		case key.Matches(msg, c.keys.Quit):
-			return c, SelectLoadingDone()
+			return c, SelectItemShowClosed()
 		case key.Matches(msg, c.keys.CursorUp):
 			c.selected--
 			if c.selected < 0 {
 				c.selected = 0
 			}
+			if c.locked() && c.rows[c.selected].hidden && c.rows[c.selected].value != "" {
+				// selecting reveals the value
+				return c.reprompt(nil)
+			}
 		case key.Matches(msg, c.keys.CursorDown):
 			c.selected++
 			if c.selected > len(c.rows)-1 {
 				c.selected = len(c.rows) - 1
 			}
+			if c.locked() && c.rows[c.selected].hidden && c.rows[c.selected].value != "" {
+				return c.reprompt(nil)
+			}
 		case key.Matches(msg, c.keys.Copy):
+			if c.locked() && c.rows[c.selected].hidden {
+				return c.reprompt(msg)
+			}
 			data := ""
 			row := c.rows[c.selected]
 			data = row.value
@@ -244,11 +479,19 @@ func (c ItemShow) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return c.flash("copied to clipboard")
 		case key.Matches(msg, c.keys.CopyPassword):
+			if c.locked() {
+				return c.reprompt(msg)
+			}
 			err := clipboard.WriteAll(c.item.Login.Password)
 			if err != nil {
 				panic(fmt.Errorf("error copying password to clipboard: %w", err))
 			}
 			return c.flash("copied password to clipboard")
+		case key.Matches(msg, c.keys.Launch), key.Matches(msg, c.keys.LaunchTOTP):
+			if c.locked() {
+				return c.reprompt(msg)
+			}
+			return c.launch(key.Matches(msg, c.keys.LaunchTOTP))
 		case key.Matches(msg, c.keys.CopyUsername):
 			err := clipboard.WriteAll(c.item.Login.Username)
 			if err != nil {
@@ -268,9 +511,14 @@ func (c ItemShow) View() string {
 	b.WriteString(titleStyle.Render(fmt.Sprintf(" %s Item | %s", logo, c.item.Name)))
 	b.WriteString("\n")
 	for i, row := range c.rows {
-		b.WriteString(row.render(i == c.selected))
+		selected := i == c.selected
+		b.WriteString(row.render(selected, selected && !c.reprompting && !c.locked()))
 	}
 	sections[0] = b.String()
-	sections[1] = lipgloss.JoinVertical(lipgloss.Bottom, c.flashMsg, c.help.View(c.keys))
+	if c.reprompting {
+		sections[1] = lipgloss.JoinVertical(lipgloss.Left, c.repromptMsg, c.password.View(), mutedStyle.Render("enter: confirm • esc: cancel"))
+	} else {
+		sections[1] = lipgloss.JoinVertical(lipgloss.Bottom, c.flashMsg, c.help.View(c.keys))
+	}
 	return docStyle.Render(lipgloss.JoinVertical(lipgloss.Left, sections...))
 }
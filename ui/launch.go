@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var errNoLaunchCommand = errors.New("no command to open the URI with")
+
+type uriLaunched struct {
+	uri string
+	// copied names what was copied to the clipboard along with it.
+	copied string
+	err    error
+}
+
+// launchURI opens uri with the command configured for its scheme.
+func launchURI(commands map[string][]string, uri string, copied string) tea.Cmd {
+	return func() tea.Msg {
+		argv, err := launchCommand(commands, uri)
+		if err != nil {
+			return uriLaunched{uri, copied, err}
+		}
+		cmd := exec.Command(argv[0], argv[1:]...) // #nosec G204
+		err = cmd.Start()
+		if err != nil {
+			return uriLaunched{uri, copied, err}
+		}
+		// openers like xdg-open hand the URI over and exit
+		go func() {
+			_ = cmd.Wait()
+		}()
+		return uriLaunched{uri, copied, nil}
+	}
+}
+
+// launchCommand returns the command line opening uri: the configured command
+// for its scheme or "*", $BROWSER for web pages, or the desktop's opener.
+func launchCommand(commands map[string][]string, uri string) ([]string, error) {
+	var scheme string
+	if u, err := url.Parse(uri); err == nil {
+		scheme = strings.ToLower(u.Scheme)
+	}
+	command, ok := commands[scheme]
+	if !ok {
+		command, ok = commands["*"]
+	}
+	if !ok && (scheme == "http" || scheme == "https") {
+		// $BROWSER is a colon separated list, of which the first is used
+		if browser, _, _ := strings.Cut(os.Getenv("BROWSER"), ":"); browser != "" {
+			command, ok = strings.Fields(browser), true
+		}
+	}
+	if !ok {
+		switch runtime.GOOS {
+		case "darwin":
+			command = []string{"open"}
+		default:
+			command = []string{"xdg-open"}
+		}
+	}
+	if len(command) == 0 {
+		return nil, errNoLaunchCommand
+	}
+	argv := make([]string, 0, len(command)+1)
+	substituted := false
+	for _, arg := range command {
+		if strings.Contains(arg, "%s") {
+			arg = strings.ReplaceAll(arg, "%s", uri)
+			substituted = true
+		}
+		argv = append(argv, arg)
+	}
+	if !substituted {
+		argv = append(argv, uri)
+	}
+	return argv, nil
+}
@@ -2,12 +2,15 @@ package ui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 
 	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/config"
 )
 
 type ListSelectedEntry struct {
@@ -25,6 +28,9 @@ type BWListItem struct {
 	ID         string
 	ObjectName string
 	UserName   string
+	// description overrides the username while a search highlights another
+	// field of the item.
+	description string
 }
 
 func NewBWListItem(bwi bw.Item) BWListItem {
@@ -36,34 +42,209 @@ func NewBWListItem(bwi bw.Item) BWListItem {
 	}
 }
 
-func (bwl BWListItem) Title() string       { return bwl.ObjectName }
-func (bwl BWListItem) Description() string { return bwl.UserName }
+// favoriteMarker is prepended to the title of favorite items.
+const favoriteMarker = "★ "
+
 func (bwl BWListItem) FilterValue() string { return bwl.ObjectName }
 
+func (bwl BWListItem) Title() string {
+	if bwl.Item.Favorite {
+		return favoriteMarker + bwl.ObjectName
+	}
+	return bwl.ObjectName
+}
+
+func (bwl BWListItem) Description() string {
+	if bwl.description != "" {
+		return bwl.description
+	}
+	return bwl.UserName
+}
+
+type listFavoriteSet struct {
+	item bw.Item
+	err  error
+}
+
+func setFavorite(bwm *bw.Manager, item bw.Item) tea.Cmd {
+	return func() tea.Msg {
+		updated, err := bwm.SetFavorite(item.ID, !item.Favorite)
+		return listFavoriteSet{updated, err}
+	}
+}
+
+// VaultLocked reports the vault locked from the list.
+type VaultLocked struct {
+	err error
+}
+
+func lockVault(bwm *bw.Manager) tea.Cmd {
+	return func() tea.Msg {
+		return VaultLocked{bwm.Lock()}
+	}
+}
+
+// pinLockVault hides the vault behind the quick-unlock PIN, leaving the bw
+// session valid.
+func pinLockVault(bwm *bw.Manager) tea.Cmd {
+	return func() tea.Msg {
+		return VaultLocked{bwm.PinLock()}
+	}
+}
+
+type listKeyBindings struct {
+	View            key.Binding
+	NextTab         key.Binding
+	PrevTab         key.Binding
+	ToggleFavorite  key.Binding
+	FavoritesFilter key.Binding
+	CycleSort       key.Binding
+	ReverseSort     key.Binding
+	Health          key.Binding
+	Export          key.Binding
+	Import          key.Binding
+	Sends           key.Binding
+	Secrets         key.Binding
+	Lock            key.Binding
+	PinLock         key.Binding
+}
+
+func newListKeyBindings() listKeyBindings {
+	return listKeyBindings{
+		View: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("Enter", "view item"),
+		),
+		NextTab: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "next type"),
+		),
+		PrevTab: key.NewBinding(
+			key.WithKeys("shift+tab"),
+			key.WithHelp("shift+tab", "previous type"),
+		),
+		ToggleFavorite: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "star/unstar"),
+		),
+		FavoritesFilter: key.NewBinding(
+			key.WithKeys("*"),
+			key.WithHelp("*", "only favorites"),
+		),
+		CycleSort: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "sort by"),
+		),
+		ReverseSort: key.NewBinding(
+			key.WithKeys("O"),
+			key.WithHelp("O", "reverse sort"),
+		),
+		Health: key.NewBinding(
+			key.WithKeys("H"),
+			key.WithHelp("H", "vault health"),
+		),
+		Export: key.NewBinding(
+			key.WithKeys("E"),
+			key.WithHelp("E", "export vault"),
+		),
+		Import: key.NewBinding(
+			key.WithKeys("I"),
+			key.WithHelp("I", "import items"),
+		),
+		Sends: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "sends"),
+		),
+		Secrets: key.NewBinding(
+			key.WithKeys("M"),
+			key.WithHelp("M", "secrets manager"),
+		),
+		Lock: key.NewBinding(
+			key.WithKeys("L"),
+			key.WithHelp("L", "lock vault"),
+		),
+		PinLock: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", "lock with PIN"),
+		),
+	}
+}
+
+// listTabs are the item type filters across the header. The zero value means
+// all types.
+func listTabs() []bw.ItemType {
+	return []bw.ItemType{0, bw.Login, bw.SecureNote, bw.Card, bw.Identity, bw.SSHKey}
+}
+
+func tabLabel(t bw.ItemType) string {
+	switch t {
+	case 0:
+		return "All"
+	case bw.SecureNote:
+		return "Secure Note"
+	case bw.SSHKey:
+		return "SSH Key"
+	default:
+		return t.String()
+	}
+}
+
+// tabBarHeight is the number of lines the tab bar takes above the list.
+const tabBarHeight = 2
+
 type List struct {
-	list list.Model
-	bwm  *bw.Manager
+	list   list.Model
+	bwm    *bw.Manager
+	search *listSearch
+	keys   listKeyBindings
+	// url limits the list to logins matching it, if set.
+	url string
+	// items are the entries before the tab and favorites filters.
+	items         []bw.Item
+	tab           int
+	favoritesOnly bool
+	// state holds the sort order and recently used items across sessions.
+	state *config.State
 }
 
 func NewList(h int, v int, bwm *bw.Manager) List {
-	d := list.NewDefaultDelegate()
+	search := newListSearch()
+	d := itemDelegate{
+		DefaultDelegate: list.NewDefaultDelegate(),
+		search:          search,
+	}
 	d.Styles.SelectedTitle = d.Styles.SelectedTitle.Foreground(selectedColor).BorderLeftForeground(selectedColor)
 	d.Styles.SelectedDesc = d.Styles.SelectedTitle.Copy()
 	width, height := docStyle.GetFrameSize()
-	l := list.New(nil, d, h-width, v-height)
+	l := list.New(nil, d, h-width, v-height-tabBarHeight)
+	l.Filter = search.filter
+	keys := newListKeyBindings()
 	l.AdditionalFullHelpKeys = func() []key.Binding {
 		return []key.Binding{
-			key.NewBinding(
-				key.WithKeys("enter"),
-				key.WithHelp("Enter", "view item"),
-			),
+			keys.View,
+			keys.NextTab,
+			keys.PrevTab,
+			keys.ToggleFavorite,
+			keys.FavoritesFilter,
+			keys.CycleSort,
+			keys.ReverseSort,
+			keys.Health,
+			keys.Export,
+			keys.Import,
+			keys.Sends,
+			keys.Secrets,
+			keys.Lock,
+			keys.PinLock,
 		}
 	}
 	l.Styles.Title = titleStyle
 
 	return List{
-		list: l,
-		bwm:  bwm,
+		list:   l,
+		bwm:    bwm,
+		search: search,
+		keys:   keys,
+		state:  &config.State{},
 	}
 }
 
@@ -71,32 +252,174 @@ func (m List) Init() tea.Cmd {
 	return nil
 }
 
-func (m *List) GetEntries() {
-	listItems := []list.Item{}
-	items, err := m.bwm.GetList()
+func (m *List) GetEntries() tea.Cmd {
+	var items []bw.Item
+	var err error
+	if m.url != "" {
+		items, err = m.bwm.MatchURI(m.url)
+	} else {
+		items, err = m.bwm.GetList()
+	}
 	if err != nil {
 		panic(err)
 	}
+	m.items = items
+	m.list.Title = fmt.Sprintf(" %s Vault | %s ", logo, m.bwm.VaultStatus.UserEmail)
+	if m.url != "" {
+		m.list.Title = fmt.Sprintf(" %s Vault | %s | %s ", logo, m.bwm.VaultStatus.UserEmail, m.url)
+	}
+	return m.refresh()
+}
+
+// visible reports whether an item passes the favorites filter and, unless
+// ignoreTab is set, the current tab.
+func (m *List) visible(item bw.Item, ignoreTab bool) bool {
+	if m.favoritesOnly && !item.Favorite {
+		return false
+	}
+	t := listTabs()[m.tab]
+	return ignoreTab || t == 0 || item.Type == t
+}
+
+// refresh applies the tab and favorites filters and the sort order to the
+// entries and pins favorites to the top.
+func (m *List) refresh() tea.Cmd {
+	mode := parseSortMode(m.state.Sort)
+	sorted := make([]bw.Item, len(m.items))
+	copy(sorted, m.items)
+	sortItems(sorted, mode, m.state.SortDescending, m.state.LastUsed)
+	label := fmt.Sprintf("sorted by %s, %s", mode.label(), mode.direction(m.state.SortDescending))
+	m.list.SetStatusBarItemName("item • "+label, "items • "+label)
+
+	items := make([]bw.Item, 0, len(sorted))
+	for _, item := range sorted {
+		if m.visible(item, false) && item.Favorite {
+			items = append(items, item)
+		}
+	}
+	for _, item := range sorted {
+		if m.visible(item, false) && !item.Favorite {
+			items = append(items, item)
+		}
+	}
+	listItems := make([]list.Item, 0, len(items))
 	for _, v := range items {
 		listItems = append(listItems, NewBWListItem(v))
 	}
-	m.list.Title = fmt.Sprintf(" %s Vault | %s ", logo, m.bwm.VaultStatus.UserEmail)
-	m.list.SetItems(listItems)
+	m.search.setIndex(m.bwm.NewSearchIndex(items))
+	return m.list.SetItems(listItems)
+}
+
+func (m List) saveState() tea.Cmd {
+	err := m.state.Save()
+	if err != nil {
+		return m.list.NewStatusMessage(err.Error())
+	}
+	return nil
+}
+
+func (m List) tabBar() string {
+	counts := make(map[bw.ItemType]int)
+	for _, item := range m.items {
+		if m.visible(item, true) {
+			counts[0]++
+			counts[item.Type]++
+		}
+	}
+	tabs := make([]string, 0, len(listTabs()))
+	for i, t := range listTabs() {
+		label := fmt.Sprintf(" %s %d ", tabLabel(t), counts[t])
+		if i == m.tab {
+			tabs = append(tabs, activeTabStyle.Render(label))
+		} else {
+			tabs = append(tabs, tabStyle.Render(label))
+		}
+	}
+	bar := strings.Join(tabs, mutedStyle.Render("│"))
+	if m.favoritesOnly {
+		bar += mutedStyle.Render("  " + favoriteMarker + "only")
+	}
+	return bar
 }
 
 func (m List) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
-	case LoadingDone:
-		m.GetEntries()
+	case LoadingDone, ItemShowClosed:
+		return m, m.GetEntries()
+	case VaultLocked:
+		if msg.err != nil {
+			return m, m.list.NewStatusMessage(msg.err.Error())
+		}
+		// nothing of the vault stays on screen behind the unlock view
+		m.items = nil
+		m.list.SetItems(nil)
+		return m, nil
+	case listFavoriteSet:
+		if msg.err != nil {
+			return m, m.list.NewStatusMessage(msg.err.Error())
+		}
+		status := "removed from favorites"
+		if msg.item.Favorite {
+			status = "added to favorites"
+		}
+		return m, tea.Batch(m.GetEntries(), m.list.NewStatusMessage(status))
 	case tea.KeyMsg:
 		if msg.String() == "ctrl+c" {
 			return m, tea.Quit
 		}
 		if msg.String() == "enter" {
-			return m, SelectListSelectedEntry(m.list.SelectedItem())
+			// tabs and filters often leave the list empty
+			selected, ok := m.list.SelectedItem().(BWListItem)
+			if !ok {
+				return m, m.list.NewStatusMessage("no item selected")
+			}
+			m.state.MarkUsed(selected.ID)
+			// failing to remember the item is not worth interrupting for
+			_ = m.state.Save()
+			return m, SelectListSelectedEntry(selected)
+		}
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+		switch {
+		case key.Matches(msg, m.keys.Health):
+			return m, SelectShowHealth()
+		case key.Matches(msg, m.keys.Export):
+			return m, SelectShowExport()
+		case key.Matches(msg, m.keys.Import):
+			return m, SelectShowImport()
+		case key.Matches(msg, m.keys.Sends):
+			return m, SelectShowSends()
+		case key.Matches(msg, m.keys.Secrets):
+			return m, SelectShowSecrets()
+		case key.Matches(msg, m.keys.Lock):
+			return m, lockVault(m.bwm)
+		case key.Matches(msg, m.keys.PinLock):
+			return m, pinLockVault(m.bwm)
+		case key.Matches(msg, m.keys.CycleSort):
+			mode := parseSortMode(m.state.Sort).next()
+			m.state.Sort = string(mode)
+			m.state.SortDescending = mode.defaultDescending()
+			return m, tea.Batch(m.refresh(), m.saveState())
+		case key.Matches(msg, m.keys.ReverseSort):
+			m.state.SortDescending = !m.state.SortDescending
+			return m, tea.Batch(m.refresh(), m.saveState())
+		case key.Matches(msg, m.keys.NextTab):
+			m.tab = (m.tab + 1) % len(listTabs())
+			return m, m.refresh()
+		case key.Matches(msg, m.keys.PrevTab):
+			m.tab = (m.tab + len(listTabs()) - 1) % len(listTabs())
+			return m, m.refresh()
+		case key.Matches(msg, m.keys.FavoritesFilter):
+			m.favoritesOnly = !m.favoritesOnly
+			return m, m.refresh()
+		case key.Matches(msg, m.keys.ToggleFavorite):
+			if selected, ok := m.list.SelectedItem().(BWListItem); ok {
+				return m, setFavorite(m.bwm, selected.Item)
+			}
 		}
 	case tea.WindowSizeMsg:
-		m.list.SetSize(msg.Width, msg.Height)
+		m.list.SetSize(msg.Width, msg.Height-tabBarHeight)
 		return m, tea.ClearScreen
 	}
 	var cmd tea.Cmd
@@ -105,5 +428,5 @@ func (m List) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m List) View() string {
-	return docStyle.Render(m.list.View())
+	return docStyle.Render(lipgloss.JoinVertical(lipgloss.Left, m.tabBar(), "", m.list.View()))
 }
@@ -14,15 +14,17 @@ type loginType int
 const (
 	login loginType = iota
 	unlock
+	pinUnlock
 )
 
 type LoadingLoginFailed struct {
-	lt loginType
+	lt  loginType
+	err error
 }
 
-func SelectLoadingFailed(lt loginType) tea.Cmd {
+func SelectLoadingFailed(lt loginType, err error) tea.Cmd {
 	return func() tea.Msg {
-		return LoadingLoginFailed{lt}
+		return LoadingLoginFailed{lt, err}
 	}
 }
 
@@ -37,6 +39,7 @@ func SelectLoadingDone() tea.Cmd {
 type Loading struct {
 	un  string
 	pw  string
+	pin string
 	lt  loginType
 	bwm *bw.Manager
 }
@@ -64,6 +67,17 @@ func (m Loading) Login() error {
 		if err != nil {
 			return err
 		}
+		if m.pin != "" {
+			err := m.bwm.SetPin(m.pin)
+			if err != nil {
+				return err
+			}
+		}
+	case pinUnlock:
+		err := m.bwm.UnlockWithPin(m.pw)
+		if err != nil {
+			return err
+		}
 	}
 	err = m.bwm.UpdateList()
 	return err
@@ -75,6 +89,7 @@ func (m Loading) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.un = msg.un
 		m.pw = msg.pw
 		m.lt = msg.lt
+		m.pin = msg.pin
 		return m, tick
 	case tea.KeyMsg:
 		switch msg.String() {
@@ -86,7 +101,7 @@ func (m Loading) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	default:
 		err := m.Login()
 		if err != nil {
-			return m, SelectLoadingFailed(m.lt)
+			return m, SelectLoadingFailed(m.lt, err)
 		}
 		return m, SelectLoadingDone()
 	}
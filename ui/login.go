@@ -12,11 +12,13 @@ type LoginSubmit struct {
 	un string
 	pw string
 	lt loginType
+	// pin, if set, becomes the quick-unlock PIN after unlocking.
+	pin string
 }
 
 func SelectSubmit(un string, pw string) tea.Cmd {
 	return func() tea.Msg {
-		return LoginSubmit{un, pw, login}
+		return LoginSubmit{un, pw, login, ""}
 	}
 }
 
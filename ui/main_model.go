@@ -5,6 +5,8 @@ import (
 	"golang.org/x/term"
 
 	"github.com/sapslaj/gobw/bw"
+	"github.com/sapslaj/gobw/bws"
+	"github.com/sapslaj/gobw/config"
 )
 
 type sessionState int
@@ -15,18 +17,72 @@ const (
 	viewLoading
 	viewList
 	viewItemShow
+	viewHealth
+	viewExport
+	viewImport
+	viewSends
+	viewSecrets
 )
 
 type MainModel struct {
-	state        sessionState
+	state sessionState
+	// returnTo is the view to go back to when the item view is closed.
+	returnTo     sessionState
 	ModelLogin   tea.Model
 	ModelUnlock  tea.Model
 	ModelLoading tea.Model
 	ModelList    tea.Model
 	ModelClip    tea.Model
+	ModelHealth  tea.Model
+	ModelExport  tea.Model
+	ModelImport  tea.Model
+	ModelSends   tea.Model
+	ModelSecrets tea.Model
 }
 
-func NewMainModel(bwm *bw.Manager) MainModel {
+type options struct {
+	url   string
+	state *config.State
+	cfg   *config.Config
+	sm    bws.Backend
+}
+
+type Option func(*options)
+
+// WithURL limits the list to logins with a URI matching url.
+func WithURL(url string) Option {
+	return func(o *options) {
+		o.url = url
+	}
+}
+
+// WithState makes the list remember its sort order and recently used items in
+// the given state.
+func WithState(state *config.State) Option {
+	return func(o *options) {
+		o.state = state
+	}
+}
+
+// WithConfig applies the user's configuration file.
+func WithConfig(cfg *config.Config) Option {
+	return func(o *options) {
+		o.cfg = cfg
+	}
+}
+
+// WithSecretsManager enables the Secrets Manager view.
+func WithSecretsManager(sm bws.Backend) Option {
+	return func(o *options) {
+		o.sm = sm
+	}
+}
+
+func NewMainModel(bwm *bw.Manager, opts ...Option) MainModel {
+	o := options{cfg: &config.Config{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
 	var initialState sessionState
 	h, v, _ := term.GetSize(0)
 	switch bwm.VaultStatus.Status {
@@ -39,13 +95,26 @@ func NewMainModel(bwm *bw.Manager) MainModel {
 	default:
 		initialState = viewUnlock
 	}
+	itemList := NewList(h, v, bwm)
+	itemList.url = o.url
+	if o.state != nil {
+		itemList.state = o.state
+	}
+	healthOpts := bw.DefaultHealthOptions()
+	healthOpts.MaxPasswordAge = o.cfg.PasswordMaxAge()
+	healthOpts.TwoFactorDomains = o.cfg.TwoFactorDomains
 	return MainModel{
 		state:        initialState,
 		ModelLogin:   NewLogin(),
-		ModelUnlock:  NewUnlock(),
+		ModelUnlock:  NewUnlock(bwm),
 		ModelLoading: NewLoading(bwm),
-		ModelList:    NewList(h, v, bwm),
-		ModelClip:    NewItemShow(bwm),
+		ModelList:    itemList,
+		ModelClip:    NewItemShow(bwm, o.cfg),
+		ModelHealth:  NewHealth(h, v, bwm, healthOpts, o.cfg),
+		ModelExport:  NewExport(bwm),
+		ModelImport:  NewImport(bwm),
+		ModelSends:   NewSends(h, v, bwm),
+		ModelSecrets: NewSecrets(h, v, o.sm),
 	}
 }
 
@@ -60,15 +129,44 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case LoadingLoginFailed:
 		if msg.lt == login {
 			m.state = viewLogin
-		} else if msg.lt == unlock {
+		} else if msg.lt == unlock || msg.lt == pinUnlock {
+			m.state = viewUnlock
+		}
+	case VaultLocked:
+		if msg.err == nil {
+			m.ModelList, _ = m.ModelList.Update(msg)
 			m.state = viewUnlock
 		}
 	case LoginSubmit:
 		m.state = viewLoading
 	case ListSelectedEntry:
+		m.returnTo = m.state
 		m.state = viewItemShow
+	case ItemShowClosed:
+		m.state = m.returnTo
 	case LoadingDone:
 		m.state = viewList
+	case ShowHealth:
+		m.state = viewHealth
+	case ShowExport:
+		m.state = viewExport
+	case ShowImport:
+		m.state = viewImport
+	case ShowSends:
+		m.state = viewSends
+	case ShowSecrets:
+		m.state = viewSecrets
+	case tea.WindowSizeMsg:
+		// keep the health view sized while it is in the background
+		if m.state != viewHealth {
+			m.ModelHealth, _ = m.ModelHealth.Update(msg)
+		}
+		if m.state != viewSends {
+			m.ModelSends, _ = m.ModelSends.Update(msg)
+		}
+		if m.state != viewSecrets {
+			m.ModelSecrets, _ = m.ModelSecrets.Update(msg)
+		}
 	}
 	switch m.state {
 	case viewList:
@@ -114,6 +212,46 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.ModelClip = clip
 		cmd = newCmd
+	case viewHealth:
+		newHealth, newCmd := m.ModelHealth.Update(msg)
+		health, ok := newHealth.(Health)
+		if !ok {
+			panic("could not perform assertion on Health model")
+		}
+		m.ModelHealth = health
+		cmd = newCmd
+	case viewExport:
+		newExport, newCmd := m.ModelExport.Update(msg)
+		export, ok := newExport.(Export)
+		if !ok {
+			panic("could not perform assertion on Export model")
+		}
+		m.ModelExport = export
+		cmd = newCmd
+	case viewImport:
+		newImport, newCmd := m.ModelImport.Update(msg)
+		imp, ok := newImport.(Import)
+		if !ok {
+			panic("could not perform assertion on Import model")
+		}
+		m.ModelImport = imp
+		cmd = newCmd
+	case viewSends:
+		newSends, newCmd := m.ModelSends.Update(msg)
+		sends, ok := newSends.(Sends)
+		if !ok {
+			panic("could not perform assertion on Sends model")
+		}
+		m.ModelSends = sends
+		cmd = newCmd
+	case viewSecrets:
+		newSecrets, newCmd := m.ModelSecrets.Update(msg)
+		secrets, ok := newSecrets.(Secrets)
+		if !ok {
+			panic("could not perform assertion on Secrets model")
+		}
+		m.ModelSecrets = secrets
+		cmd = newCmd
 	}
 	cmds = append(cmds, cmd)
 	return m, tea.Batch(cmds...)
@@ -131,6 +269,16 @@ func (m MainModel) View() string {
 		return m.ModelClip.View()
 	case viewUnlock:
 		return m.ModelUnlock.View()
+	case viewHealth:
+		return m.ModelHealth.View()
+	case viewExport:
+		return m.ModelExport.View()
+	case viewImport:
+		return m.ModelImport.View()
+	case viewSends:
+		return m.ModelSends.View()
+	case viewSecrets:
+		return m.ModelSecrets.View()
 	default:
 		return m.ModelLogin.View()
 	}
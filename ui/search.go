@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+// listSearch plugs the bw search engine into the list filter. The list runs
+// its filter in a goroutine, so the results are guarded by a mutex for the
+// delegate to read while rendering.
+type listSearch struct {
+	mu      sync.Mutex
+	index   *bw.SearchIndex
+	results map[string]bw.SearchResult
+}
+
+func newListSearch() *listSearch {
+	return &listSearch{
+		results: make(map[string]bw.SearchResult),
+	}
+}
+
+// setIndex replaces the index. It must be built from the items in the same
+// order as they are set on the list.
+func (s *listSearch) setIndex(idx *bw.SearchIndex) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.index = idx
+	s.results = make(map[string]bw.SearchResult)
+}
+
+func (s *listSearch) filter(term string, _ []string) []list.Rank {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index == nil {
+		return nil
+	}
+	results := s.index.Search(term)
+	s.results = make(map[string]bw.SearchResult, len(results))
+	ranks := make([]list.Rank, 0, len(results))
+	for _, r := range results {
+		s.results[r.Item.ID] = r
+		indexes := r.NameIndexes()
+		if r.Item.Favorite {
+			// the title is prefixed with the favorite marker
+			offset := len([]rune(favoriteMarker))
+			for i := range indexes {
+				indexes[i] += offset
+			}
+		}
+		ranks = append(ranks, list.Rank{
+			Index:          r.Position,
+			MatchedIndexes: indexes,
+		})
+	}
+	return ranks
+}
+
+func (s *listSearch) result(id string) (bw.SearchResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.results[id]
+	return r, ok
+}
+
+// itemDelegate renders list items like the default delegate, but replaces the
+// description with the field that matched the filter.
+type itemDelegate struct {
+	list.DefaultDelegate
+	search *listSearch
+}
+
+func (d itemDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	bwl, ok := item.(BWListItem)
+	if ok && m.FilterState() != list.Unfiltered && m.FilterValue() != "" {
+		if r, ok := d.search.result(bwl.ID); ok {
+			if match, ok := r.Highlight(); ok {
+				selected := index == m.Index() && m.FilterState() != list.Filtering
+				bwl.description = d.renderMatch(match, selected)
+				item = bwl
+			}
+		}
+	}
+	d.DefaultDelegate.Render(w, m, index, item)
+}
+
+func (d itemDelegate) renderMatch(match bw.SearchMatch, selected bool) string {
+	unmatched := d.Styles.NormalDesc.Copy().Inline(true)
+	if selected {
+		unmatched = d.Styles.SelectedDesc.Copy().Inline(true)
+	}
+	matched := unmatched.Copy().Inherit(d.Styles.FilterMatch)
+	value, indexes := matchLine(match.Value, match.Indexes)
+	label := fmt.Sprintf("%s: ", match.Field)
+	shifted := make([]int, 0, len(indexes))
+	for _, i := range indexes {
+		shifted = append(shifted, i+len([]rune(label)))
+	}
+	return lipgloss.StyleRunes(label+value, shifted, matched, unmatched)
+}
+
+// matchLine narrows a multi-line value (i.e. notes) down to the line holding
+// the first matched character and shifts the indexes accordingly.
+func matchLine(value string, indexes []int) (string, []int) {
+	if !strings.Contains(value, "\n") {
+		return value, indexes
+	}
+	first := 0
+	if len(indexes) > 0 {
+		first = indexes[0]
+	}
+	start := 0
+	for _, line := range strings.Split(value, "\n") {
+		n := len([]rune(line))
+		if first <= start+n {
+			shifted := make([]int, 0, len(indexes))
+			for _, i := range indexes {
+				if i >= start && i < start+n {
+					shifted = append(shifted, i-start)
+				}
+			}
+			return line, shifted
+		}
+		start += n + 1
+	}
+	return value, indexes
+}
@@ -0,0 +1,585 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sapslaj/gobw/bws"
+)
+
+type ShowSecrets struct{}
+
+func SelectShowSecrets() tea.Cmd {
+	return func() tea.Msg {
+		return ShowSecrets{}
+	}
+}
+
+type secretsLoaded struct {
+	projects []bws.Project
+	secrets  []bws.Secret
+	err      error
+}
+
+func loadSecrets(sm bws.Backend) tea.Cmd {
+	return func() tea.Msg {
+		if sm == nil {
+			return secretsLoaded{err: bws.ErrNoAccessToken}
+		}
+		projects, err := sm.Projects()
+		if err != nil {
+			return secretsLoaded{err: err}
+		}
+		secrets, err := sm.Secrets("")
+		return secretsLoaded{projects, secrets, err}
+	}
+}
+
+type secretSaved struct {
+	secret bws.Secret
+	err    error
+}
+
+// saveSecret creates a secret, or edits the one with the given ID.
+func saveSecret(sm bws.Backend, id string, in bws.SecretInput) tea.Cmd {
+	return func() tea.Msg {
+		if id == "" {
+			secret, err := sm.CreateSecret(in)
+			return secretSaved{secret, err}
+		}
+		secret, err := sm.EditSecret(id, in)
+		return secretSaved{secret, err}
+	}
+}
+
+type secretDeleted struct {
+	secret bws.Secret
+	err    error
+}
+
+func deleteSecret(sm bws.Backend, secret bws.Secret) tea.Cmd {
+	return func() tea.Msg {
+		return secretDeleted{secret, sm.DeleteSecret(secret.ID)}
+	}
+}
+
+// secretEntry is a secret in the list.
+type secretEntry struct {
+	secret  bws.Secret
+	project string
+}
+
+func (e secretEntry) Title() string { return e.secret.Key }
+
+func (e secretEntry) Description() string {
+	parts := []string{e.project}
+	if note, _, _ := strings.Cut(e.secret.Note, "\n"); note != "" {
+		parts = append(parts, note)
+	}
+	parts = append(parts, "updated "+e.secret.RevisionDate.Local().Format("2006-01-02 15:04"))
+	return strings.Join(parts, " · ")
+}
+
+func (e secretEntry) FilterValue() string { return e.secret.Key + " " + e.project }
+
+type secretsKeyBindings struct {
+	View    key.Binding
+	Copy    key.Binding
+	New     key.Binding
+	Edit    key.Binding
+	Delete  key.Binding
+	Refresh key.Binding
+	Back    key.Binding
+}
+
+func newSecretsKeyBindings() secretsKeyBindings {
+	return secretsKeyBindings{
+		View: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "view secret"),
+		),
+		Copy: key.NewBinding(
+			key.WithKeys("c", "y"),
+			key.WithHelp("c/y", "copy value"),
+		),
+		New: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "new secret"),
+		),
+		Edit: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "edit"),
+		),
+		Delete: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "delete"),
+		),
+		Refresh: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "refresh"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("q", "esc"),
+			key.WithHelp("q", "back"),
+		),
+	}
+}
+
+type secretShowKeyBindings struct {
+	CursorUp   key.Binding
+	CursorDown key.Binding
+	Copy       key.Binding
+	Edit       key.Binding
+	Back       key.Binding
+}
+
+func newSecretShowKeyBindings() secretShowKeyBindings {
+	return secretShowKeyBindings{
+		CursorUp: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑/k", "up"),
+		),
+		CursorDown: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓/j", "down"),
+		),
+		Copy: key.NewBinding(
+			key.WithKeys("enter", "c", "y"),
+			key.WithHelp("enter/c/y", "copy"),
+		),
+		Edit: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "edit"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("q", "esc"),
+			key.WithHelp("q", "back"),
+		),
+	}
+}
+
+func (k secretShowKeyBindings) ShortHelp() []key.Binding {
+	return []key.Binding{k.CursorUp, k.CursorDown, k.Copy, k.Edit, k.Back}
+}
+
+func (k secretShowKeyBindings) FullHelp() [][]key.Binding {
+	return [][]key.Binding{}
+}
+
+type secretsMode int
+
+const (
+	secretsListMode secretsMode = iota
+	secretsShowMode
+	secretsFormMode
+)
+
+// the fields of the secret form
+const (
+	secretKeyField = iota
+	secretValueField
+	secretNoteField
+	secretProjectField
+	secretSubmitField
+)
+
+// Secrets lists the Secrets Manager secrets the machine account can read,
+// shows them like ItemShow and edits them.
+type Secrets struct {
+	sm       bws.Backend
+	mode     secretsMode
+	list     list.Model
+	keys     secretsKeyBindings
+	loaded   bool
+	err      error
+	projects []bws.Project
+	// deleting is the secret waiting for d to be pressed again.
+	deleting string
+
+	// secret is the one shown, or edited in the form.
+	secret   bws.Secret
+	rows     []itemShowRow
+	selected int
+	showKeys secretShowKeyBindings
+	help     help.Model
+	flashMsg string
+
+	// editing is set when the form changes secret rather than creating
+	// a new one.
+	editing bool
+	// formReturn is the mode esc goes back to from the form.
+	formReturn secretsMode
+	focus      int
+	inputs     []textinput.Model
+	// project indexes projects.
+	project  int
+	formText string
+	busy     bool
+}
+
+func NewSecrets(h int, v int, sm bws.Backend) Secrets {
+	d := list.NewDefaultDelegate()
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.Foreground(selectedColor).BorderLeftForeground(selectedColor)
+	d.Styles.SelectedDesc = d.Styles.SelectedTitle.Copy()
+	width, height := docStyle.GetFrameSize()
+	l := list.New(nil, d, h-width, v-height)
+	l.Title = fmt.Sprintf(" %s Secrets Manager ", logo)
+	l.Styles.Title = titleStyle
+	l.SetStatusBarItemName("secret", "secrets")
+	// unbound rather than disabled, see NewHealth
+	l.KeyMap.Quit = key.NewBinding()
+	keys := newSecretsKeyBindings()
+	l.AdditionalFullHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.View, keys.Copy, keys.New, keys.Edit, keys.Delete, keys.Refresh, keys.Back}
+	}
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.View, keys.New, keys.Back}
+	}
+	inputs := make([]textinput.Model, secretProjectField)
+	for i, placeholder := range []string{"Key", "Value", "Note"} {
+		t := textinput.New()
+		t.CursorStyle = cursorStyle
+		t.Placeholder = placeholder
+		if i == secretValueField {
+			t.EchoMode = textinput.EchoPassword
+			t.EchoCharacter = '•'
+		}
+		inputs[i] = t
+	}
+	return Secrets{
+		sm:       sm,
+		list:     l,
+		keys:     keys,
+		showKeys: newSecretShowKeyBindings(),
+		help:     help.New(),
+		inputs:   inputs,
+	}
+}
+
+func (m Secrets) Init() tea.Cmd {
+	return nil
+}
+
+func (m Secrets) projectName(id string) string {
+	return bws.ProjectName(m.projects, id)
+}
+
+// show opens secret in the detail view, with the value hidden unless it is
+// selected.
+func (m Secrets) show(secret bws.Secret) Secrets {
+	m.mode = secretsShowMode
+	m.secret = secret
+	m.selected = 0
+	m.flashMsg = ""
+	m.rows = []itemShowRow{
+		{label: "Key", value: secret.Key, marginTop: 1},
+		{label: "Value", value: secret.Value, hidden: true},
+		{label: "ID", value: secret.ID, marginTop: 1},
+		{label: "Project", value: m.projectName(secret.ProjectID), detail: secret.ProjectID},
+		{label: "Org ID", value: secret.OrganizationID},
+		{label: "Updated", value: secret.RevisionDate.Local().Format("2006-01-02 15:04")},
+		{label: "Note", value: secret.Note, marginTop: 1, blockRender: true},
+	}
+	return m
+}
+
+// openForm opens the form for a new secret, or for editing m.secret.
+func (m Secrets) openForm(editing bool) (Secrets, tea.Cmd) {
+	m.formReturn = m.mode
+	m.mode = secretsFormMode
+	m.editing = editing
+	m.busy = false
+	m.project = 0
+	if editing {
+		m.inputs[secretKeyField].SetValue(m.secret.Key)
+		m.inputs[secretValueField].SetValue(m.secret.Value)
+		m.inputs[secretNoteField].SetValue(m.secret.Note)
+		for i, p := range m.projects {
+			if p.ID == m.secret.ProjectID {
+				m.project = i
+			}
+		}
+		m.formText = "Edit " + m.secret.Key + "."
+	} else {
+		for i := range m.inputs {
+			m.inputs[i].SetValue("")
+		}
+		m.formText = "Add a secret."
+	}
+	m.focus = secretKeyField
+	return m, m.moveFocus(0)
+}
+
+func (m *Secrets) moveFocus(delta int) tea.Cmd {
+	m.focus = (m.focus + delta + secretSubmitField + 1) % (secretSubmitField + 1)
+	var cmd tea.Cmd
+	for i := range m.inputs {
+		if i == m.focus {
+			cmd = m.inputs[i].Focus()
+			m.inputs[i].PromptStyle = focusedStyle
+			m.inputs[i].TextStyle = focusedStyle
+			continue
+		}
+		m.inputs[i].Blur()
+		m.inputs[i].PromptStyle = noStyle
+		m.inputs[i].TextStyle = noStyle
+	}
+	return cmd
+}
+
+func (m Secrets) submit() (Secrets, tea.Cmd) {
+	in := bws.SecretInput{
+		Key:   strings.TrimSpace(m.inputs[secretKeyField].Value()),
+		Value: m.inputs[secretValueField].Value(),
+		Note:  m.inputs[secretNoteField].Value(),
+	}
+	if in.Key == "" {
+		m.formText = "Please enter a key."
+		return m, nil
+	}
+	if len(m.projects) == 0 {
+		m.formText = "The machine account cannot access any project."
+		return m, nil
+	}
+	in.ProjectID = m.projects[m.project].ID
+	id := ""
+	if m.editing {
+		id = m.secret.ID
+	}
+	m.busy = true
+	m.formText = "Saving. Please wait"
+	return m, saveSecret(m.sm, id, in)
+}
+
+func (m Secrets) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.busy {
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.mode = m.formReturn
+		return m, nil
+	case "tab", "down":
+		return m, m.moveFocus(1)
+	case "shift+tab", "up":
+		return m, m.moveFocus(-1)
+	case "left", "right":
+		if m.focus == secretProjectField && len(m.projects) > 0 {
+			delta := 1
+			if msg.String() == "left" {
+				delta = -1
+			}
+			m.project = (m.project + delta + len(m.projects)) % len(m.projects)
+			return m, nil
+		}
+	case "enter":
+		if m.focus == secretSubmitField {
+			return m.submit()
+		}
+		return m, m.moveFocus(1)
+	}
+	if m.focus < len(m.inputs) {
+		var cmd tea.Cmd
+		m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m Secrets) updateShow(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.flashMsg = ""
+	switch {
+	case msg.String() == "ctrl+c":
+		return m, tea.Quit
+	case key.Matches(msg, m.showKeys.Back):
+		m.mode = secretsListMode
+	case key.Matches(msg, m.showKeys.CursorUp):
+		if m.selected > 0 {
+			m.selected--
+		}
+	case key.Matches(msg, m.showKeys.CursorDown):
+		if m.selected < len(m.rows)-1 {
+			m.selected++
+		}
+	case key.Matches(msg, m.showKeys.Copy):
+		err := clipboard.WriteAll(m.rows[m.selected].value)
+		if err != nil {
+			panic(fmt.Errorf("error copying data to clipboard: %w", err))
+		}
+		m.flashMsg = "copied to clipboard"
+	case key.Matches(msg, m.showKeys.Edit):
+		return m.openForm(true)
+	}
+	return m, nil
+}
+
+func (m Secrets) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case ShowSecrets:
+		m.mode = secretsListMode
+		m.loaded = false
+		m.err = nil
+		m.deleting = ""
+		return m, loadSecrets(m.sm)
+	case secretsLoaded:
+		m.loaded = true
+		m.err = msg.err
+		if m.err != nil {
+			return m, nil
+		}
+		m.projects = msg.projects
+		items := make([]list.Item, 0, len(msg.secrets))
+		for _, secret := range msg.secrets {
+			items = append(items, secretEntry{secret, m.projectName(secret.ProjectID)})
+		}
+		return m, m.list.SetItems(items)
+	case secretSaved:
+		m.busy = false
+		if msg.err != nil {
+			m.formText = fmt.Sprintf("Saving failed: %s", msg.err)
+			return m, nil
+		}
+		m = m.show(msg.secret)
+		m.flashMsg = "saved " + msg.secret.Key
+		return m, loadSecrets(m.sm)
+	case secretDeleted:
+		if msg.err != nil {
+			return m, m.list.NewStatusMessage(fmt.Sprintf("delete failed: %s", msg.err))
+		}
+		return m, tea.Batch(loadSecrets(m.sm), m.list.NewStatusMessage("deleted "+msg.secret.Key))
+	case tea.KeyMsg:
+		switch m.mode {
+		case secretsShowMode:
+			return m.updateShow(msg)
+		case secretsFormMode:
+			return m.updateForm(msg)
+		}
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+		deleting := m.deleting
+		m.deleting = ""
+		switch {
+		case key.Matches(msg, m.keys.Back) && m.list.FilterState() == list.Unfiltered:
+			return m, SelectLoadingDone()
+		case !m.loaded || m.err != nil:
+			return m, nil
+		case key.Matches(msg, m.keys.Refresh):
+			return m, loadSecrets(m.sm)
+		case key.Matches(msg, m.keys.New):
+			return m.openForm(false)
+		}
+		entry, ok := m.list.SelectedItem().(secretEntry)
+		if !ok {
+			break
+		}
+		switch {
+		case key.Matches(msg, m.keys.View):
+			return m.show(entry.secret), nil
+		case key.Matches(msg, m.keys.Copy):
+			err := clipboard.WriteAll(entry.secret.Value)
+			if err != nil {
+				panic(fmt.Errorf("error copying secret to clipboard: %w", err))
+			}
+			return m, m.list.NewStatusMessage("copied " + entry.secret.Key + " to clipboard")
+		case key.Matches(msg, m.keys.Edit):
+			m.secret = entry.secret
+			return m.openForm(true)
+		case key.Matches(msg, m.keys.Delete):
+			if deleting != entry.secret.ID {
+				m.deleting = entry.secret.ID
+				return m, m.list.NewStatusMessage(fmt.Sprintf("press d again to delete %s", entry.secret.Key))
+			}
+			return m, deleteSecret(m.sm, entry.secret)
+		}
+	case tea.WindowSizeMsg:
+		m.list.SetSize(msg.Width, msg.Height)
+		return m, tea.ClearScreen
+	}
+	if m.mode != secretsListMode {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m Secrets) viewShow() string {
+	var b strings.Builder
+	b.WriteString("  ")
+	b.WriteString(titleStyle.Render(fmt.Sprintf(" %s Secret | %s", logo, m.secret.Key)))
+	b.WriteString("\n")
+	for i, row := range m.rows {
+		selected := i == m.selected
+		b.WriteString(row.render(selected, selected))
+	}
+	footer := lipgloss.JoinVertical(lipgloss.Bottom, m.flashMsg, m.help.View(m.showKeys))
+	return docStyle.Render(lipgloss.JoinVertical(lipgloss.Left, b.String(), footer))
+}
+
+func (m Secrets) viewForm() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(fmt.Sprintf(" %s Secrets Manager ", logo)))
+	b.WriteString("\n\n")
+	b.WriteString(m.formText)
+	b.WriteString("\n\n")
+	for _, input := range m.inputs {
+		b.WriteString(input.View())
+		b.WriteRune('\n')
+	}
+	project := "-"
+	if len(m.projects) > 0 {
+		project = m.projects[m.project].Name
+	}
+	style := blurredStyle
+	if m.focus == secretProjectField {
+		style = focusedStyle
+	}
+	b.WriteString(style.Render(fmt.Sprintf("Project: ‹ %s ›", project)))
+	b.WriteRune('\n')
+	button := &blurredButton
+	if m.focus == secretSubmitField {
+		button = &focusedButton
+	}
+	fmt.Fprintf(&b, "\n%s\n\n", *button)
+	b.WriteString(mutedStyle.Render("tab/↑/↓ move • ←/→ change project • esc back"))
+	return docStyle.Render(b.String())
+}
+
+func (m Secrets) View() string {
+	switch m.mode {
+	case secretsShowMode:
+		return m.viewShow()
+	case secretsFormMode:
+		return m.viewForm()
+	}
+	title := titleStyle.Render(fmt.Sprintf(" %s Secrets Manager ", logo))
+	if errors.Is(m.err, bws.ErrNoAccessToken) {
+		return docStyle.Render(fmt.Sprintf("%s\n\n No access token. Set bwsAccessToken in the config or %s.\n\n Press q to go back.",
+			title, bws.AccessTokenEnv))
+	}
+	if m.err != nil {
+		return docStyle.Render(fmt.Sprintf("%s\n\n Failed to load secrets: %s\n\n Press q to go back.", title, m.err))
+	}
+	if !m.loaded {
+		return docStyle.Render(title + "\n\n Loading secrets. Please wait\n\n")
+	}
+	return docStyle.Render(m.list.View())
+}
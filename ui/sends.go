@@ -0,0 +1,225 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+type ShowSends struct{}
+
+func SelectShowSends() tea.Cmd {
+	return func() tea.Msg {
+		return ShowSends{}
+	}
+}
+
+type sendsLoaded struct {
+	sends []bw.Send
+	err   error
+}
+
+func loadSends(bwm *bw.Manager) tea.Cmd {
+	return func() tea.Msg {
+		sends, err := bwm.ListSends()
+		return sendsLoaded{sends, err}
+	}
+}
+
+type sendDeleted struct {
+	send bw.Send
+	err  error
+}
+
+func deleteSend(bwm *bw.Manager, send bw.Send) tea.Cmd {
+	return func() tea.Msg {
+		return sendDeleted{send, bwm.DeleteSend(send.ID)}
+	}
+}
+
+// sendEntry is a Send in the list.
+type sendEntry struct {
+	send bw.Send
+}
+
+func (e sendEntry) Title() string {
+	title := fmt.Sprintf("%s · %s", e.send.Name, strings.ToLower(e.send.Type.String()))
+	if e.send.Expired(time.Now()) {
+		title += " · expired"
+	}
+	return title
+}
+
+func (e sendEntry) Description() string {
+	parts := []string{}
+	if e.send.MaxAccessCount != nil {
+		parts = append(parts, fmt.Sprintf("opened %d/%d times", e.send.AccessCount, *e.send.MaxAccessCount))
+	} else {
+		parts = append(parts, fmt.Sprintf("opened %d times", e.send.AccessCount))
+	}
+	if e.send.ExpirationDate != nil {
+		parts = append(parts, "expires "+e.send.ExpirationDate.Local().Format("2006-01-02 15:04"))
+	}
+	parts = append(parts, "deleted "+e.send.DeletionDate.Local().Format("2006-01-02 15:04"))
+	if e.send.PasswordSet {
+		parts = append(parts, "password")
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (e sendEntry) FilterValue() string { return e.send.Name }
+
+type sendsKeyBindings struct {
+	CopyLink key.Binding
+	Delete   key.Binding
+	Refresh  key.Binding
+	Back     key.Binding
+}
+
+func newSendsKeyBindings() sendsKeyBindings {
+	return sendsKeyBindings{
+		CopyLink: key.NewBinding(
+			key.WithKeys("enter", "c", "y"),
+			key.WithHelp("enter/c/y", "copy link"),
+		),
+		Delete: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "delete"),
+		),
+		Refresh: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "refresh"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("q", "esc"),
+			key.WithHelp("q", "back"),
+		),
+	}
+}
+
+// Sends lists the Bitwarden Sends of the account.
+type Sends struct {
+	bwm    *bw.Manager
+	list   list.Model
+	keys   sendsKeyBindings
+	loaded bool
+	err    error
+	// deleting is the Send waiting for d to be pressed again.
+	deleting string
+}
+
+func NewSends(h int, v int, bwm *bw.Manager) Sends {
+	d := list.NewDefaultDelegate()
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.Foreground(selectedColor).BorderLeftForeground(selectedColor)
+	d.Styles.SelectedDesc = d.Styles.SelectedTitle.Copy()
+	width, height := docStyle.GetFrameSize()
+	l := list.New(nil, d, h-width, v-height)
+	l.Title = fmt.Sprintf(" %s Sends ", logo)
+	l.Styles.Title = titleStyle
+	l.SetStatusBarItemName("Send", "Sends")
+	// unbound rather than disabled, see NewHealth
+	l.KeyMap.Quit = key.NewBinding()
+	keys := newSendsKeyBindings()
+	l.AdditionalFullHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.CopyLink, keys.Delete, keys.Refresh, keys.Back}
+	}
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{keys.CopyLink, keys.Delete, keys.Back}
+	}
+	return Sends{
+		bwm:  bwm,
+		list: l,
+		keys: keys,
+	}
+}
+
+func (m Sends) Init() tea.Cmd {
+	return nil
+}
+
+func (m Sends) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case ShowSends:
+		m.loaded = false
+		m.err = nil
+		m.deleting = ""
+		return m, loadSends(m.bwm)
+	case sendsLoaded:
+		m.loaded = true
+		m.err = msg.err
+		if m.err != nil {
+			return m, nil
+		}
+		items := make([]list.Item, 0, len(msg.sends))
+		for _, send := range msg.sends {
+			items = append(items, sendEntry{send})
+		}
+		return m, m.list.SetItems(items)
+	case sendDeleted:
+		if msg.err != nil {
+			return m, m.list.NewStatusMessage(fmt.Sprintf("delete failed: %s", msg.err))
+		}
+		return m, tea.Batch(loadSends(m.bwm), m.list.NewStatusMessage("deleted "+msg.send.Name))
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+		deleting := m.deleting
+		m.deleting = ""
+		switch {
+		case key.Matches(msg, m.keys.Back) && m.list.FilterState() == list.Unfiltered:
+			return m, SelectLoadingDone()
+		case !m.loaded || m.err != nil:
+			return m, nil
+		case key.Matches(msg, m.keys.Refresh):
+			return m, loadSends(m.bwm)
+		case key.Matches(msg, m.keys.CopyLink):
+			entry, ok := m.list.SelectedItem().(sendEntry)
+			if !ok {
+				return m, nil
+			}
+			err := clipboard.WriteAll(entry.send.AccessURL)
+			if err != nil {
+				panic(fmt.Errorf("error copying link to clipboard: %w", err))
+			}
+			return m, m.list.NewStatusMessage("copied link to clipboard")
+		case key.Matches(msg, m.keys.Delete):
+			entry, ok := m.list.SelectedItem().(sendEntry)
+			if !ok {
+				return m, nil
+			}
+			if deleting != entry.send.ID {
+				m.deleting = entry.send.ID
+				return m, m.list.NewStatusMessage(fmt.Sprintf("press d again to delete %s", entry.send.Name))
+			}
+			return m, deleteSend(m.bwm, entry.send)
+		}
+	case tea.WindowSizeMsg:
+		m.list.SetSize(msg.Width, msg.Height)
+		return m, tea.ClearScreen
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m Sends) View() string {
+	if m.err != nil {
+		return docStyle.Render(fmt.Sprintf("%s\n\n Failed to load Sends: %s\n\n Press q to go back.",
+			titleStyle.Render(fmt.Sprintf(" %s Sends ", logo)), m.err))
+	}
+	if !m.loaded {
+		return docStyle.Render(titleStyle.Render(fmt.Sprintf(" %s Sends ", logo)) + "\n\n Loading Sends. Please wait\n\n")
+	}
+	return docStyle.Render(m.list.View())
+}
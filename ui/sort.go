@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sapslaj/gobw/bw"
+)
+
+type sortMode string
+
+const (
+	sortName         sortMode = "name"
+	sortModified     sortMode = "modified"
+	sortCreated      sortMode = "created"
+	sortPasswordAge  sortMode = "password-age"
+	sortRecentlyUsed sortMode = "recently-used"
+)
+
+func sortModes() []sortMode {
+	return []sortMode{sortName, sortModified, sortCreated, sortPasswordAge, sortRecentlyUsed}
+}
+
+func (s sortMode) label() string {
+	switch s {
+	case sortModified:
+		return "last modified"
+	case sortCreated:
+		return "created"
+	case sortPasswordAge:
+		return "password age"
+	case sortRecentlyUsed:
+		return "recently used"
+	default:
+		return "name"
+	}
+}
+
+// defaultDescending is the direction a mode starts in when cycled to: names
+// A to Z, the newest dates first, and the oldest passwords first.
+func (s sortMode) defaultDescending() bool {
+	switch s {
+	case sortModified, sortCreated, sortRecentlyUsed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s sortMode) direction(descending bool) string {
+	switch {
+	case s == sortName && descending:
+		return "Z-A"
+	case s == sortName:
+		return "A-Z"
+	case s == sortRecentlyUsed && descending:
+		return "most recent first"
+	case s == sortRecentlyUsed:
+		return "least recent first"
+	case descending:
+		return "newest first"
+	default:
+		return "oldest first"
+	}
+}
+
+func (s sortMode) next() sortMode {
+	modes := sortModes()
+	for i, m := range modes {
+		if m == s {
+			return modes[(i+1)%len(modes)]
+		}
+	}
+	return sortName
+}
+
+func parseSortMode(s string) sortMode {
+	for _, m := range sortModes() {
+		if string(m) == s {
+			return m
+		}
+	}
+	return sortName
+}
+
+// sortItems sorts items in place. Items without a password or never used sort
+// last when sorting by password age or recent use, whatever the direction.
+func sortItems(items []bw.Item, mode sortMode, descending bool, lastUsed map[string]time.Time) {
+	less := func(a bw.Item, b bw.Item) bool {
+		switch mode {
+		case sortModified:
+			return a.RevisionDate.Before(b.RevisionDate)
+		case sortCreated:
+			return a.CreationDate.Before(b.CreationDate)
+		case sortPasswordAge:
+			// an older password date means an older password
+			return bw.PasswordDate(a).Before(bw.PasswordDate(b))
+		case sortRecentlyUsed:
+			return lastUsed[a.ID].Before(lastUsed[b.ID])
+		default:
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		}
+	}
+	hasKey := func(item bw.Item) bool {
+		switch mode {
+		case sortPasswordAge:
+			return item.Login.Password != ""
+		case sortRecentlyUsed:
+			_, ok := lastUsed[item.ID]
+			return ok
+		default:
+			return true
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if hasKey(a) != hasKey(b) {
+			return hasKey(a)
+		}
+		if descending {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+}
@@ -27,6 +27,9 @@ var (
 				Foreground(lipgloss.Color("4")).
 				Padding(0, 0, 0, 1)
 
+	tabStyle       = mutedStyle.Copy()
+	activeTabStyle = titleStyle.Copy().Bold(true)
+
 	focusedButton = focusedStyle.Copy().Render("[ Submit ]")
 	blurredButton = fmt.Sprintf("[ %s ]", blurredStyle.Render("Submit"))
 )
@@ -1,16 +1,25 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sapslaj/gobw/bw"
 )
 
-func SelectUnlockSubmit(pw string) tea.Cmd {
+func SelectUnlockSubmit(pw string, pin string) tea.Cmd {
+	return func() tea.Msg {
+		return LoginSubmit{"", pw, unlock, pin}
+	}
+}
+
+func SelectPinUnlockSubmit(pin string) tea.Cmd {
 	return func() tea.Msg {
-		return LoginSubmit{"", pw, unlock}
+		return LoginSubmit{"", pin, pinUnlock, ""}
 	}
 }
 
@@ -18,32 +27,74 @@ type Unlock struct {
 	focusIndex int
 	inputs     []textinput.Model
 	text       string
+	bwm        *bw.Manager
+	// pinMode asks for the quick-unlock PIN instead of the master password.
+	pinMode bool
 }
 
-func NewUnlock() Unlock {
-	l := Unlock{
-		inputs: make([]textinput.Model, 1),
-		text:   "Please unlock your Bitwarden Vault",
+func NewUnlock(bwm *bw.Manager) Unlock {
+	l := Unlock{bwm: bwm}
+	l.reset(bwm.HasPin())
+	return l
+}
+
+// reset empties the inputs, asking for the PIN or the master password and,
+// if quick unlock is enabled, a new PIN.
+func (l *Unlock) reset(pinMode bool) {
+	l.pinMode = pinMode
+	l.focusIndex = 0
+	switch {
+	case pinMode:
+		l.text = fmt.Sprintf("Enter your PIN to unlock (%s), ctrl+p for the master password.\n"+
+			"The bw session stays valid until `bw lock`, or L in the list", attemptsLeft(l.bwm.PinAttemptsLeft()))
+		l.inputs = []textinput.Model{newUnlockInput("PIN")}
+	case l.bwm.PinEnabled():
+		l.text = "Please unlock your Bitwarden Vault. A PIN unlocks it quicker next time"
+		l.inputs = []textinput.Model{newUnlockInput("Password"), newUnlockInput("Quick-unlock PIN (optional)")}
+	default:
+		l.text = "Please unlock your Bitwarden Vault"
+		l.inputs = []textinput.Model{newUnlockInput("Password")}
+	}
+	for i := 1; i < len(l.inputs); i++ {
+		l.inputs[i].Blur()
+		l.inputs[i].PromptStyle = noStyle
+		l.inputs[i].TextStyle = noStyle
 	}
+}
 
-	var t textinput.Model
-	for i := range l.inputs {
-		t = textinput.New()
-		t.Focus()
-		t.CursorStyle = cursorStyle
-		t.CharLimit = 32
-
-		if i == 0 {
-			t.Placeholder = "Password"
-			t.TextStyle = focusedStyle
-			t.EchoMode = textinput.EchoPassword
-			t.EchoCharacter = '•'
-		}
+func newUnlockInput(placeholder string) textinput.Model {
+	t := textinput.New()
+	t.Focus()
+	t.CursorStyle = cursorStyle
+	t.CharLimit = 32
+	t.Placeholder = placeholder
+	t.TextStyle = focusedStyle
+	t.EchoMode = textinput.EchoPassword
+	t.EchoCharacter = '•'
+	return t
+}
 
-		l.inputs[i] = t
+func attemptsLeft(n int) string {
+	if n == 1 {
+		return "1 attempt left"
 	}
+	return fmt.Sprintf("%d attempts left", n)
+}
 
-	return l
+// submit unlocks with what was entered.
+func (l Unlock) submit() (Unlock, tea.Cmd) {
+	if l.pinMode {
+		return l, SelectPinUnlockSubmit(l.inputs[0].Value())
+	}
+	var pin string
+	if len(l.inputs) > 1 {
+		pin = l.inputs[1].Value()
+		if pin != "" && len(pin) < bw.MinPinLength {
+			l.text = fmt.Sprintf("The PIN needs at least %d characters", bw.MinPinLength)
+			return l, nil
+		}
+	}
+	return l, SelectUnlockSubmit(l.inputs[0].Value(), pin)
 }
 
 func (l Unlock) Init() tea.Cmd {
@@ -53,11 +104,34 @@ func (l Unlock) Init() tea.Cmd {
 func (l Unlock) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case LoadingLoginFailed:
-		l.text = "Login Failed. Please try again or press 'esc' to exit"
+		switch {
+		case msg.lt != pinUnlock:
+			l.reset(false)
+			l.text = "Login Failed. Please try again or press 'esc' to exit"
+			if errors.Is(msg.err, bw.ErrPinTooShort) {
+				l.text = msg.err.Error()
+			}
+		case errors.Is(msg.err, bw.ErrWrongPin):
+			l.reset(true)
+			l.text = fmt.Sprintf("Wrong PIN, %s. ctrl+p for the master password", attemptsLeft(l.bwm.PinAttemptsLeft()))
+		default:
+			// out of attempts or time, or the session is gone
+			l.reset(false)
+			l.text = "The PIN can no longer be used. Please unlock with your master password"
+		}
+	case VaultLocked:
+		l.reset(l.bwm.HasPin())
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "ctrl+c", "esc":
 			return l, tea.Quit
+		case "ctrl+p":
+			if l.pinMode {
+				l.reset(false)
+			} else if l.bwm.HasPin() {
+				l.reset(true)
+			}
+			return l, textinput.Blink
 
 		// Set focus to next input
 		case "tab", "shift+tab", "enter", "up", "down":
@@ -66,7 +140,7 @@ func (l Unlock) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Did the user press enter while the submit button was focused?
 			// If so, exit.
 			if s == "enter" && l.focusIndex == len(l.inputs) {
-				return l, SelectUnlockSubmit(l.inputs[0].Value())
+				return l.submit()
 			}
 
 			// Cycle indexes